@@ -3,3 +3,5 @@ module pocketanalyst
 go 1.24.2
 
 require github.com/lib/pq v1.10.9
+
+require github.com/mattn/go-sqlite3 v1.14.52
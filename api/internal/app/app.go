@@ -1,36 +1,66 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"pocketanalyst/internal/controllers"
+	"pocketanalyst/internal/migrations"
+	"pocketanalyst/internal/models"
 	"pocketanalyst/internal/repositories"
 	"pocketanalyst/internal/services"
 	"pocketanalyst/pkg/clients"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	_ "github.com/lib/pq"           // PostgreSQL driver
+	_ "github.com/mattn/go-sqlite3" // SQLite driver, selected by sqlite:// database URLs
 )
 
 // App represents the main application with all its dependencies
 type App struct {
-	DB     *sql.DB
-	Router *http.ServeMux
-	Config *Config
+	DB      *sql.DB
+	Router  *http.ServeMux
+	Config  *Config
+	Dialect migrations.Dialect // Storage backend the DatabaseURL selected
+
+	// Services are exposed for the command-line subcommands
+	StockService  *services.StockService
+	ImportService *services.ImportService
+
+	scheduler       *services.JobScheduler
+	backfillService *services.BackfillService
 }
 
 // Config holds all application configuration
 type Config struct {
-	DatabaseURL           string
+	DatabaseURL           string // postgres:// or sqlite:// URL, the scheme selects the storage backend
+	AutoMigrate           bool   // Apply pending schema migrations on startup
 	FMPAPIKey             string
 	FMPBaseURL            string
+	AlphaVantageAPIKey    string
+	AlphaVantageBaseURL   string
+	CSVBaseURL            string   // HTTP URL or local directory holding <SYMBOL>.csv files
+	Providers             []string // Providers to fetch from, in order of preference
 	Port                  string
 	ReadTimeout           time.Duration
 	WriteTimeout          time.Duration
 	MaxIdleConnections    int
 	MaxOpenConnections    int
 	ConnectionMaxLifetime time.Duration
+	SyncOverlapDays       int
+	SyncConcurrency       int // Symbols synchronized in parallel by a batch fetch
+	RateLimitFailFast     bool
+	RetryPolicies         map[string]clients.RetryPolicy // Keyed by provider name, e.g. "fmp"
+	CircuitFailureLimit   int
+	CircuitCooldown       time.Duration
+	ReconcileTolerances   services.ReconciliationTolerances
+	ImportChunkSize       int
+	ImportDefaultSource   string // Data source name for imported rows that don't name one
+	SchedulerEnabled      bool   // Run due data_fetch_jobs, including async fetches, and backfills in this instance
+	Scheduler             services.SchedulerConfig
+	Backfill              services.BackfillConfig
 }
 
 // NewApp creates a new app instance.
@@ -45,6 +75,14 @@ func NewApp(config *Config) (*App, error) {
 		return nil, err
 	}
 
+	// Bring the schema up to date before anything touches the tables
+	if config.AutoMigrate {
+		if err := app.migrateUp(context.Background()); err != nil {
+			app.DB.Close()
+			return nil, err
+		}
+	}
+
 	// Setup routes and dependencies
 	if err := app.setupRoutes(); err != nil {
 		return nil, err
@@ -55,15 +93,27 @@ func NewApp(config *Config) (*App, error) {
 
 // initDatabase establishse a db connection
 func (app *App) initDatabase() error {
-	db, err := sql.Open("postgres", app.Config.DatabaseURL)
+	backend, err := parseDatabaseURL(app.Config.DatabaseURL)
 	if err != nil {
 		return err
 	}
 
-	// Configure connection pool for prod use
-	db.SetMaxIdleConns(app.Config.MaxIdleConnections)
-	db.SetMaxOpenConns(app.Config.MaxOpenConnections)
-	db.SetConnMaxLifetime(app.Config.ConnectionMaxLifetime)
+	db, err := sql.Open(backend.driver, backend.dsn)
+	if err != nil {
+		return err
+	}
+
+	if backend.dialect == migrations.SQLite {
+		// SQLite allows one writer at a time, so every statement goes through a single connection
+		db.SetMaxIdleConns(1)
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+	} else {
+		// Configure connection pool for prod use
+		db.SetMaxIdleConns(app.Config.MaxIdleConnections)
+		db.SetMaxOpenConns(app.Config.MaxOpenConnections)
+		db.SetConnMaxLifetime(app.Config.ConnectionMaxLifetime)
+	}
 
 	// Verify db connection
 	if err := db.Ping(); err != nil {
@@ -72,40 +122,198 @@ func (app *App) initDatabase() error {
 	}
 
 	app.DB = db
-	log.Println("Database connection established successfully")
+	app.Dialect = backend.dialect
+	log.Printf("Database connection established successfully (%s)", backend.dialect)
 	return nil
 }
 
+// newMigrator creates a migrator for the schema of the connected database.
+func (app *App) newMigrator() (*migrations.Migrator, error) {
+	return migrations.NewMigrator(app.DB, app.Dialect)
+}
+
+// migrateUp applies all pending schema migrations.
+func (app *App) migrateUp(ctx context.Context) error {
+	migrator, err := app.newMigrator()
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		log.Println("Database schema is up to date")
+	}
+	return nil
+}
+
+// newStorage creates the repositories of the connected database.
+func (app *App) newStorage() *repositories.Storage {
+	if app.Dialect == migrations.SQLite {
+		return repositories.NewSQLiteStorage(app.DB)
+	}
+	return repositories.NewPostgresStorage(app.DB)
+}
+
 // setupRoutes initalizes all application dependencies and sets up HTTP routes
 func (app *App) setupRoutes() error {
 	// Initalize repositories
-	stockRepo := repositories.NewStockRepository(app.DB)
+	storage := app.newStorage()
+	dataSourceRepo := storage.DataSources
+	stockRepo := storage.Stocks
+	quotaRepo := storage.Quotas
+	jobRepo := storage.Jobs
+	logRepo := storage.Logs
+	companyRepo := storage.Companies
+	backfillRepo := storage.Backfills
+	actionRepo := storage.CorporateActions
 
 	// Initialize client factory and register providers
 	factory := clients.NewClientFactory()
 	factory.RegisterProvider("fmp", app.Config.FMPBaseURL, app.Config.FMPAPIKey)
+	factory.RegisterProvider("alphavantage", app.Config.AlphaVantageBaseURL, app.Config.AlphaVantageAPIKey)
+	factory.RegisterProvider("csv", app.Config.CSVBaseURL, "")
+	for provider, policy := range app.Config.RetryPolicies {
+		if err := factory.SetRetryPolicy(provider, policy); err != nil {
+			return err
+		}
+	}
 
-	// Create FMP Client using factory
-	client, err := factory.CreateClient("fmp")
+	// Create the configured providers using the factory, failing over between them in order
+	client, actionClients, profileClients, err := app.createStockDataClient(factory, dataSourceRepo, quotaRepo)
 	if err != nil {
 		return err
 	}
 
 	// Initialize services
-	stockService := services.NewStockService(stockRepo, client)
+	adjustmentService := services.NewAdjustmentService(stockRepo, actionRepo, actionClients)
+	companyService := services.NewCompanyService(companyRepo, profileClients)
+	stockService := services.NewStockService(stockRepo, companyRepo, logRepo, adjustmentService, client, app.Config.SyncOverlapDays)
+	reconciliationService := services.NewReconciliationService(stockRepo, app.Config.ReconcileTolerances)
+	importService := services.NewImportService(stockRepo, app.Config.ImportChunkSize)
+	app.StockService = stockService
+	app.ImportService = importService
+	app.backfillService = services.NewBackfillService(backfillRepo, stockService, app.Config.Backfill)
+	gapService := services.NewGapService(stockRepo, stockService, app.backfillService)
+	app.scheduler = services.NewJobScheduler(jobRepo, dataSourceRepo, stockService, gapService, adjustmentService, companyService, app.Config.Scheduler)
+	jobService := services.NewJobService(jobRepo, logRepo, dataSourceRepo, stockService, app.scheduler)
 
 	// Initialize controllers
-	stockController := controllers.NewStockController(stockService)
+	stockController := controllers.NewStockController(stockService, jobService, app.Config.SyncConcurrency)
+	reconciliationController := controllers.NewReconciliationController(reconciliationService)
+	importController := controllers.NewImportController(importService, app.Config.ImportDefaultSource)
+	jobController := controllers.NewJobController(jobService)
+	backfillController := controllers.NewBackfillController(app.backfillService)
+	gapController := controllers.NewGapController(gapService)
+	corporateActionController := controllers.NewCorporateActionController(adjustmentService)
+	companyController := controllers.NewCompanyController(companyService)
 
 	// Register routes with middleware
 	app.Router.HandleFunc("/api/stocks/fetch", app.withMiddleware(stockController.HandleStockFetchRequest))
 	app.Router.HandleFunc("/api/stocks/get", app.withMiddleware(stockController.HandleStockHistoryRequest))
 	app.Router.HandleFunc("/api/stocks/health", app.withMiddleware(stockController.HandleHealthCheckRequest))
+	app.Router.HandleFunc("/api/stocks/reconcile", app.withMiddleware(reconciliationController.HandleReconcileRequest))
+	app.Router.HandleFunc("/api/stocks/import", app.withMiddleware(importController.HandleStockImportRequest))
+	app.Router.HandleFunc("/api/stocks/gaps", app.withMiddleware(gapController.HandleGapsRequest))
+	app.Router.HandleFunc("/api/stocks/gaps/repair", app.withMiddleware(gapController.HandleRepairGapsRequest))
+	app.Router.HandleFunc("/api/stocks/actions", app.withMiddleware(corporateActionController.HandleCorporateActionsRequest))
+	app.Router.HandleFunc("/api/stocks/actions/fetch", app.withMiddleware(corporateActionController.HandleFetchCorporateActionsRequest))
+	app.Router.HandleFunc("/api/companies/enrich", app.withMiddleware(companyController.HandleEnrichCompaniesRequest))
+	app.Router.HandleFunc("/api/companies/{symbol}", app.withMiddleware(companyController.HandleCompanyRequest))
+	app.Router.HandleFunc("/api/jobs", app.withMiddleware(jobController.HandleJobsRequest))
+	app.Router.HandleFunc("/api/jobs/{id}", app.withMiddleware(jobController.HandleJobRequest))
+	app.Router.HandleFunc("/api/jobs/{id}/pause", app.withMiddleware(jobController.HandlePauseJobRequest))
+	app.Router.HandleFunc("/api/jobs/{id}/resume", app.withMiddleware(jobController.HandleResumeJobRequest))
+	app.Router.HandleFunc("/api/jobs/{id}/run", app.withMiddleware(jobController.HandleRunJobRequest))
+	app.Router.HandleFunc("/api/jobs/{id}/logs", app.withMiddleware(jobController.HandleJobLogsRequest))
+	app.Router.HandleFunc("/api/backfills", app.withMiddleware(backfillController.HandleBackfillsRequest))
+	app.Router.HandleFunc("/api/backfills/{id}", app.withMiddleware(backfillController.HandleBackfillRequest))
+	app.Router.HandleFunc("/api/backfills/{id}/pause", app.withMiddleware(backfillController.HandlePauseBackfillRequest))
+	app.Router.HandleFunc("/api/backfills/{id}/resume", app.withMiddleware(backfillController.HandleResumeBackfillRequest))
+	app.Router.HandleFunc("/api/backfills/{id}/cancel", app.withMiddleware(backfillController.HandleCancelBackfillRequest))
 
 	log.Println("Routes configured successfully")
 	return nil
 }
 
+// createStockDataClient creates a client for every configured provider. Each one is rate limited and wrapped
+// in its own circuit breaker. Multiple providers are combined into a FailoverClient. The providers that also
+// report corporate actions or company profiles are returned in order of preference, sharing the rate limits of
+// their price client.
+func (app *App) createStockDataClient(
+	factory *clients.ClientFactory,
+	dataSourceRepo repositories.DataSourceStore,
+	quotaRepo clients.QuotaStore,
+) (clients.StockDataClient, []clients.CorporateActionsClient, []clients.CompanyProfileClient, error) {
+	if len(app.Config.Providers) == 0 {
+		return nil, nil, nil, fmt.Errorf("at least one data provider must be configured")
+	}
+
+	providerClients := make([]clients.StockDataClient, 0, len(app.Config.Providers))
+	actionClients := []clients.CorporateActionsClient{}
+	profileClients := []clients.CompanyProfileClient{}
+	for _, provider := range app.Config.Providers {
+		client, err := factory.CreateClient(provider)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		// Throttle the client with the rate limits stored for its data source
+		if err := app.configureRateLimiter(client, dataSourceRepo, quotaRepo); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if actionClient, ok := client.(clients.CorporateActionsClient); ok {
+			actionClients = append(actionClients, actionClient)
+		}
+		if profileClient, ok := client.(clients.CompanyProfileClient); ok {
+			profileClients = append(profileClients, profileClient)
+		}
+
+		// Stop calling the provider while it keeps failing
+		providerClients = append(providerClients,
+			clients.NewCircuitBreakerClient(client, app.Config.CircuitFailureLimit, app.Config.CircuitCooldown))
+	}
+
+	if len(providerClients) == 1 {
+		return providerClients[0], actionClients, profileClients, nil
+	}
+
+	log.Printf("Failing over between providers: %v", app.Config.Providers)
+	return clients.NewFailoverClient(providerClients...), actionClients, profileClients, nil
+}
+
+// configureRateLimiter attaches a rate limiter built from the client's data_sources row, registering
+// the data source first if it does not exist yet.
+func (app *App) configureRateLimiter(
+	client clients.StockDataClient,
+	dataSourceRepo repositories.DataSourceStore,
+	quotaRepo clients.QuotaStore,
+) error {
+	limited, ok := client.(clients.RateLimitedClient)
+	if !ok {
+		return nil
+	}
+
+	ds, err := dataSourceRepo.Create(context.Background(), &models.DataSource{
+		SourceName: client.GetProviderName(),
+		SourceType: "PRICE",
+		IsActive:   true,
+	})
+	if err != nil {
+		return err
+	}
+
+	limited.SetRateLimiter(clients.NewRateLimiterFromDataSource(ds, quotaRepo, app.Config.RateLimitFailFast))
+	log.Printf("Rate limits for %s: %d/minute, %d/day", ds.SourceName, ds.RateLimitPerMinute, ds.RateLimitPerDay)
+	return nil
+}
+
 // withMiddleware applies common middleware to all routes
 func (app *App) withMiddleware(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -182,12 +390,25 @@ func (app *App) Start() error {
 		WriteTimeout: app.Config.WriteTimeout,
 	}
 
+	// Run scheduled jobs and backfills alongside the server
+	if app.Config.SchedulerEnabled {
+		app.scheduler.Start()
+		app.backfillService.Start()
+	}
+
 	log.Printf("Starting server on port %s", app.Config.Port)
 	return server.ListenAndServe()
 }
 
 // Gracefully shuts down the application
 func (app *App) Close() error {
+	if app.scheduler != nil {
+		app.scheduler.Stop()
+	}
+	if app.backfillService != nil {
+		app.backfillService.Stop()
+	}
+
 	if app.DB != nil {
 		log.Println("Closing database connection")
 		return app.DB.Close()
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"pocketanalyst/internal/services"
+	"strings"
+)
+
+// RunCommand runs a command-line subcommand against the initialized application.
+//
+//	import -file <path> [-format csv|ndjson] [-symbol SYMBOL] [-source NAME]
+//	import -file - -format csv|ndjson [-symbol SYMBOL] [-source NAME]
+func (app *App) RunCommand(args []string) error {
+	// Stop cleanly on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch args[0] {
+	case "import":
+		return app.runImportCommand(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// RunMigrateCommand manages the schema migrations. It only needs a database connection, so it runs
+// without NewApp, whose setup expects the tables to exist already.
+//
+//	migrate up
+//	migrate down [-steps N]
+//	migrate status
+func RunMigrateCommand(config *Config, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down|status")
+	}
+
+	app := &App{Config: config}
+	if err := app.initDatabase(); err != nil {
+		return err
+	}
+	defer app.Close()
+
+	migrator, err := app.newMigrator()
+	if err != nil {
+		return err
+	}
+
+	var result any
+	switch args[0] {
+	case "up":
+		result, err = migrator.Up(ctx)
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps < 1 {
+			return fmt.Errorf("-steps must be at least 1")
+		}
+		result, err = migrator.Down(ctx, *steps)
+	case "status":
+		result, err = migrator.Status(ctx)
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// runImportCommand imports a CSV or NDJSON file and prints the per-row report as JSON.
+func (app *App) runImportCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "CSV or NDJSON file to import, - for stdin")
+	format := flags.String("format", "", "csv or ndjson, detected from the file extension if empty, required for stdin")
+	symbol := flags.String("symbol", "", "symbol for rows without a symbol column")
+	source := flags.String("source", app.Config.ImportDefaultSource, "data source name for rows without a source column")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	// Detect the format from the extension when not given. Stdin has no extension to detect it from.
+	if *format == "" && *file == "-" {
+		return fmt.Errorf("-format is required when importing from stdin")
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".csv":
+			*format = string(services.ImportFormatCSV)
+		case ".ndjson", ".jsonl":
+			*format = string(services.ImportFormatNDJSON)
+		default:
+			return fmt.Errorf("cannot detect the format of %s, use -format", *file)
+		}
+	}
+
+	input := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	result, err := app.ImportService.Import(ctx, input, services.ImportFormat(strings.ToLower(*format)), services.ImportOptions{
+		Symbol: *symbol,
+		Source: *source,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/services"
+	"strconv"
+	"time"
+)
+
+// BackfillController handles HTTP requests related to historical backfills
+type BackfillController struct {
+	backfillService *services.BackfillService
+}
+
+// NewBackfillController creates a new instance of BackfillController
+func NewBackfillController(backfillService *services.BackfillService) *BackfillController {
+	return &BackfillController{
+		backfillService: backfillService,
+	}
+}
+
+// startBackfillRequest is the JSON body that starts a backfill. Either symbols is set or all_active is true.
+// Dates are formatted like YYYY-MM-DD.
+type startBackfillRequest struct {
+	Symbols   []string `json:"symbols"`
+	AllActive bool     `json:"all_active"`
+	StartDate string   `json:"start_date"`
+	EndDate   string   `json:"end_date"`
+	ChunkDays int      `json:"chunk_days"`
+}
+
+// HandleBackfillsRequest lists all backfill runs (GET) or starts a new one (POST).
+func (bc *BackfillController) HandleBackfillsRequest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		runs, err := bc.backfillService.ListBackfills(r.Context())
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, runs)
+
+	case http.MethodPost:
+		var request startBackfillRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid backfill request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		startDate, err := time.Parse("2006-01-02", request.StartDate)
+		if err != nil {
+			http.Error(w, "Invalid start date format. Please format like 'YYYY-MM-DD.'", http.StatusBadRequest)
+			return
+		}
+
+		// Default to loading everything up to today
+		endDate := time.Now().UTC().Truncate(24 * time.Hour)
+		if request.EndDate != "" {
+			if endDate, err = time.Parse("2006-01-02", request.EndDate); err != nil {
+				http.Error(w, "Invalid end date format. Please format like: 'YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+
+		run, err := bc.backfillService.StartBackfill(r.Context(), request.Symbols, request.AllActive,
+			startDate, endDate, request.ChunkDays)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/backfills/"+strconv.Itoa(run.RunID))
+		writeJSON(w, http.StatusCreated, run)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleBackfillRequest reports the status and progress of the backfill run in the {id} path segment.
+func (bc *BackfillController) HandleBackfillRequest(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	runID, ok := parseBackfillID(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := bc.backfillService.GetBackfill(r.Context(), runID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// HandlePauseBackfillRequest pauses the backfill run in the {id} path segment.
+func (bc *BackfillController) HandlePauseBackfillRequest(w http.ResponseWriter, r *http.Request) {
+	bc.handleBackfillAction(w, r, bc.backfillService.PauseBackfill)
+}
+
+// HandleResumeBackfillRequest resumes the paused backfill run in the {id} path segment.
+func (bc *BackfillController) HandleResumeBackfillRequest(w http.ResponseWriter, r *http.Request) {
+	bc.handleBackfillAction(w, r, bc.backfillService.ResumeBackfill)
+}
+
+// HandleCancelBackfillRequest cancels the backfill run in the {id} path segment.
+func (bc *BackfillController) HandleCancelBackfillRequest(w http.ResponseWriter, r *http.Request) {
+	bc.handleBackfillAction(w, r, bc.backfillService.CancelBackfill)
+}
+
+// handleBackfillAction applies a status change to a backfill run and returns the updated run.
+func (bc *BackfillController) handleBackfillAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, runID int) (*models.BackfillRun, error),
+) {
+	// Only allow POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	runID, ok := parseBackfillID(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := action(r.Context(), runID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// parseBackfillID reads the run ID from the {id} path segment. On invalid input an error response is written
+// and ok is false, in which case the caller must return.
+func parseBackfillID(w http.ResponseWriter, r *http.Request) (runID int, ok bool) {
+	runID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || runID <= 0 {
+		http.Error(w, "Invalid backfill ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return runID, true
+}
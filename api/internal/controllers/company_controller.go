@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/services"
+	"pocketanalyst/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// CompanyController handles HTTP requests related to companies and their profiles
+type CompanyController struct {
+	companyService *services.CompanyService
+}
+
+// NewCompanyController creates a new instance of CompanyController
+func NewCompanyController(companyService *services.CompanyService) *CompanyController {
+	return &CompanyController{
+		companyService: companyService,
+	}
+}
+
+// HandleCompanyRequest returns (GET), stores (PUT) or deletes (DELETE) the company in the {symbol} path segment.
+// PUT creates the company if it doesn't exist yet, otherwise only the fields present in the body change.
+// Companies stored through PUT keep their profile when placeholders are enriched.
+func (cc *CompanyController) HandleCompanyRequest(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.TrimSpace(r.PathValue("symbol")))
+
+	switch r.Method {
+	case http.MethodGet:
+		company, err := cc.companyService.GetCompany(r.Context(), symbol)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, company)
+
+	case http.MethodPut:
+		// New companies are active unless the body says otherwise
+		company, err := cc.companyService.GetCompany(r.Context(), symbol)
+		var notFound *errors.NotFoundError
+		if errors.As(err, &notFound) {
+			company = &models.Company{IsActive: true}
+		} else if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+
+		// Decoding onto the stored company keeps the fields the body leaves out
+		if err := json.NewDecoder(r.Body).Decode(company); err != nil {
+			http.Error(w, "Invalid company: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		company.Symbol = symbol
+
+		saved, created, err := cc.companyService.SaveCompany(r.Context(), company)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		if created {
+			w.Header().Set("Location", "/api/companies/"+saved.Symbol)
+			writeJSON(w, http.StatusCreated, saved)
+			return
+		}
+		writeJSON(w, http.StatusOK, saved)
+
+	case http.MethodDelete:
+		if err := cc.companyService.DeleteCompany(r.Context(), symbol); err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleEnrichCompaniesRequest fetches company profiles from the providers (POST). With a symbol query parameter
+// the profile of that company is fetched and returned, otherwise up to limit placeholder companies are enriched.
+func (cc *CompanyController) HandleEnrichCompaniesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+		company, err := cc.companyService.EnrichCompany(r.Context(), symbol)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, company)
+		return
+	}
+
+	limit := services.DefaultEnrichLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid limit parameter. Please use a positive number.", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	result, err := cc.companyService.EnrichPlaceholders(r.Context(), limit)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/services"
+	"time"
+)
+
+// CorporateActionController handles HTTP requests related to splits and dividends
+type CorporateActionController struct {
+	adjustmentService *services.AdjustmentService
+}
+
+// NewCorporateActionController creates a new instance of CorporateActionController
+func NewCorporateActionController(adjustmentService *services.AdjustmentService) *CorporateActionController {
+	return &CorporateActionController{
+		adjustmentService: adjustmentService,
+	}
+}
+
+// corporateActionRequest is a single action in the JSON body of a POST. The ex_date is formatted like YYYY-MM-DD.
+type corporateActionRequest struct {
+	Symbol     string  `json:"symbol"`
+	ActionType string  `json:"action_type"`
+	ExDate     string  `json:"ex_date"`
+	Ratio      float64 `json:"ratio"`
+	Amount     float64 `json:"amount"`
+	Source     string  `json:"source"`
+}
+
+// HandleCorporateActionsRequest lists the actions of the symbol query parameter (GET) or records the JSON
+// array of actions in the body (POST). Recording actions recomputes the adjusted closes of the affected symbols.
+func (cac *CorporateActionController) HandleCorporateActionsRequest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		actions, err := cac.adjustmentService.ListCorporateActions(r.Context(), r.URL.Query().Get("symbol"))
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, actions)
+
+	case http.MethodPost:
+		var requests []corporateActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			http.Error(w, "Invalid corporate actions request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(requests) == 0 {
+			http.Error(w, "At least one corporate action is required", http.StatusBadRequest)
+			return
+		}
+
+		actions := make([]*models.CorporateAction, 0, len(requests))
+		for i, request := range requests {
+			exDate, err := time.Parse("2006-01-02", request.ExDate)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid ex_date of action %d. Please format like 'YYYY-MM-DD'.", i+1),
+					http.StatusBadRequest)
+				return
+			}
+			actions = append(actions, &models.CorporateAction{
+				Symbol:     request.Symbol,
+				ActionType: request.ActionType,
+				ExDate:     exDate,
+				Ratio:      request.Ratio,
+				Amount:     request.Amount,
+				Source:     request.Source,
+			})
+		}
+
+		result, err := cac.adjustmentService.RecordCorporateActions(r.Context(), actions)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleFetchCorporateActionsRequest fetches the splits and dividends of the symbol query parameter from the
+// providers (POST) and re-adjusts its prices. The optional start_date and end_date limit the ex-dates fetched.
+func (cac *CorporateActionController) HandleFetchCorporateActionsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "Symbol parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	// Without a range every past action is fetched. Announced future ex-dates would adjust prices too early.
+	startDate, endDate, ok := parseDateRange(w, r, time.Time{}, time.Now().UTC())
+	if !ok {
+		return
+	}
+
+	result, err := cac.adjustmentService.SyncCorporateActions(r.Context(), symbol, startDate, endDate)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"pocketanalyst/internal/services"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GapController handles HTTP requests about trading days missing from the stored prices
+type GapController struct {
+	gapService *services.GapService
+}
+
+// NewGapController creates a new instance of GapController
+func NewGapController(gapService *services.GapService) *GapController {
+	return &GapController{
+		gapService: gapService,
+	}
+}
+
+// HandleGapsRequest lists the gaps per symbol and source. The optional symbol and source query parameters
+// take comma separated lists; without symbols all active companies are checked.
+func (gc *GapController) HandleGapsRequest(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbols, sources, startDate, endDate, ok := parseGapParameters(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := gc.gapService.FindGaps(r.Context(), symbols, sources, startDate, endDate)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// HandleRepairGapsRequest finds the gaps like HandleGapsRequest and starts a backfill re-fetching them.
+// It responds with 202 Accepted and the backfill's URL in the Location header, or 200 if there are no gaps.
+func (gc *GapController) HandleRepairGapsRequest(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbols, sources, startDate, endDate, ok := parseGapParameters(w, r)
+	if !ok {
+		return
+	}
+
+	report, run, err := gc.gapService.RepairGaps(r.Context(), symbols, sources, startDate, endDate)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	if run == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"gaps": report, "message": "No gaps to repair"})
+		return
+	}
+
+	location := "/api/backfills/" + strconv.Itoa(run.RunID)
+	w.Header().Set("Location", location)
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"gaps":       report,
+		"backfill":   run,
+		"status_url": location,
+		"message":    "Backfill of the missing ranges started",
+	})
+}
+
+// parseGapParameters reads the symbol, source, start_date and end_date query parameters. The range defaults to
+// the last 30 days up to yesterday, since today's session may not be over. On invalid input an error response
+// is written and ok is false, in which case the caller must return.
+func parseGapParameters(
+	w http.ResponseWriter,
+	r *http.Request,
+) (symbols, sources []string, startDate, endDate time.Time, ok bool) {
+	symbols = splitList(r.URL.Query().Get("symbol"))
+	sources = splitList(r.URL.Query().Get("source"))
+
+	endDate = time.Now().UTC().AddDate(0, 0, -1)
+	startDate, endDate, ok = parseDateRange(w, r, endDate.AddDate(0, 0, -30), endDate)
+	return symbols, sources, startDate, endDate, ok
+}
+
+// splitList splits a comma separated query parameter, dropping blank entries.
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"pocketanalyst/pkg/errors"
+	"pocketanalyst/pkg/errors/client_errors"
+	"strconv"
+	"time"
+)
+
+// parseDateRange reads the optional start_date and end_date query parameters, falling back to the given
+// defaults. On invalid input an error response is written and ok is false, in which case the caller must return.
+func parseDateRange(
+	w http.ResponseWriter,
+	r *http.Request,
+	defaultStart, defaultEnd time.Time,
+) (startDate, endDate time.Time, ok bool) {
+	startDate, endDate = defaultStart, defaultEnd
+
+	// Parse date ranges if provided in query parameters
+	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
+		if parsedDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			startDate = parsedDate
+		} else {
+			http.Error(w, "Invalid start date format. Please format like 'YYYY-MM-DD.'", http.StatusBadRequest)
+			return startDate, endDate, false
+		}
+	}
+
+	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
+		if parsedDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = parsedDate
+		} else {
+			http.Error(w, "Invalid end date format. Please format like: 'YYYY-MM-DD", http.StatusBadRequest)
+			return startDate, endDate, false
+		}
+	}
+
+	// Validate date range: ensure start date is not after end date
+	if startDate.After(endDate) {
+		http.Error(w, "Invalid date range: start date cannot be after end date.", http.StatusBadRequest)
+		return startDate, endDate, false
+	}
+
+	return startDate, endDate, true
+}
+
+// writeJSON writes value as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// Caller must return for this function!
+func handleServiceError(w http.ResponseWriter, err error) {
+	// Rate limit errors are wrapped by the service, so they need to be unwrapped first
+	var rateLimitErr *client_errors.RateLimitExceededError
+	if errors.As(err, &rateLimitErr) {
+		// 429 Too Many Requests
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		http.Error(w, rateLimitErr.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	// An open circuit breaker means the provider is unavailable for now
+	var circuitErr *client_errors.CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		// 503 Service Unavailable
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(circuitErr.RetryAfter.Seconds()))))
+		http.Error(w, circuitErr.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	switch e := err.(type) {
+	case *errors.ModelValidationError:
+		// 400 Bad Request
+		http.Error(w, e.Error(), http.StatusBadRequest)
+	case *errors.NotFoundError:
+		// 404 Not Found
+		http.Error(w, e.Error(), http.StatusNotFound)
+	case *errors.ConflictError:
+		// 409 Conflict
+		http.Error(w, e.Error(), http.StatusConflict)
+	case *errors.UnavailableError:
+		// 503 Service Unavailable
+		http.Error(w, e.Error(), http.StatusServiceUnavailable)
+	case *errors.ServiceError:
+		// Service/database errors -> 500 Internal Server Error
+		http.Error(w, "Internal server error occurred", http.StatusInternalServerError)
+		// Log the actual error for debugging (don't expose to user)
+		log.Printf("Service error: %v", e)
+	default:
+		// Unknown errors -> 500
+		http.Error(w, "Internal server error occurred", http.StatusInternalServerError)
+		log.Printf("Unknown error: %v", err)
+	}
+}
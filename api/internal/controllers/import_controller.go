@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"pocketanalyst/internal/services"
+	"strings"
+)
+
+// ImportController handles HTTP requests that load stock data we already own
+type ImportController struct {
+	importService *services.ImportService
+	defaultSource string
+}
+
+// NewImportController creates a new instance of ImportController. Rows without a source column and
+// requests without a source parameter are stored under defaultSource.
+func NewImportController(importService *services.ImportService, defaultSource string) *ImportController {
+	return &ImportController{
+		importService: importService,
+		defaultSource: defaultSource,
+	}
+}
+
+// HandleStockImportRequest imports CSV or NDJSON rows from the request body. The format is taken from the
+// format query parameter, falling back to the Content-Type header. Optional symbol and source parameters
+// fill in rows that don't carry those columns. The response lists every row that could not be imported.
+func (ic *ImportController) HandleStockImportRequest(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := importFormatFromRequest(r)
+	if format == "" {
+		http.Error(w, "Unknown import format. Use format=csv or format=ndjson", http.StatusBadRequest)
+		return
+	}
+
+	options := services.ImportOptions{
+		Symbol: r.URL.Query().Get("symbol"),
+		Source: r.URL.Query().Get("source"),
+	}
+	if options.Source == "" {
+		options.Source = ic.defaultSource
+	}
+
+	result, err := ic.importService.Import(r.Context(), r.Body, format, options)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	// Return the per-row report
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Error encoding response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// importFormatFromRequest returns the requested import format, or "" if it cannot be determined.
+func importFormatFromRequest(r *http.Request) services.ImportFormat {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "csv":
+		return services.ImportFormatCSV
+	case "ndjson", "jsonl":
+		return services.ImportFormatNDJSON
+	case "":
+	default:
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return services.ImportFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return services.ImportFormatNDJSON
+	}
+	return ""
+}
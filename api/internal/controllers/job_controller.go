@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/services"
+	"strconv"
+)
+
+// JobController handles HTTP requests related to data fetch jobs
+type JobController struct {
+	jobService *services.JobService
+}
+
+// NewJobController creates a new instance of JobController
+func NewJobController(jobService *services.JobService) *JobController {
+	return &JobController{
+		jobService: jobService,
+	}
+}
+
+// HandleJobsRequest lists the jobs (GET, optionally filtered with active=true|false) or creates a new one (POST).
+func (jc *JobController) HandleJobsRequest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var active *bool
+		if activeStr := r.URL.Query().Get("active"); activeStr != "" {
+			parsed, err := strconv.ParseBool(activeStr)
+			if err != nil {
+				http.Error(w, "Invalid active parameter. Please use true or false.", http.StatusBadRequest)
+				return
+			}
+			active = &parsed
+		}
+
+		jobs, err := jc.jobService.ListJobs(r.Context(), active)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, jobs)
+
+	case http.MethodPost:
+		// New jobs are active unless the body says otherwise
+		job := &models.DataFetchJob{IsActive: true}
+		if err := json.NewDecoder(r.Body).Decode(job); err != nil {
+			http.Error(w, "Invalid job: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		created, err := jc.jobService.CreateJob(r.Context(), job)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/jobs/"+strconv.Itoa(created.JobID))
+		writeJSON(w, http.StatusCreated, created)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleJobRequest returns (GET), updates (PUT) or deletes (DELETE) the job in the {id} path segment.
+// GET includes the latest execution, which reports the progress of a running job. An update only changes
+// the fields present in the body.
+func (jc *JobController) HandleJobRequest(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := parseJobID(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		status, err := jc.jobService.GetJobStatus(r.Context(), jobID)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+
+	case http.MethodPut:
+		job, err := jc.jobService.GetJob(r.Context(), jobID)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+
+		// Decoding onto the stored job keeps the fields the body leaves out
+		if err := json.NewDecoder(r.Body).Decode(job); err != nil {
+			http.Error(w, "Invalid job: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		job.JobID = jobID
+
+		updated, err := jc.jobService.UpdateJob(r.Context(), job)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		if err := jc.jobService.DeleteJob(r.Context(), jobID); err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandlePauseJobRequest deactivates the job in the {id} path segment, so the scheduler skips it.
+func (jc *JobController) HandlePauseJobRequest(w http.ResponseWriter, r *http.Request) {
+	jc.handleSetJobActive(w, r, false)
+}
+
+// HandleResumeJobRequest reactivates the job in the {id} path segment.
+func (jc *JobController) HandleResumeJobRequest(w http.ResponseWriter, r *http.Request) {
+	jc.handleSetJobActive(w, r, true)
+}
+
+// handleSetJobActive pauses or resumes a job.
+func (jc *JobController) handleSetJobActive(w http.ResponseWriter, r *http.Request, active bool) {
+	// Only allow POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID, ok := parseJobID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := jc.jobService.SetJobActive(r.Context(), jobID, active)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// HandleRunJobRequest requests a run of the job in the {id} path segment without changing its schedule. The
+// scheduler picks it up on its next poll, so the response is 202 Accepted.
+func (jc *JobController) HandleRunJobRequest(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID, ok := parseJobID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := jc.jobService.RunJobNow(r.Context(), jobID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// HandleJobLogsRequest lists the execution history of the job in the {id} path segment, newest first.
+// The optional limit query parameter caps the number of logs returned.
+func (jc *JobController) HandleJobLogsRequest(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID, ok := parseJobID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit. Please provide a positive number.", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	logs, err := jc.jobService.GetJobLogs(r.Context(), jobID, limit)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, logs)
+}
+
+// parseJobID reads the job ID from the {id} path segment. On invalid input an error response is written
+// and ok is false, in which case the caller must return.
+func parseJobID(w http.ResponseWriter, r *http.Request) (jobID int, ok bool) {
+	jobID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || jobID <= 0 {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return jobID, true
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/internal/services"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newJobRouter routes the job endpoints to a JobController on top of the in-memory repositories, which
+// hold data source 1.
+func newJobRouter(t *testing.T) *http.ServeMux {
+	db := repositories.NewMemoryDB()
+	dataSources := repositories.NewMemoryDataSourceRepository(db)
+	if _, err := dataSources.Create(context.Background(), &models.DataSource{SourceName: "Stub", SourceType: "PRICE", IsActive: true}); err != nil {
+		t.Fatalf("Expected the data source to be created, got %v", err)
+	}
+	jobController := NewJobController(services.NewJobService(
+		repositories.NewMemoryDataFetchJobRepository(db),
+		repositories.NewMemoryJobExecutionLogRepository(db),
+		dataSources,
+		nil,
+		nil,
+	))
+
+	router := http.NewServeMux()
+	router.HandleFunc("/api/jobs", jobController.HandleJobsRequest)
+	router.HandleFunc("/api/jobs/{id}", jobController.HandleJobRequest)
+	router.HandleFunc("/api/jobs/{id}/pause", jobController.HandlePauseJobRequest)
+	router.HandleFunc("/api/jobs/{id}/resume", jobController.HandleResumeJobRequest)
+	router.HandleFunc("/api/jobs/{id}/run", jobController.HandleRunJobRequest)
+	return router
+}
+
+// serve sends a request to router and decodes a JSON response into out, if given.
+func serve(t *testing.T, router http.Handler, method, target, body string, out any) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if out != nil && recorder.Code < 300 {
+		if err := json.NewDecoder(recorder.Body).Decode(out); err != nil {
+			t.Fatalf("Expected a JSON response from %s %s, got %v", method, target, err)
+		}
+	}
+	return recorder.Code
+}
+
+// TestJobController_CRUD verifies jobs are created, updated, listed and deleted, and that a second job for
+// the same entity is rejected with 409 Conflict.
+func TestJobController_CRUD(t *testing.T) {
+	router := newJobRouter(t)
+	body := `{"source_id": 1, "entity_type": "symbol", "entity_value": "ibm", "data_type": "price", "frequency": "Daily"}`
+
+	var created models.DataFetchJob
+	if code := serve(t, router, http.MethodPost, "/api/jobs", body, &created); code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d", code)
+	}
+	if created.JobID == 0 || created.EntityValue != "IBM" || created.Frequency != models.FrequencyDaily ||
+		created.Status != models.JobStatusPending || !created.IsActive || created.NextScheduled.IsZero() {
+		t.Errorf("Expected a normalized, active and pending job, got %+v", created)
+	}
+	jobPath := "/api/jobs/" + strconv.Itoa(created.JobID)
+
+	if code := serve(t, router, http.MethodPost, "/api/jobs", body, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 Conflict for a duplicate job, got %d", code)
+	}
+
+	// An update only changes the fields in the body
+	var updated models.DataFetchJob
+	if code := serve(t, router, http.MethodPut, jobPath, `{"frequency": "weekly"}`, &updated); code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for the update, got %d", code)
+	}
+	if updated.Frequency != models.FrequencyWeekly || updated.EntityValue != "IBM" {
+		t.Errorf("Expected a weekly IBM job, got %+v", updated)
+	}
+
+	var jobs []*models.DataFetchJob
+	if code := serve(t, router, http.MethodGet, "/api/jobs?active=true", "", &jobs); code != http.StatusOK || len(jobs) != 1 {
+		t.Errorf("Expected the active job to be listed, got %d, %v", code, jobs)
+	}
+
+	if code := serve(t, router, http.MethodDelete, jobPath, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 No Content for the delete, got %d", code)
+	}
+	if code := serve(t, router, http.MethodGet, jobPath, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 Not Found for the deleted job, got %d", code)
+	}
+	if code := serve(t, router, http.MethodPut, jobPath, `{}`, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 Not Found when updating the deleted job, got %d", code)
+	}
+}
+
+// TestJobController_RunNow verifies running a job now keeps its schedule and that paused jobs are rejected.
+func TestJobController_RunNow(t *testing.T) {
+	router := newJobRouter(t)
+	scheduled := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Second)
+	body := `{"source_id": 1, "entity_type": "SYMBOL", "entity_value": "IBM", "data_type": "PRICE", ` +
+		`"frequency": "daily", "next_scheduled": "` + scheduled.Format(time.RFC3339) + `"}`
+
+	var created models.DataFetchJob
+	if code := serve(t, router, http.MethodPost, "/api/jobs", body, &created); code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d", code)
+	}
+	jobPath := "/api/jobs/" + strconv.Itoa(created.JobID)
+
+	var run models.DataFetchJob
+	if code := serve(t, router, http.MethodPost, jobPath+"/run", "", &run); code != http.StatusAccepted {
+		t.Fatalf("Expected 202 Accepted, got %d", code)
+	}
+	if run.RunRequested.IsZero() || !run.NextScheduled.Equal(scheduled) {
+		t.Errorf("Expected a requested run that keeps the schedule %v, got %+v", scheduled, run)
+	}
+
+	if code := serve(t, router, http.MethodPost, jobPath+"/pause", "", nil); code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for the pause, got %d", code)
+	}
+	if code := serve(t, router, http.MethodPost, jobPath+"/run", "", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request when running a paused job, got %d", code)
+	}
+	if code := serve(t, router, http.MethodPost, "/api/jobs/999/run", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 Not Found for an unknown job, got %d", code)
+	}
+	if code := serve(t, router, http.MethodGet, jobPath+"/run", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 Method Not Allowed, got %d", code)
+	}
+}
+
+// TestJobController_UnsupportedJob verifies jobs the scheduler can't run are rejected with 400 Bad Request
+// instead of failing on every run.
+func TestJobController_UnsupportedJob(t *testing.T) {
+	router := newJobRouter(t)
+
+	tests := []struct {
+		entityType, dataType string
+		expected             int
+	}{
+		{"symbol", "daily", http.StatusBadRequest},
+		{"symbol", "fundamentals", http.StatusBadRequest},
+		{"market", "price", http.StatusBadRequest},
+		{"sector", "gap_repair", http.StatusBadRequest},
+		{"market", "corporate_actions", http.StatusBadRequest},
+		{"market", "gap_repair", http.StatusCreated},
+		{"market", "company_profile", http.StatusCreated},
+		{"symbol", "corporate_actions", http.StatusCreated},
+	}
+	for _, test := range tests {
+		body := `{"source_id": 1, "entity_type": "` + test.entityType + `", "entity_value": "ALL", "data_type": "` +
+			test.dataType + `", "frequency": "daily"}`
+		if code := serve(t, router, http.MethodPost, "/api/jobs", body, nil); code != test.expected {
+			t.Errorf("Expected %d for a %s job of a %s, got %d", test.expected, test.dataType, test.entityType, code)
+		}
+	}
+
+	var created models.DataFetchJob
+	body := `{"source_id": 1, "entity_type": "SYMBOL", "entity_value": "IBM", "data_type": "PRICE", "frequency": "daily"}`
+	if code := serve(t, router, http.MethodPost, "/api/jobs", body, &created); code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d", code)
+	}
+	jobPath := "/api/jobs/" + strconv.Itoa(created.JobID)
+	if code := serve(t, router, http.MethodPut, jobPath, `{"data_type": "daily"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request when updating to an unsupported data type, got %d", code)
+	}
+	if code := serve(t, router, http.MethodPut, jobPath, `{"entity_type": "market"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request when updating to an unsupported entity type, got %d", code)
+	}
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"pocketanalyst/internal/services"
+	"strconv"
+	"time"
+)
+
+// ReconciliationController handles HTTP requests comparing stock prices across data sources
+type ReconciliationController struct {
+	reconciliationService *services.ReconciliationService
+}
+
+// NewReconciliationController creates a new instance of ReconciliationController
+func NewReconciliationController(reconciliationService *services.ReconciliationService) *ReconciliationController {
+	return &ReconciliationController{
+		reconciliationService: reconciliationService,
+	}
+}
+
+// HandleReconcileRequest reports the days on which the stored sources of a symbol disagree.
+// Optional price_tolerance and volume_tolerance query parameters override the default relative tolerances.
+func (rc *ReconciliationController) HandleReconcileRequest(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request parameters
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "Symbol parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	// Default to last 30 days if not provided
+	endDate := time.Now()
+	startDate, endDate, ok := parseDateRange(w, r, endDate.AddDate(0, 0, -30), endDate)
+	if !ok {
+		return
+	}
+
+	tolerances := rc.reconciliationService.DefaultTolerances()
+	if value := r.URL.Query().Get("price_tolerance"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			http.Error(w, "Invalid price_tolerance. Please provide a decimal like 0.005", http.StatusBadRequest)
+			return
+		}
+		tolerances.PriceTolerance = parsed
+	}
+	if value := r.URL.Query().Get("volume_tolerance"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			http.Error(w, "Invalid volume_tolerance. Please provide a decimal like 0.05", http.StatusBadRequest)
+			return
+		}
+		tolerances.VolumeTolerance = parsed
+	}
+
+	report, err := rc.reconciliationService.Reconcile(r.Context(), symbol, startDate, endDate, tolerances)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	// Return data as JSON
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Error encoding response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -2,25 +2,53 @@ package controllers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"pocketanalyst/internal/services"
-	"pocketanalyst/pkg/errors"
+	"pocketanalyst/pkg/calendar"
+	"strconv"
 	"time"
 )
 
+// defaultHistoryTradingDays is the number of trading days returned by a history request without a date range,
+// roughly one month of sessions.
+const defaultHistoryTradingDays = 21
+
+// maxHistoryTradingDays caps the days parameter of a history request at roughly 40 years of sessions, which
+// keeps the trading calendar walk cheap.
+const maxHistoryTradingDays = 10000
+
+// maxSyncBatchSymbols is the largest batch fetched within the request. Larger batches and all_active fetches
+// run in the background unless async=false is given, in which case they are rejected, so a batch never
+// outlives the server's write timeout.
+const maxSyncBatchSymbols = 10
+
 // StockController handles HTTP Requests related to stocks
 type StockController struct {
-	stockService *services.StockService
+	stockService    *services.StockService
+	jobService      *services.JobService
+	syncConcurrency int
 }
 
-// NewStockController creates a new instance of StockController
-func NewStockController(stockService *services.StockService) *StockController {
+// NewStockController creates a new instance of StockController. syncConcurrency is the number of symbols
+// a batch fetch synchronizes in parallel.
+func NewStockController(
+	stockService *services.StockService,
+	jobService *services.JobService,
+	syncConcurrency int,
+) *StockController {
 	return &StockController{
-		stockService: stockService,
+		stockService:    stockService,
+		jobService:      jobService,
+		syncConcurrency: syncConcurrency,
 	}
 }
 
+// batchFetchRequest is the JSON body of a batch fetch. Either symbols is set or all_active is true.
+type batchFetchRequest struct {
+	Symbols   []string `json:"symbols"`
+	AllActive bool     `json:"all_active"`
+}
+
 func (sc *StockController) HandleHealthCheckRequest(w http.ResponseWriter, r *http.Request) {
 	// Only allow GET requests
 	if r.Method != http.MethodGet {
@@ -28,8 +56,21 @@ func (sc *StockController) HandleHealthCheckRequest(w http.ResponseWriter, r *ht
 		return
 	}
 
+	// Report degraded providers without failing the health check, the API itself is still up
+	providers := sc.stockService.ProviderHealth()
+	status := "ok"
+	for _, provider := range providers {
+		if !provider.Healthy() {
+			status = "degraded"
+		}
+	}
+
 	// Return success response
-	response := "Health check received. Success!"
+	response := map[string]any{
+		"status":    status,
+		"providers": providers,
+		"message":   "Health check received. Success!",
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -37,31 +78,56 @@ func (sc *StockController) HandleHealthCheckRequest(w http.ResponseWriter, r *ht
 	}
 }
 
-// HandleStockFetchRequest handles requests to fetch and store new stock data
+// HandleStockFetchRequest handles requests to fetch and store new stock data. A single symbol is given in
+// the symbol query parameter, a batch as a JSON body like {"symbols": ["AAPL", "MSFT"]} or {"all_active": true}.
+// With async=true the work is handed to background jobs and 202 Accepted is returned right away. Batches of
+// more than maxSyncBatchSymbols symbols and all_active fetches are async by default.
 func (sc *StockController) HandleStockFetchRequest(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST requests
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+
+	var async *bool
+	if asyncStr := r.URL.Query().Get("async"); asyncStr != "" {
+		parsed, err := strconv.ParseBool(asyncStr)
+		if err != nil {
+			http.Error(w, "Invalid async parameter. Please use true or false.", http.StatusBadRequest)
+			return
+		}
+		async = &parsed
+	}
+
 	// Parse requset parameters
 	symbol := r.URL.Query().Get("symbol")
 	if symbol == "" {
-		http.Error(w, "Symbol parameter is required", http.StatusBadRequest)
+		sc.handleBatchFetch(w, r, async)
+		return
+	}
+	if async != nil && *async {
+		sc.enqueueStockFetch(w, r, symbol)
 		return
 	}
 
 	// Fetch and store stock data in DB
-	count, err := sc.stockService.SynchronizeStockData(r.Context(), symbol)
+	result, err := sc.stockService.SynchronizeStockData(r.Context(), symbol)
 	if err != nil {
-		sc.handleServiceError(w, err)
+		handleServiceError(w, err)
 		return
 	}
 
 	// Return success response
 	response := map[string]any{
 		"success":           true,
-		"records_processed": count,
+		"records_processed": result.Fetched,
+		"inserted":          result.Inserted,
+		"updated":           result.Updated,
+		"unchanged":         result.Unchanged,
+		"incremental":       result.Incremental,
+		"provider":          result.Provider,
+		"retries":           result.Retries,
+		"log_id":            result.LogID,
 		"message":           "Successfully fetched and stored stock data",
 	}
 
@@ -71,6 +137,94 @@ func (sc *StockController) HandleStockFetchRequest(w http.ResponseWriter, r *htt
 	}
 }
 
+// handleBatchFetch synchronizes the symbols listed in the request body and returns a per-symbol summary.
+// Individual failures are part of the summary, the request itself succeeds. async is nil if the request
+// leaves the choice to the size of the batch.
+func (sc *StockController) handleBatchFetch(w http.ResponseWriter, r *http.Request, async *bool) {
+	var request batchFetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Symbol parameter or a JSON body with symbols is required", http.StatusBadRequest)
+		return
+	}
+
+	symbols := request.Symbols
+	if request.AllActive {
+		var err error
+		if symbols, err = sc.stockService.ActiveSymbols(r.Context()); err != nil {
+			handleServiceError(w, err)
+			return
+		}
+	}
+	if len(symbols) == 0 {
+		http.Error(w, "No symbols to fetch", http.StatusBadRequest)
+		return
+	}
+
+	large := request.AllActive || len(symbols) > maxSyncBatchSymbols
+	if async == nil {
+		async = &large
+	}
+	if *async {
+		sc.enqueueBatchFetch(w, r, symbols)
+		return
+	}
+	if large {
+		http.Error(w, "Batches of more than "+strconv.Itoa(maxSyncBatchSymbols)+
+			" symbols and all_active fetches are only run in the background, please use async=true.", http.StatusBadRequest)
+		return
+	}
+
+	result, err := sc.stockService.SynchronizeStockDataBatch(r.Context(), symbols, sc.syncConcurrency)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// enqueueBatchFetch starts a background fetch per symbol and responds with 202 Accepted and the job of each symbol.
+func (sc *StockController) enqueueBatchFetch(w http.ResponseWriter, r *http.Request, symbols []string) {
+	jobs := make([]map[string]any, 0, len(symbols))
+	for _, symbol := range symbols {
+		job, err := sc.jobService.EnqueueStockFetch(r.Context(), symbol)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		jobs = append(jobs, map[string]any{
+			"symbol":     job.EntityValue,
+			"job_id":     job.JobID,
+			"status":     job.Status,
+			"status_url": "/api/jobs/" + strconv.Itoa(job.JobID),
+		})
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"success": true,
+		"jobs":    jobs,
+		"message": "Stock data fetches accepted, poll each status_url for its progress",
+	})
+}
+
+// enqueueStockFetch starts a background fetch of symbol and responds with 202 Accepted.
+func (sc *StockController) enqueueStockFetch(w http.ResponseWriter, r *http.Request, symbol string) {
+	job, err := sc.jobService.EnqueueStockFetch(r.Context(), symbol)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	location := "/api/jobs/" + strconv.Itoa(job.JobID)
+	w.Header().Set("Location", location)
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"success":    true,
+		"job_id":     job.JobID,
+		"status":     job.Status,
+		"status_url": location,
+		"message":    "Stock data fetch accepted, poll status_url for its progress",
+	})
+}
+
 func (sc *StockController) HandleStockHistoryRequest(w http.ResponseWriter, r *http.Request) {
 	// Parse request parameters
 	symbol := r.URL.Query().Get("symbol")
@@ -79,39 +233,41 @@ func (sc *StockController) HandleStockHistoryRequest(w http.ResponseWriter, r *h
 		return
 	}
 
-	// Default to last 30 days if not provided
-	endDate := time.Now()
-	startDate := endDate.AddDate(0, 0, -30)
-
-	// Parse date ranges if provided in query parameters
-	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
-		if parsedDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
-			startDate = parsedDate
-		} else {
-			http.Error(w, "Invalid start date format. Please format like 'YYYY-MM-DD.'", http.StatusBadRequest)
+	// Without a start date, return the last N trading days of the symbol's exchange up to the end date
+	tradingDays := defaultHistoryTradingDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days <= 0 || days > maxHistoryTradingDays {
+			http.Error(w, "Invalid days parameter, expected a positive number of trading days up to "+
+				strconv.Itoa(maxHistoryTradingDays), http.StatusBadRequest)
 			return
 		}
+		tradingDays = days
 	}
 
-	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
-		if parsedDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
-			endDate = parsedDate
-		} else {
-			http.Error(w, "Invalid end date format. Please format like: 'YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
+	startDate, endDate, ok := parseDateRange(w, r, time.Time{}, time.Now().UTC())
+	if !ok {
+		return
+	}
+	if r.URL.Query().Get("start_date") == "" {
+		cal := sc.stockService.TradingCalendar(r.Context(), symbol)
+		startDate = calendar.LastTradingDays(cal, endDate, tradingDays)
 	}
 
-	// Validate date range: ensure start date is not after end date
-	if startDate.After(endDate) {
-		http.Error(w, "Invalid date range: start date cannot be after end date.", http.StatusBadRequest)
-		return
+	// Prices are returned as reported unless adjusted=true asks for split and dividend adjusted prices
+	adjusted := false
+	if adjustedStr := r.URL.Query().Get("adjusted"); adjustedStr != "" {
+		var err error
+		if adjusted, err = strconv.ParseBool(adjustedStr); err != nil {
+			http.Error(w, "Invalid adjusted parameter, expected true or false", http.StatusBadRequest)
+			return
+		}
 	}
 
 	// Get stock history from service layer
-	stocks, err := sc.stockService.GetStockHistory(r.Context(), symbol, startDate, endDate)
+	stocks, err := sc.stockService.GetStockHistory(r.Context(), symbol, startDate, endDate, adjusted)
 	if err != nil {
-		sc.handleServiceError(w, err)
+		handleServiceError(w, err)
 		return
 	}
 
@@ -121,24 +277,3 @@ func (sc *StockController) HandleStockHistoryRequest(w http.ResponseWriter, r *h
 		http.Error(w, "Error encoding response: "+err.Error(), http.StatusInternalServerError)
 	}
 }
-
-// Caller must return for this function!
-func (sc *StockController) handleServiceError(w http.ResponseWriter, err error) {
-	switch e := err.(type) {
-	case *errors.ModelValidationError:
-		// 400 Bad Request
-		http.Error(w, e.Error(), http.StatusBadRequest)
-	case *errors.NotFoundError:
-		// 404 Not Found
-		http.Error(w, e.Error(), http.StatusNotFound)
-	case *errors.ServiceError:
-		// Service/database errors -> 500 Internal Server Error
-		http.Error(w, "Internal server error occurred", http.StatusInternalServerError)
-		// Log the actual error for debugging (don't expose to user)
-		log.Printf("Service error: %v", e)
-	default:
-		// Unknown errors -> 500
-		http.Error(w, "Internal server error occurred", http.StatusInternalServerError)
-		log.Printf("Unknown error: %v", err)
-	}
-}
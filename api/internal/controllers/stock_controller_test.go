@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/internal/services"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubClient serves one trading day of prices for every symbol.
+type stubClient struct{}
+
+func (c *stubClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	return []*models.Stock{{Symbol: symbol, Date: date, OpenPrice: 10, HighPrice: 11, LowPrice: 9, ClosePrice: 10.5,
+		AdjustedClose: 10.5, Volume: 1000, SplitCoefficient: 1, DataSource: "Stub"}}, nil
+}
+
+func (c *stubClient) GetProviderName() string {
+	return "Stub"
+}
+
+// newStockRouter routes the stock endpoints to a StockController on top of the in-memory repositories, and the
+// job status endpoint to a JobController. The job scheduler only runs if schedulerEnabled is true.
+func newStockRouter(t *testing.T, schedulerEnabled bool) *http.ServeMux {
+	db := repositories.NewMemoryDB()
+	dataSources := repositories.NewMemoryDataSourceRepository(db)
+	if _, err := dataSources.Create(context.Background(), &models.DataSource{SourceName: "Stub", SourceType: "PRICE", IsActive: true}); err != nil {
+		t.Fatalf("Expected the data source to be created, got %v", err)
+	}
+
+	jobRepo := repositories.NewMemoryDataFetchJobRepository(db)
+	logRepo := repositories.NewMemoryJobExecutionLogRepository(db)
+	stockService := services.NewStockService(
+		repositories.NewMemoryStockRepository(db, dataSources),
+		repositories.NewMemoryCompanyRepository(db),
+		logRepo,
+		nil,
+		&stubClient{},
+		0,
+	)
+	scheduler := services.NewJobScheduler(jobRepo, dataSources, stockService, nil, nil, nil, services.SchedulerConfig{})
+	if schedulerEnabled {
+		scheduler.Start()
+		t.Cleanup(scheduler.Stop)
+	}
+	jobService := services.NewJobService(jobRepo, logRepo, dataSources, stockService, scheduler)
+	stockController := NewStockController(stockService, jobService, 2)
+
+	router := http.NewServeMux()
+	router.HandleFunc("/api/stocks/fetch", stockController.HandleStockFetchRequest)
+	router.HandleFunc("/api/stocks/get", stockController.HandleStockHistoryRequest)
+	router.HandleFunc("/api/jobs/{id}", NewJobController(jobService).HandleJobRequest)
+	return router
+}
+
+// TestStockController_AsyncFetch verifies background fetches are only accepted while the scheduler runs,
+// as nothing would pick up their jobs otherwise.
+func TestStockController_AsyncFetch(t *testing.T) {
+	router := newStockRouter(t, true)
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?symbol=IBM&async=true", "", nil); code != http.StatusAccepted {
+		t.Errorf("Expected 202 Accepted with the scheduler running, got %d", code)
+	}
+
+	router = newStockRouter(t, false)
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?symbol=IBM&async=true", "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 Service Unavailable with the scheduler disabled, got %d", code)
+	}
+	body := `{"symbols": ["IBM", "MSFT"]}`
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?async=true", body, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 Service Unavailable for a batch with the scheduler disabled, got %d", code)
+	}
+
+	// Synchronous fetches don't need the scheduler
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?symbol=IBM", "", nil); code != http.StatusOK {
+		t.Errorf("Expected 200 OK for a synchronous fetch, got %d", code)
+	}
+}
+
+// TestStockController_AsyncFetchAgain verifies fetching a symbol again reports the new run as PENDING rather
+// than the outcome of the previous one.
+func TestStockController_AsyncFetchAgain(t *testing.T) {
+	router := newStockRouter(t, true)
+
+	var accepted struct {
+		JobID     int    `json:"job_id"`
+		Status    string `json:"status"`
+		StatusURL string `json:"status_url"`
+	}
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?symbol=IBM&async=true", "", &accepted); code != http.StatusAccepted {
+		t.Fatalf("Expected 202 Accepted, got %d", code)
+	}
+	if accepted.Status != models.JobStatusPending {
+		t.Errorf("Expected the first fetch to be PENDING, got %s", accepted.Status)
+	}
+
+	// Wait for the scheduler to finish the first run
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var status services.JobStatus
+		if code := serve(t, router, http.MethodGet, accepted.StatusURL, "", &status); code != http.StatusOK {
+			t.Fatalf("Expected 200 OK for the job status, got %d", code)
+		}
+		if status.Status == models.JobStatusSuccess {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the first fetch to succeed, got %+v", status.DataFetchJob)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	firstJobID := accepted.JobID
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?symbol=IBM&async=true", "", &accepted); code != http.StatusAccepted {
+		t.Fatalf("Expected 202 Accepted for the second fetch, got %d", code)
+	}
+	if accepted.JobID != firstJobID || accepted.Status != models.JobStatusPending {
+		t.Errorf("Expected job %d to be PENDING again, got job %d %s", firstJobID, accepted.JobID, accepted.Status)
+	}
+}
+
+// TestStockController_BatchFetch verifies only small batches are fetched within the request by default, so a
+// batch can't outlive the write timeout.
+func TestStockController_BatchFetch(t *testing.T) {
+	router := newStockRouter(t, true)
+
+	var result services.BatchSyncResult
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch", `{"symbols": ["IBM", "msft"]}`, &result); code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for a small batch, got %d", code)
+	}
+	if result.Requested != 2 || result.Succeeded != 2 {
+		t.Errorf("Expected 2 synchronized symbols, got %+v", result)
+	}
+
+	symbols := make([]string, maxSyncBatchSymbols+1)
+	for i := range symbols {
+		symbols[i] = `"SYM` + strconv.Itoa(i) + `"`
+	}
+	body := `{"symbols": [` + strings.Join(symbols, ",") + `]}`
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch", body, nil); code != http.StatusAccepted {
+		t.Errorf("Expected 202 Accepted for a large batch, got %d", code)
+	}
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?async=false", body, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request for a large synchronous batch, got %d", code)
+	}
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch", `{"all_active": true}`, nil); code != http.StatusAccepted {
+		t.Errorf("Expected 202 Accepted for all active symbols, got %d", code)
+	}
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?async=false", `{"all_active": true}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request for a synchronous fetch of all active symbols, got %d", code)
+	}
+}
+
+// TestStockController_HistoryDays verifies the number of trading days of a history request is bounded.
+func TestStockController_HistoryDays(t *testing.T) {
+	router := newStockRouter(t, false)
+	if code := serve(t, router, http.MethodPost, "/api/stocks/fetch?symbol=IBM", "", nil); code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for the fetch, got %d", code)
+	}
+
+	tests := []struct {
+		days     string
+		expected int
+	}{
+		{"5", http.StatusOK},
+		{strconv.Itoa(maxHistoryTradingDays), http.StatusOK},
+		{strconv.Itoa(maxHistoryTradingDays + 1), http.StatusBadRequest},
+		{"2147483647", http.StatusBadRequest},
+		{"0", http.StatusBadRequest},
+		{"-3", http.StatusBadRequest},
+		{"ten", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		target := "/api/stocks/get?symbol=IBM&end_date=2024-03-20&days=" + test.days
+		if code := serve(t, router, http.MethodGet, target, "", nil); code != test.expected {
+			t.Errorf("Expected %d for days=%s, got %d", test.expected, test.days, code)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+/*
+Package migrations versions the database schema. Migrations are numbered pairs of SQL files embedded from
+the directory of their dialect, e.g. sql/postgres/0002_data_sources_created_at.up.sql and
+sql/postgres/0002_data_sources_created_at.down.sql. Applied versions are recorded in the schema_migrations table.
+
+Schema changes are made by adding a new migration, never by editing one that has been released. Every
+migration is written for both PostgreSQL and SQLite, under the same version and name.
+*/
+package migrations
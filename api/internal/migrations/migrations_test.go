@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// TestLoad_Embedded verifies the embedded migrations of every dialect parse, have both directions and ascend
+// without gaps, and that the dialects share their versions.
+func TestLoad_Embedded(t *testing.T) {
+	names := map[Dialect][]string{}
+	for _, dialect := range []Dialect{Postgres, SQLite} {
+		migrations, err := load(files, "sql/"+string(dialect))
+		if err != nil {
+			t.Fatalf("Expected the embedded %s migrations to load, got %v", dialect, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("Expected at least one embedded %s migration", dialect)
+		}
+
+		for i, migration := range migrations {
+			if migration.Version != int64(i+1) {
+				t.Errorf("Expected %s migration %d to have version %d, got %d", dialect, i, i+1, migration.Version)
+			}
+			names[dialect] = append(names[dialect], migration.Name)
+		}
+	}
+
+	if !slices.Equal(names[Postgres], names[SQLite]) {
+		t.Errorf("Expected both dialects to have the same migrations, got %v and %v", names[Postgres], names[SQLite])
+	}
+}
+
+// TestLoad_Invalid verifies unpaired files, duplicate versions and unknown names are rejected.
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"sql/0001_init.up.sql": {Data: []byte("SELECT 1")},
+		},
+		"duplicate version": {
+			"sql/0001_init.up.sql":    {Data: []byte("SELECT 1")},
+			"sql/0001_init.down.sql":  {Data: []byte("SELECT 1")},
+			"sql/0001_other.up.sql":   {Data: []byte("SELECT 1")},
+			"sql/0001_other.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"invalid name": {
+			"sql/init.sql": {Data: []byte("SELECT 1")},
+		},
+	}
+
+	for name, fsys := range tests {
+		if _, err := load(fsys, "sql"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
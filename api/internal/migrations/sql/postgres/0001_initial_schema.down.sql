@@ -0,0 +1,19 @@
+-- Drops every table of the initial schema, dependents first. This deletes all data.
+DROP TABLE IF EXISTS job_execution_logs;
+DROP TABLE IF EXISTS ml_predictions;
+DROP TABLE IF EXISTS model_training_history;
+DROP TABLE IF EXISTS ml_models;
+DROP TABLE IF EXISTS feature_data;
+DROP TABLE IF EXISTS feature_sets;
+DROP TABLE IF EXISTS sentiment_data;
+DROP TABLE IF EXISTS news_events;
+DROP TABLE IF EXISTS fundamental_data;
+DROP TABLE IF EXISTS technical_indicators;
+DROP TABLE IF EXISTS corporate_actions;
+DROP TABLE IF EXISTS stock_prices;
+DROP TABLE IF EXISTS backfill_chunks;
+DROP TABLE IF EXISTS backfill_runs;
+DROP TABLE IF EXISTS data_fetch_jobs;
+DROP TABLE IF EXISTS provider_quota_usage;
+DROP TABLE IF EXISTS data_sources;
+DROP TABLE IF EXISTS companies;
@@ -0,0 +1,4 @@
+-- data_sources only tracked last_updated, which the code reported as the creation time.
+-- Existing rows get their last update as the best known creation time.
+ALTER TABLE data_sources ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+UPDATE data_sources SET created_at = COALESCE(last_updated, created_at);
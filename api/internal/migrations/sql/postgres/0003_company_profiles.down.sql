@@ -0,0 +1,2 @@
+ALTER TABLE companies DROP COLUMN IF EXISTS profile_updated;
+ALTER TABLE companies DROP COLUMN IF EXISTS profile_source;
@@ -0,0 +1,8 @@
+-- Companies created along with their first stock prices are placeholders named after their symbol.
+-- profile_updated records when a company's name, sector, industry and exchange were last filled in, from a
+-- provider's profile or by hand, and stays NULL for placeholders. profile_source names where they came from.
+-- Companies whose name differs from their symbol were entered by hand.
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS profile_source VARCHAR(100);
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS profile_updated TIMESTAMP;
+UPDATE companies SET profile_source = 'Manual', profile_updated = COALESCE(last_updated, CURRENT_TIMESTAMP)
+WHERE name <> symbol AND profile_updated IS NULL;
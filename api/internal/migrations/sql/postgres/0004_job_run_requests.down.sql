@@ -0,0 +1 @@
+ALTER TABLE data_fetch_jobs DROP COLUMN IF EXISTS run_requested;
@@ -0,0 +1,3 @@
+-- Running a job now used to move next_scheduled to the time of the request, which shifted the job's schedule
+-- from then on. run_requested records the request instead and is cleared once the job ran.
+ALTER TABLE data_fetch_jobs ADD COLUMN IF NOT EXISTS run_requested TIMESTAMP;
@@ -0,0 +1,4 @@
+-- Enriching placeholders used to pick them in alphabetical order, so symbols no provider has a profile for
+-- were retried first on every call. profile_checked records when a profile was last requested, so the
+-- placeholders checked longest ago come first.
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS profile_checked TIMESTAMP;
@@ -0,0 +1,10 @@
+-- Drops every table of the initial schema, dependents first. This deletes all data.
+DROP TABLE IF EXISTS job_execution_logs;
+DROP TABLE IF EXISTS corporate_actions;
+DROP TABLE IF EXISTS stock_prices;
+DROP TABLE IF EXISTS backfill_chunks;
+DROP TABLE IF EXISTS backfill_runs;
+DROP TABLE IF EXISTS data_fetch_jobs;
+DROP TABLE IF EXISTS provider_quota_usage;
+DROP TABLE IF EXISTS data_sources;
+DROP TABLE IF EXISTS companies;
@@ -0,0 +1,139 @@
+-- Initial schema for SQLite, holding the tables of the PostgreSQL initial schema that the application uses.
+-- SQLite has no date, timestamp, array or JSON types, so the repositories store:
+--   dates as 'YYYY-MM-DD' text,
+--   timestamps as UTC 'YYYY-MM-DD HH:MM:SS.ffffff' text, which sorts and compares like the timestamps,
+--   arrays and JSON documents as JSON text.
+-- The columns are declared TEXT rather than DATE or TIMESTAMP so that drivers hand back the stored text.
+
+-- Companies table to store company information
+CREATE TABLE IF NOT EXISTS companies (
+	company_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	symbol VARCHAR(20) NOT NULL UNIQUE,
+	name VARCHAR(255) NOT NULL,
+	sector VARCHAR(100),
+	industry VARCHAR(100),
+	exchange VARCHAR(50),
+	is_active BOOLEAN DEFAULT TRUE,
+	last_updated TEXT
+);
+
+-- Data sources configuration table
+CREATE TABLE IF NOT EXISTS data_sources (
+	source_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	source_name VARCHAR(100) NOT NULL UNIQUE,
+	source_type VARCHAR(50) NOT NULL,
+	base_url VARCHAR(255),
+	rate_limit_per_minute INTEGER,
+	rate_limit_per_day INTEGER,
+	config_parameters TEXT,				-- JSON object
+	is_active BOOLEAN DEFAULT TRUE,
+	last_updated TEXT
+);
+
+-- Daily request counts per data source, used to enforce rate_limit_per_day across restarts
+CREATE TABLE IF NOT EXISTS provider_quota_usage (
+	source_name VARCHAR(100) NOT NULL REFERENCES data_sources(source_name),
+	usage_date TEXT NOT NULL,			-- UTC day the requests were made on
+	request_count INTEGER NOT NULL DEFAULT 0,
+	last_updated TEXT,
+	PRIMARY KEY (source_name, usage_date)
+);
+
+-- Data fetch jobs for tracking what to fetch and when
+CREATE TABLE IF NOT EXISTS data_fetch_jobs (
+	job_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	source_id INTEGER NOT NULL REFERENCES data_sources(source_id),
+	entity_type VARCHAR(50) NOT NULL,
+	entity_value VARCHAR(100) NOT NULL,
+	data_type VARCHAR(50) NOT NULL,
+	frequency VARCHAR(20) NOT NULL,
+	parameters TEXT,				-- JSON object
+	last_execution TEXT,
+	last_success TEXT,
+	next_scheduled TEXT,
+	status VARCHAR(20) DEFAULT 'PENDING',
+	is_active BOOLEAN DEFAULT TRUE,
+	last_updated TEXT,
+	CONSTRAINT fetch_job_unique UNIQUE (source_id, entity_type, entity_value, data_type)
+);
+
+-- Backfill runs loading a date range for a list of symbols
+CREATE TABLE IF NOT EXISTS backfill_runs (
+	run_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	symbols TEXT NOT NULL,				-- JSON array
+	start_date TEXT NOT NULL,
+	end_date TEXT NOT NULL,
+	chunk_days INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'RUNNING',
+	created_at TEXT,
+	last_updated TEXT
+);
+
+-- Checkpoints of a backfill run, one per symbol and date range
+CREATE TABLE IF NOT EXISTS backfill_chunks (
+	chunk_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id INTEGER NOT NULL REFERENCES backfill_runs(run_id) ON DELETE CASCADE,
+	symbol VARCHAR(20) NOT NULL,
+	start_date TEXT NOT NULL,
+	end_date TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	records_processed INTEGER NOT NULL DEFAULT 0,
+	error_message TEXT,
+	last_updated TEXT,
+	CONSTRAINT backfill_chunk_unique UNIQUE (run_id, symbol, start_date)
+);
+
+-- Stock price data. Prices are rounded to 5 decimals by the repository, like NUMERIC(15, 5).
+CREATE TABLE IF NOT EXISTS stock_prices (
+	price_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	company_id INTEGER NOT NULL REFERENCES companies(company_id),
+	symbol VARCHAR(20) NOT NULL,
+	date TEXT NOT NULL,
+	open_price REAL,
+	high_price REAL,
+	low_price REAL,
+	close_price REAL,
+	adjusted_close REAL,
+	volume INTEGER,
+	dividend_amount REAL,
+	split_coefficient REAL,
+	source_id INTEGER NOT NULL REFERENCES data_sources(source_id),
+	last_updated TEXT,
+	CONSTRAINT stock_price_unique UNIQUE (company_id, date, source_id)
+);
+
+-- Splits and cash dividends used to back-adjust stock prices
+CREATE TABLE IF NOT EXISTS corporate_actions (
+	action_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	company_id INTEGER NOT NULL REFERENCES companies(company_id),
+	symbol VARCHAR(20) NOT NULL,
+	action_type VARCHAR(20) NOT NULL,
+	ex_date TEXT NOT NULL,
+	ratio REAL,					-- Rounded to 8 decimals, splits only
+	amount REAL,					-- Rounded to 5 decimals, dividends only
+	source VARCHAR(100) NOT NULL,
+	last_updated TEXT,
+	CONSTRAINT corporate_action_unique UNIQUE (company_id, action_type, ex_date)
+);
+
+-- System job scheduler status tracking
+CREATE TABLE IF NOT EXISTS job_execution_logs (
+	log_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_id INTEGER REFERENCES data_fetch_jobs(job_id),
+	job_type VARCHAR(50) NOT NULL,
+	start_time TEXT NOT NULL,
+	end_time TEXT,
+	status VARCHAR(20) NOT NULL,
+	records_processed INTEGER,
+	error_message TEXT,
+	details TEXT,					-- JSON object
+	last_updated TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_prices_symbol_date ON stock_prices(symbol, date);
+CREATE INDEX IF NOT EXISTS idx_stock_prices_company_date ON stock_prices(company_id, date);
+CREATE INDEX IF NOT EXISTS idx_data_fetch_jobs_next_scheduled ON data_fetch_jobs(next_scheduled, is_active);
+CREATE INDEX IF NOT EXISTS idx_job_execution_logs_job_id ON job_execution_logs(job_id);
+CREATE INDEX IF NOT EXISTS idx_backfill_chunks_run_status ON backfill_chunks(run_id, status);
+CREATE INDEX IF NOT EXISTS idx_corporate_actions_symbol_date ON corporate_actions(symbol, ex_date);
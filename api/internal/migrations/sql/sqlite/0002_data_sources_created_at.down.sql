@@ -0,0 +1 @@
+ALTER TABLE data_sources DROP COLUMN created_at;
@@ -0,0 +1,4 @@
+-- Same as the PostgreSQL migration. SQLite can't add a column with a non-constant default, so the
+-- repository sets created_at when it inserts a data source.
+ALTER TABLE data_sources ADD COLUMN created_at TEXT;
+UPDATE data_sources SET created_at = last_updated;
@@ -0,0 +1,2 @@
+ALTER TABLE companies DROP COLUMN profile_updated;
+ALTER TABLE companies DROP COLUMN profile_source;
@@ -0,0 +1,5 @@
+-- Same as the PostgreSQL migration.
+ALTER TABLE companies ADD COLUMN profile_source VARCHAR(100);
+ALTER TABLE companies ADD COLUMN profile_updated TEXT;
+UPDATE companies SET profile_source = 'Manual', profile_updated = COALESCE(last_updated, CURRENT_TIMESTAMP)
+WHERE name <> symbol AND profile_updated IS NULL;
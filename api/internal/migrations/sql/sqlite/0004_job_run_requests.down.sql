@@ -0,0 +1 @@
+ALTER TABLE data_fetch_jobs DROP COLUMN run_requested;
@@ -0,0 +1,2 @@
+-- Same as the PostgreSQL migration.
+ALTER TABLE data_fetch_jobs ADD COLUMN run_requested TEXT;
@@ -0,0 +1,2 @@
+-- Same as the PostgreSQL migration.
+ALTER TABLE companies ADD COLUMN profile_checked TEXT;
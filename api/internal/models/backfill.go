@@ -0,0 +1,84 @@
+package models
+
+import (
+	"pocketanalyst/pkg/errors"
+	"time"
+)
+
+// Backfill run statuses stored in backfill_runs.status
+const (
+	BackfillStatusRunning   = "RUNNING"
+	BackfillStatusPaused    = "PAUSED"
+	BackfillStatusCancelled = "CANCELLED"
+	BackfillStatusCompleted = "COMPLETED"
+	BackfillStatusFailed    = "FAILED" // Finished, but some chunks failed
+)
+
+// BackfillRun represents a historical load of a date range for a list of symbols.
+// The chunk counts are computed from its backfill_chunks rows.
+type BackfillRun struct {
+	RunID            int       `json:"run_id"`
+	Symbols          []string  `json:"symbols"`
+	StartDate        time.Time `json:"start_date"`
+	EndDate          time.Time `json:"end_date"`
+	ChunkDays        int       `json:"chunk_days"`
+	Status           string    `json:"status"`
+	TotalChunks      int       `json:"total_chunks"`
+	PendingChunks    int       `json:"pending_chunks"`
+	RunningChunks    int       `json:"running_chunks"`
+	CompletedChunks  int       `json:"completed_chunks"`
+	FailedChunks     int       `json:"failed_chunks"`
+	RecordsProcessed int       `json:"records_processed"`
+	CreatedAt        time.Time `json:"created_at"`
+	LastUpdated      time.Time `json:"last_updated"`
+}
+
+// Validate ensures the backfill run meets all logical requirements
+func (r *BackfillRun) Validate() error {
+	switch {
+	case len(r.Symbols) == 0:
+		return errors.NewModelValidationError("BackfillRun", "symbols", "at least one symbol is required")
+	case r.StartDate.IsZero() || r.EndDate.IsZero():
+		return errors.NewModelValidationError("BackfillRun", "date_range", "start and end date are required")
+	case r.StartDate.After(r.EndDate):
+		return errors.NewModelValidationError("BackfillRun", "date_range", "start date cannot be after end date")
+	case r.ChunkDays <= 0:
+		return errors.NewModelValidationError("BackfillRun", "chunk_days", "chunk_days must be positive")
+	}
+	return nil
+}
+
+// Chunks splits the run into one chunk per symbol and chunk_days long date range, oldest first.
+func (r *BackfillRun) Chunks() []*BackfillChunk {
+	chunks := []*BackfillChunk{}
+	for _, symbol := range r.Symbols {
+		for start := r.StartDate; !start.After(r.EndDate); start = start.AddDate(0, 0, r.ChunkDays) {
+			end := start.AddDate(0, 0, r.ChunkDays-1)
+			if end.After(r.EndDate) {
+				end = r.EndDate
+			}
+			chunks = append(chunks, &BackfillChunk{
+				RunID:     r.RunID,
+				Symbol:    symbol,
+				StartDate: start,
+				EndDate:   end,
+				Status:    JobStatusPending,
+			})
+		}
+	}
+	return chunks
+}
+
+// BackfillChunk is the checkpoint of one symbol and date range of a backfill run.
+type BackfillChunk struct {
+	ChunkID          int       `json:"chunk_id"`
+	RunID            int       `json:"run_id"`
+	Symbol           string    `json:"symbol"`
+	StartDate        time.Time `json:"start_date"`
+	EndDate          time.Time `json:"end_date"`
+	Status           string    `json:"status"` // Uses the job statuses, e.g. JobStatusPending
+	Attempts         int       `json:"attempts"`
+	RecordsProcessed int       `json:"records_processed"`
+	ErrorMessage     string    `json:"error_message"`
+	LastUpdated      time.Time `json:"last_updated"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackfillRun_Chunks verifies the date range is split into consecutive chunks per symbol,
+// with a shorter last chunk that ends on the end date.
+func TestBackfillRun_Chunks(t *testing.T) {
+	run := &BackfillRun{
+		Symbols:   []string{"AAPL", "MSFT"},
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+		ChunkDays: 10,
+	}
+
+	chunks := run.Chunks()
+	if len(chunks) != 6 {
+		t.Fatalf("Expected 3 chunks per symbol, got %d", len(chunks))
+	}
+
+	expected := [][2]int{{1, 10}, {11, 20}, {21, 25}}
+	for i, chunk := range chunks[:3] {
+		if chunk.Symbol != "AAPL" || chunk.StartDate.Day() != expected[i][0] || chunk.EndDate.Day() != expected[i][1] {
+			t.Errorf("Chunk %d: expected AAPL from day %d to %d, got %s from %v to %v",
+				i, expected[i][0], expected[i][1], chunk.Symbol, chunk.StartDate, chunk.EndDate)
+		}
+	}
+	if chunks[3].Symbol != "MSFT" || !chunks[3].StartDate.Equal(run.StartDate) {
+		t.Errorf("Expected the second symbol to start over at the start date, got %s from %v", chunks[3].Symbol, chunks[3].StartDate)
+	}
+}
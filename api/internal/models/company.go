@@ -7,14 +7,23 @@ import (
 
 // Company represents a company entity in the database
 type Company struct {
-	CompanyID   int       `json:"company_id"`
-	Symbol      string    `json:"symbol"`
-	Name        string    `json:"name"`
-	Sector      string    `json:"sector"`
-	Industry    string    `json:"industry"`
-	Exchange    string    `json:"exchange"`
-	IsActive    bool      `json:"is_active"`
-	LastUpdated time.Time `json:"last_updated"`
+	CompanyID      int       `json:"company_id"`
+	Symbol         string    `json:"symbol"`
+	Name           string    `json:"name"`
+	Sector         string    `json:"sector"`
+	Industry       string    `json:"industry"`
+	Exchange       string    `json:"exchange"`
+	IsActive       bool      `json:"is_active"`
+	ProfileSource  string    `json:"profile_source"`  // Provider the profile came from, or Manual
+	ProfileUpdated time.Time `json:"profile_updated"` // Zero until a profile is stored
+	ProfileChecked time.Time `json:"profile_checked"` // When enrichment last requested a profile, zero if never
+	LastUpdated    time.Time `json:"last_updated"`
+}
+
+// IsPlaceholder reports whether the company was only created for its stock prices, with its symbol as
+// name and no sector, industry or exchange, and has not received a profile since.
+func (c *Company) IsPlaceholder() bool {
+	return c.ProfileUpdated.IsZero()
 }
 
 // Validate ensures the stock data meets all logical rules
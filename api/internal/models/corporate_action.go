@@ -0,0 +1,53 @@
+package models
+
+import (
+	"pocketanalyst/pkg/errors"
+	"time"
+)
+
+// Corporate action types stored in corporate_actions.action_type
+const (
+	CorporateActionSplit    = "SPLIT"
+	CorporateActionDividend = "DIVIDEND"
+)
+
+// CorporateAction is a split or cash dividend changing how past prices compare to current ones.
+// A split has a Ratio of new shares per old share, e.g. 4 for a 4-for-1 split and 0.1 for a 1-for-10
+// reverse split. A dividend has the cash Amount paid per share.
+type CorporateAction struct {
+	ActionID    int       `json:"action_id"`
+	CompanyID   int       `json:"company_id"`
+	Symbol      string    `json:"symbol"`
+	ActionType  string    `json:"action_type"`
+	ExDate      time.Time `json:"ex_date"` // First trading day without the dividend or at the split-adjusted price
+	Ratio       float64   `json:"ratio,omitempty"`
+	Amount      float64   `json:"amount,omitempty"`
+	Source      string    `json:"source"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// Validate ensures the corporate action meets all logical requirements
+func (a *CorporateAction) Validate() error {
+	switch {
+	case a.Symbol == "":
+		return errors.NewModelValidationError("CorporateAction", "symbol", "symbol is required")
+	case a.ExDate.IsZero():
+		return errors.NewModelValidationError("CorporateAction", "ex_date", "ex_date is required")
+	case a.Source == "":
+		return errors.NewModelValidationError("CorporateAction", "source", "source is required")
+	}
+
+	switch a.ActionType {
+	case CorporateActionSplit:
+		if a.Ratio <= 0 || a.Ratio == 1 {
+			return errors.NewModelValidationError("CorporateAction", "ratio", "a split needs a positive ratio other than 1")
+		}
+	case CorporateActionDividend:
+		if a.Amount <= 0 {
+			return errors.NewModelValidationError("CorporateAction", "amount", "a dividend needs a positive amount")
+		}
+	default:
+		return errors.NewModelValidationError("CorporateAction", "action_type", "action_type must be SPLIT or DIVIDEND")
+	}
+	return nil
+}
@@ -1,10 +1,52 @@
 package models
 
 import (
+	"fmt"
 	"pocketanalyst/pkg/errors"
+	"slices"
+	"strings"
 	"time"
 )
 
+// Job statuses stored in data_fetch_jobs.status
+const (
+	JobStatusPending = "PENDING"
+	JobStatusRunning = "RUNNING"
+	JobStatusSuccess = "SUCCESS"
+	JobStatusFailed  = "FAILED"
+)
+
+// Frequencies a data fetch job can run at
+const (
+	FrequencyHourly  = "hourly"
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyOnce    = "once" // Runs a single time, then the job is deactivated
+)
+
+// Data types a data fetch job can fetch
+const (
+	JobDataTypePrice            = "PRICE"             // Daily prices of a SYMBOL
+	JobDataTypeGapRepair        = "GAP_REPAIR"        // Missing prices of a SYMBOL or of every active company (MARKET)
+	JobDataTypeCorporateActions = "CORPORATE_ACTIONS" // Splits and dividends of a SYMBOL
+	JobDataTypeCompanyProfile   = "COMPANY_PROFILE"   // Profile of a SYMBOL or of placeholder companies (MARKET)
+)
+
+// Entity types a data fetch job can run for
+const (
+	JobEntitySymbol = "SYMBOL"
+	JobEntityMarket = "MARKET"
+)
+
+// jobEntityTypes lists the entity types each data type can run for.
+var jobEntityTypes = map[string][]string{
+	JobDataTypePrice:            {JobEntitySymbol},
+	JobDataTypeGapRepair:        {JobEntitySymbol, JobEntityMarket},
+	JobDataTypeCorporateActions: {JobEntitySymbol},
+	JobDataTypeCompanyProfile:   {JobEntitySymbol, JobEntityMarket},
+}
+
 // DataFetchJob represents a scheduled data fetching job
 type DataFetchJob struct {
 	JobID         int            `json:"job_id"`
@@ -14,12 +56,13 @@ type DataFetchJob struct {
 	DataType      string         `json:"data_type"`
 	Frequency     string         `json:"frequency"`
 	Parameters    map[string]any `json:"parameters"`
-	LastExecution time.Time      `json:"last_execution"`
-	LastSuccess   time.Time      `json:"last_success"`
+	LastExecution time.Time      `json:"last_execution"` // Zero if the job never ran
+	LastSuccess   time.Time      `json:"last_success"`   // Zero if the job never succeeded
 	NextScheduled time.Time      `json:"next_scheduled"`
+	RunRequested  time.Time      `json:"run_requested"` // Zero unless a run outside of the schedule is pending
 	Status        string         `json:"status"`
-	IsActive      string         `json:"is_active"`
-	CreatedAt     string         `json:"created_at"`
+	IsActive      bool           `json:"is_active"`
+	LastUpdated   time.Time      `json:"last_updated"`
 }
 
 // Validate ensures the data fetch job meets all logical requirements
@@ -38,5 +81,46 @@ func (j *DataFetchJob) Validate() error {
 	case j.NextScheduled.IsZero():
 		return errors.NewModelValidationError("DataFetchJob", "next_scheduled", "next scheduled time is required")
 	}
+
+	entityTypes, ok := jobEntityTypes[j.DataType]
+	if !ok {
+		return errors.NewModelValidationError("DataFetchJob", "data_type",
+			fmt.Sprintf("unknown data type %q, expected PRICE, GAP_REPAIR, CORPORATE_ACTIONS or COMPANY_PROFILE", j.DataType))
+	}
+	if !slices.Contains(entityTypes, j.EntityType) {
+		return errors.NewModelValidationError("DataFetchJob", "entity_type",
+			fmt.Sprintf("%s jobs run for entity type %s, not %q", j.DataType, strings.Join(entityTypes, " or "), j.EntityType))
+	}
+
+	if _, err := j.NextRunAfter(j.NextScheduled); err != nil {
+		return err
+	}
 	return nil
 }
+
+// DueAt returns when the job is due: its next scheduled run, or the pending run request if that is earlier.
+func (j *DataFetchJob) DueAt() time.Time {
+	if !j.RunRequested.IsZero() && j.RunRequested.Before(j.NextScheduled) {
+		return j.RunRequested
+	}
+	return j.NextScheduled
+}
+
+// NextRunAfter returns when the job should run next if its last run was scheduled for t.
+// The zero time is returned for jobs that only run once.
+func (j *DataFetchJob) NextRunAfter(t time.Time) (time.Time, error) {
+	switch j.Frequency {
+	case FrequencyOnce:
+		return time.Time{}, nil
+	case FrequencyHourly:
+		return t.Add(time.Hour), nil
+	case FrequencyDaily:
+		return t.AddDate(0, 0, 1), nil
+	case FrequencyWeekly:
+		return t.AddDate(0, 0, 7), nil
+	case FrequencyMonthly:
+		return t.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, errors.NewModelValidationError("DataFetchJob", "frequency",
+		fmt.Sprintf("unknown frequency %q, expected once, hourly, daily, weekly or monthly", j.Frequency))
+}
@@ -11,11 +11,12 @@ type DataSource struct {
 	SourceName         string         `json:"source_name"`
 	SourceType         string         `json:"source_type"`
 	BaseURL            string         `json:"base_url"`
-	RateLimitPerMinute string         `json:"rate_limit_per_minute"`
-	RateLimitPerDay    string         `json:"rate_limit_per_day"`
+	RateLimitPerMinute int            `json:"rate_limit_per_minute"` // 0 means unlimited
+	RateLimitPerDay    int            `json:"rate_limit_per_day"`    // 0 means unlimited
 	ConfigParameters   map[string]any `json:"config_parameters"`
 	IsActive           bool           `json:"is_active"`
 	CreatedAt          time.Time      `json:"created_at"`
+	LastUpdated        time.Time      `json:"last_updated"`
 }
 
 // Validate ensures the data source meets all logical requirements
@@ -25,6 +26,10 @@ func (ds *DataSource) Validate() error {
 		return errors.NewModelValidationError("DataSource", "source_name", "source_name cannot be empty")
 	case ds.SourceType == "":
 		return errors.NewModelValidationError("DataSource", "source_type", "source_type cannot be empty")
+	case ds.RateLimitPerMinute < 0:
+		return errors.NewModelValidationError("DataSource", "rate_limit_per_minute", "rate_limit_per_minute cannot be negative")
+	case ds.RateLimitPerDay < 0:
+		return errors.NewModelValidationError("DataSource", "rate_limit_per_day", "rate_limit_per_day cannot be negative")
 	}
 	return nil
 }
@@ -4,16 +4,21 @@ import (
 	"time"
 )
 
+// Job types stored in job_execution_logs.job_type
+const (
+	JobTypeDataFetch = "DATA_FETCH"
+)
+
 // JobExecutionLog represents a log entry for a job execution
 type JobExecutionLog struct {
 	LogID            int            `json:"log_id"`
-	JobID            int            `json:"job_id"`
+	JobID            int            `json:"job_id,omitempty"` // 0 for runs that were not started by a data fetch job
 	JobType          string         `json:"job_type"`
 	StartTime        time.Time      `json:"start_time"`
-	End              time.Time      `json:"end_time"`
+	EndTime          time.Time      `json:"end_time"` // Zero while the job is running
 	Status           string         `json:"status"`
 	RecordsProcessed int            `json:"records_processed"`
 	ErrorMessage     string         `json:"error_message"`
 	Details          map[string]any `json:"details"`
-	CreatedAt        time.Time      `json:"created_at"`
+	LastUpdated      time.Time      `json:"last_updated"`
 }
@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// backfillRunSelect selects every run with the chunk counts scanned by scanBackfillRun.
+const backfillRunSelect = `
+	SELECT r.run_id, r.symbols, r.start_date, r.end_date, r.chunk_days, r.status, r.created_at, r.last_updated,
+	       COUNT(c.chunk_id),
+	       COUNT(c.chunk_id) FILTER (WHERE c.status = 'PENDING'),
+	       COUNT(c.chunk_id) FILTER (WHERE c.status = 'RUNNING'),
+	       COUNT(c.chunk_id) FILTER (WHERE c.status = 'SUCCESS'),
+	       COUNT(c.chunk_id) FILTER (WHERE c.status = 'FAILED'),
+	       COALESCE(SUM(c.records_processed), 0)
+	FROM backfill_runs r
+	LEFT JOIN backfill_chunks c ON c.run_id = r.run_id
+`
+
+// BackfillRepository handles DB operations for backfill runs and their chunks
+type BackfillRepository struct {
+	db *sql.DB
+}
+
+// NewBackfillRepository creates a new backfill repository
+func NewBackfillRepository(db *sql.DB) *BackfillRepository {
+	return &BackfillRepository{db: db}
+}
+
+// CreateRun stores a new run together with its chunks in a single transaction and returns it.
+// Usually the chunks are run.Chunks(), but targeted runs may cover only parts of the run's range.
+func (br *BackfillRepository) CreateRun(
+	ctx context.Context,
+	run *models.BackfillRun,
+	chunks []*models.BackfillChunk,
+) (*models.BackfillRun, error) {
+	if err := run.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	var runID int
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO backfill_runs (symbols, start_date, end_date, chunk_days, status, created_at, last_updated)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING run_id
+		`,
+		pq.Array(run.Symbols),
+		run.StartDate,
+		run.EndDate,
+		run.ChunkDays,
+		models.BackfillStatusRunning,
+	).Scan(&runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backfill run: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO backfill_chunks (run_id, symbol, start_date, end_date, status, last_updated)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare chunk insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, chunk := range chunks {
+		if _, err := stmt.ExecContext(ctx, runID, chunk.Symbol, chunk.StartDate, chunk.EndDate, chunk.Status); err != nil {
+			return nil, fmt.Errorf("failed to create backfill chunk for %s: %w", chunk.Symbol, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return br.GetRun(ctx, runID)
+}
+
+// GetRun retrieves a run with its progress. A NotFoundError is returned if there is no such run.
+func (br *BackfillRepository) GetRun(ctx context.Context, runID int) (*models.BackfillRun, error) {
+	row := br.db.QueryRowContext(ctx, backfillRunSelect+` WHERE r.run_id = $1 GROUP BY r.run_id`, runID)
+
+	run, err := scanBackfillRun(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("BackfillRun", runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving backfill run %d: %w", runID, err)
+	}
+	return run, nil
+}
+
+// ListRuns returns every run with its progress, newest first.
+func (br *BackfillRepository) ListRuns(ctx context.Context) ([]*models.BackfillRun, error) {
+	rows, err := br.db.QueryContext(ctx, backfillRunSelect+` GROUP BY r.run_id ORDER BY r.run_id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backfill runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*models.BackfillRun{}
+	for rows.Next() {
+		run, err := scanBackfillRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backfill run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating backfill runs: %w", err)
+	}
+	return runs, nil
+}
+
+// SetRunStatus moves a run to status if its current status is one of from. A ModelValidationError is
+// returned if the run is in any other status.
+func (br *BackfillRepository) SetRunStatus(ctx context.Context, runID int, status string, from ...string) (*models.BackfillRun, error) {
+	result, err := br.db.ExecContext(
+		ctx,
+		`UPDATE backfill_runs SET status = $2, last_updated = NOW() WHERE run_id = $1 AND status = ANY($3)`,
+		runID,
+		status,
+		pq.Array(from),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update backfill run %d: %w", runID, err)
+	}
+
+	run, err := br.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return nil, errors.NewModelValidationError("BackfillRun", "status",
+			fmt.Sprintf("cannot change a %s run to %s", run.Status, status))
+	}
+	return run, nil
+}
+
+// ClaimChunk marks the next pending chunk of a running run as RUNNING and returns it, or nil if there is
+// nothing to do. Chunks are locked with FOR UPDATE SKIP LOCKED, so concurrent workers never claim the same
+// chunk. Chunks stuck in RUNNING for longer than staleAfter, e.g. because the server restarted while they ran,
+// are claimed again.
+func (br *BackfillRepository) ClaimChunk(ctx context.Context, staleAfter time.Duration) (*models.BackfillChunk, error) {
+	var chunk models.BackfillChunk
+	err := br.db.QueryRowContext(
+		ctx,
+		`
+		UPDATE backfill_chunks
+		SET status = 'RUNNING', last_updated = NOW()
+		WHERE chunk_id = (
+			SELECT c.chunk_id
+			FROM backfill_chunks c
+			JOIN backfill_runs r ON r.run_id = c.run_id
+			WHERE r.status = $1
+			AND (c.status = 'PENDING' OR (c.status = 'RUNNING' AND c.last_updated < NOW() - make_interval(secs => $2)))
+			ORDER BY c.run_id, c.chunk_id
+			LIMIT 1
+			FOR UPDATE OF c SKIP LOCKED
+		)
+		RETURNING chunk_id, run_id, symbol, start_date, end_date, status, attempts, records_processed
+		`,
+		models.BackfillStatusRunning,
+		staleAfter.Seconds(),
+	).Scan(
+		&chunk.ChunkID,
+		&chunk.RunID,
+		&chunk.Symbol,
+		&chunk.StartDate,
+		&chunk.EndDate,
+		&chunk.Status,
+		&chunk.Attempts,
+		&chunk.RecordsProcessed,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim backfill chunk: %w", err)
+	}
+	return &chunk, nil
+}
+
+// FinishChunk stores the status, attempts, record count and error message of a claimed chunk. Once no chunk
+// of its run is left to do, the run is marked COMPLETED, or FAILED if any chunk failed.
+func (br *BackfillRepository) FinishChunk(ctx context.Context, chunk *models.BackfillChunk) error {
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		UPDATE backfill_chunks
+		SET status = $2, attempts = $3, records_processed = $4, error_message = NULLIF($5, ''), last_updated = NOW()
+		WHERE chunk_id = $1
+		`,
+		chunk.ChunkID,
+		chunk.Status,
+		chunk.Attempts,
+		chunk.RecordsProcessed,
+		chunk.ErrorMessage,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update backfill chunk %d: %w", chunk.ChunkID, err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		UPDATE backfill_runs
+		SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM backfill_chunks WHERE run_id = $1 AND status = 'FAILED') THEN $2
+			ELSE $3
+		END,
+		last_updated = NOW()
+		WHERE run_id = $1
+		AND status IN ($4, $5)
+		AND NOT EXISTS (SELECT 1 FROM backfill_chunks WHERE run_id = $1 AND status IN ('PENDING', 'RUNNING'))
+		`,
+		chunk.RunID,
+		models.BackfillStatusFailed,
+		models.BackfillStatusCompleted,
+		models.BackfillStatusRunning,
+		models.BackfillStatusPaused,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update backfill run %d: %w", chunk.RunID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// scanBackfillRun scans a row selected by backfillRunSelect.
+func scanBackfillRun(row rowScanner) (*models.BackfillRun, error) {
+	var run models.BackfillRun
+	var createdAt, lastUpdated sql.NullTime
+
+	err := row.Scan(
+		&run.RunID,
+		pq.Array(&run.Symbols),
+		&run.StartDate,
+		&run.EndDate,
+		&run.ChunkDays,
+		&run.Status,
+		&createdAt,
+		&lastUpdated,
+		&run.TotalChunks,
+		&run.PendingChunks,
+		&run.RunningChunks,
+		&run.CompletedChunks,
+		&run.FailedChunks,
+		&run.RecordsProcessed,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	run.CreatedAt = createdAt.Time
+	run.LastUpdated = lastUpdated.Time
+	return &run, nil
+}
@@ -0,0 +1,254 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+
+	"github.com/lib/pq"
+)
+
+// CompanyRepository handles DB operations for companies
+type CompanyRepository struct {
+	db *sql.DB
+}
+
+// NewCompanyRepository creates a new company repository
+func NewCompanyRepository(db *sql.DB) *CompanyRepository {
+	return &CompanyRepository{db: db}
+}
+
+// ListActiveSymbols returns the symbols of all active companies in alphabetical order.
+func (cr *CompanyRepository) ListActiveSymbols(ctx context.Context) ([]string, error) {
+	rows, err := cr.db.QueryContext(
+		ctx,
+		`SELECT symbol FROM companies WHERE COALESCE(is_active, TRUE) ORDER BY symbol`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active companies: %w", err)
+	}
+	defer rows.Close()
+
+	symbols := []string{}
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, fmt.Errorf("failed to scan company symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating companies: %w", err)
+	}
+	return symbols, nil
+}
+
+// GetExchange returns the exchange a company is listed on. It is empty for unknown symbols and
+// companies without an exchange.
+func (cr *CompanyRepository) GetExchange(ctx context.Context, symbol string) (string, error) {
+	var exchange sql.NullString
+	err := cr.db.QueryRowContext(
+		ctx,
+		`SELECT exchange FROM companies WHERE symbol = $1`,
+		symbol,
+	).Scan(&exchange)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query exchange of %s: %w", symbol, err)
+	}
+	return exchange.String, nil
+}
+
+// companyColumns are the columns of companies scanned by scanCompany, in order.
+const companyColumns = `company_id, symbol, name, sector, industry, exchange, is_active, profile_source,
+	profile_updated, profile_checked, last_updated`
+
+// GetBySymbol retrieves a company. A NotFoundError is returned if there is no such company.
+func (cr *CompanyRepository) GetBySymbol(ctx context.Context, symbol string) (*models.Company, error) {
+	row := cr.db.QueryRowContext(ctx, `SELECT `+companyColumns+` FROM companies WHERE symbol = $1`, symbol)
+
+	company, err := scanCompany(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("Company", symbol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving company %s: %w", symbol, err)
+	}
+	return company, nil
+}
+
+// ListPlaceholders returns up to limit active companies that never received a profile, the ones checked longest
+// ago first.
+func (cr *CompanyRepository) ListPlaceholders(ctx context.Context, limit int) ([]*models.Company, error) {
+	rows, err := cr.db.QueryContext(
+		ctx,
+		`SELECT `+companyColumns+` FROM companies
+		WHERE profile_updated IS NULL AND COALESCE(is_active, TRUE)
+		ORDER BY profile_checked NULLS FIRST, symbol
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query placeholder companies: %w", err)
+	}
+	defer rows.Close()
+
+	companies := []*models.Company{}
+	for rows.Next() {
+		company, err := scanCompany(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan company: %w", err)
+		}
+		companies = append(companies, company)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating companies: %w", err)
+	}
+	return companies, nil
+}
+
+// MarkProfileChecked records that the profile of a company was requested now.
+func (cr *CompanyRepository) MarkProfileChecked(ctx context.Context, symbol string) error {
+	_, err := cr.db.ExecContext(ctx, `UPDATE companies SET profile_checked = NOW() WHERE symbol = $1`, symbol)
+	if err != nil {
+		return fmt.Errorf("failed to mark the profile of %s as checked: %w", symbol, err)
+	}
+	return nil
+}
+
+// Create inserts a new company and returns it as stored. A ConflictError is returned if the symbol exists.
+func (cr *CompanyRepository) Create(ctx context.Context, company *models.Company) (*models.Company, error) {
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+
+	row := cr.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO companies
+		(symbol, name, sector, industry, exchange, is_active, profile_source, profile_updated, last_updated)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, NULLIF($7, ''), $8, NOW())
+		RETURNING `+companyColumns,
+		company.Symbol,
+		company.Name,
+		company.Sector,
+		company.Industry,
+		company.Exchange,
+		company.IsActive,
+		company.ProfileSource,
+		sql.NullTime{Time: company.ProfileUpdated, Valid: !company.ProfileUpdated.IsZero()},
+	)
+
+	created, err := scanCompany(row)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return nil, companyConflictError(company.Symbol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create company %s: %w", company.Symbol, err)
+	}
+	return created, nil
+}
+
+// Update stores the name, sector, industry, exchange, is_active and profile of an existing company.
+func (cr *CompanyRepository) Update(ctx context.Context, company *models.Company) (*models.Company, error) {
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+
+	row := cr.db.QueryRowContext(
+		ctx,
+		`
+		UPDATE companies
+		SET name = $2, sector = NULLIF($3, ''), industry = NULLIF($4, ''), exchange = NULLIF($5, ''),
+		is_active = $6, profile_source = NULLIF($7, ''), profile_updated = $8, last_updated = NOW()
+		WHERE symbol = $1
+		RETURNING `+companyColumns,
+		company.Symbol,
+		company.Name,
+		company.Sector,
+		company.Industry,
+		company.Exchange,
+		company.IsActive,
+		company.ProfileSource,
+		sql.NullTime{Time: company.ProfileUpdated, Valid: !company.ProfileUpdated.IsZero()},
+	)
+
+	updated, err := scanCompany(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("Company", company.Symbol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update company %s: %w", company.Symbol, err)
+	}
+	return updated, nil
+}
+
+// Delete removes a company. A ConflictError is returned while stock prices or corporate actions reference it.
+func (cr *CompanyRepository) Delete(ctx context.Context, symbol string) error {
+	result, err := cr.db.ExecContext(ctx, `DELETE FROM companies WHERE symbol = $1`, symbol)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" { // foreign_key_violation
+		return companyInUseError(symbol)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete company %s: %w", symbol, err)
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return errors.NewNotFoundError("Company", symbol)
+	}
+	return nil
+}
+
+// companyConflictError reports a company whose symbol is taken.
+func companyConflictError(symbol string) error {
+	return errors.NewConflictError("Company", fmt.Sprintf("company %s already exists", symbol))
+}
+
+// companyInUseError reports a company that cannot be deleted because data references it.
+func companyInUseError(symbol string) error {
+	return errors.NewConflictError("Company",
+		fmt.Sprintf("company %s still has stock prices or corporate actions", symbol))
+}
+
+// scanCompany scans the companyColumns of a single row, mapping NULLs onto zero values.
+func scanCompany(row rowScanner) (*models.Company, error) {
+	var company models.Company
+	var sector, industry, exchange, profileSource sql.NullString
+	var isActive sql.NullBool
+	var profileUpdated, profileChecked, lastUpdated sql.NullTime
+
+	err := row.Scan(
+		&company.CompanyID,
+		&company.Symbol,
+		&company.Name,
+		&sector,
+		&industry,
+		&exchange,
+		&isActive,
+		&profileSource,
+		&profileUpdated,
+		&profileChecked,
+		&lastUpdated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	company.Sector = sector.String
+	company.Industry = industry.String
+	company.Exchange = exchange.String
+	company.IsActive = !isActive.Valid || isActive.Bool // Column defaults to TRUE
+	company.ProfileSource = profileSource.String
+	company.ProfileUpdated = profileUpdated.Time
+	company.ProfileChecked = profileChecked.Time
+	company.LastUpdated = lastUpdated.Time
+	return &company, nil
+}
@@ -0,0 +1,749 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"pocketanalyst/internal/migrations"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3" // SQLite driver for TestSQLiteRepositories_Conformance
+)
+
+// Names used by the conformance tests, so the PostgreSQL run can clean up after itself.
+const (
+	conformanceSymbol   = "ZZCONF"
+	conformanceRejected = "ZZCONFREJ"
+	conformanceSourceA  = "ConformanceA"
+	conformanceSourceB  = "ConformanceB"
+	conformanceInactive = "ConformanceInactive"
+)
+
+// stores bundles the repositories of one storage backend.
+type stores struct {
+	stocks      StockStore
+	companies   CompanyStore
+	dataSources DataSourceStore
+	jobs        DataFetchJobStore
+	logs        JobExecutionLogStore
+}
+
+// TestMemoryRepositories_Conformance runs the conformance tests against the in-memory repositories.
+func TestMemoryRepositories_Conformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) stores {
+		db := NewMemoryDB()
+		dataSources := NewMemoryDataSourceRepository(db)
+		return stores{
+			stocks:      NewMemoryStockRepository(db, dataSources),
+			companies:   NewMemoryCompanyRepository(db),
+			dataSources: dataSources,
+			jobs:        NewMemoryDataFetchJobRepository(db),
+			logs:        NewMemoryJobExecutionLogRepository(db),
+		}
+	})
+}
+
+// TestPostgresRepositories_Conformance runs the conformance tests against the PostgreSQL repositories.
+func TestPostgresRepositories_Conformance(t *testing.T) {
+	db := openTestDB(t)
+	runConformanceTests(t, func(t *testing.T) stores {
+		deleteConformanceData(t, db)
+		t.Cleanup(func() { deleteConformanceData(t, db) })
+
+		dataSources := NewDataSourceRepository(db)
+		return stores{
+			stocks:      NewStockRepository(db, dataSources),
+			companies:   NewCompanyRepository(db),
+			dataSources: dataSources,
+			jobs:        NewDataFetchJobRepository(db),
+			logs:        NewJobExecutionLogRepository(db),
+		}
+	})
+}
+
+// TestSQLiteRepositories_Conformance runs the conformance tests against the SQLite repositories, each subtest
+// on a new database file. It is skipped unless a SQLite driver is compiled in, see sqlite_driver_test.go.
+func TestSQLiteRepositories_Conformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) stores {
+		db := openSQLiteTestDB(t)
+		dataSources := NewSQLiteDataSourceRepository(db)
+		return stores{
+			stocks:      NewSQLiteStockRepository(db, dataSources),
+			companies:   NewSQLiteCompanyRepository(db),
+			dataSources: dataSources,
+			jobs:        NewSQLiteDataFetchJobRepository(db),
+			logs:        NewSQLiteJobExecutionLogRepository(db),
+		}
+	})
+}
+
+// openSQLiteTestDB creates a SQLite database migrated to the latest schema in a temporary directory.
+func openSQLiteTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(tb.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		tb.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	tb.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db, migrations.SQLite)
+	if err != nil {
+		tb.Fatalf("Failed to create migrator: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		tb.Fatalf("Failed to migrate SQLite database: %v", err)
+	}
+	return db
+}
+
+// deleteConformanceData removes everything the conformance tests store.
+func deleteConformanceData(tb testing.TB, db *sql.DB) {
+	tb.Helper()
+
+	symbols := pq.Array([]string{conformanceSymbol, conformanceRejected})
+	sources := pq.Array([]string{conformanceSourceA, conformanceSourceB, conformanceInactive})
+	for _, statement := range []struct {
+		query string
+		arg   any
+	}{
+		{`DELETE FROM job_execution_logs WHERE job_id IN (SELECT job_id FROM data_fetch_jobs
+			WHERE source_id IN (SELECT source_id FROM data_sources WHERE source_name = ANY($1)))`, sources},
+		{`DELETE FROM data_fetch_jobs WHERE source_id IN (SELECT source_id FROM data_sources WHERE source_name = ANY($1))`, sources},
+		{`DELETE FROM stock_prices WHERE symbol = ANY($1)`, symbols},
+		{`DELETE FROM companies WHERE symbol = ANY($1)`, symbols},
+		{`DELETE FROM data_sources WHERE source_name = ANY($1)`, sources},
+	} {
+		if _, err := db.Exec(statement.query, statement.arg); err != nil {
+			tb.Fatalf("Failed to delete conformance test data: %v", err)
+		}
+	}
+}
+
+// conformanceStock returns a bar of the conformance symbol on the given day of January 2024.
+func conformanceStock(day int, source string, closePrice float64) *models.Stock {
+	return &models.Stock{
+		Symbol:           conformanceSymbol,
+		Date:             time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		OpenPrice:        closePrice - 1,
+		HighPrice:        closePrice + 1,
+		LowPrice:         closePrice - 2,
+		ClosePrice:       closePrice,
+		AdjustedClose:    closePrice,
+		Volume:           1000000,
+		SplitCoefficient: 1,
+		DataSource:       source,
+	}
+}
+
+// runConformanceTests verifies a storage backend behaves like the PostgreSQL repositories. newStores is
+// called by every subtest and must return repositories without any of the conformance test data.
+func runConformanceTests(t *testing.T, newStores func(t *testing.T) stores) {
+	ctx := context.Background()
+	january := func(day int) time.Time { return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC) }
+
+	t.Run("UpsertStocks", func(t *testing.T) {
+		s := newStores(t)
+
+		stocks := []*models.Stock{
+			conformanceStock(4, conformanceSourceA, 101),
+			conformanceStock(3, conformanceSourceA, 100),
+			conformanceStock(2, conformanceSourceA, 99),
+		}
+		saved, err := s.stocks.SaveStocksToDatabase(ctx, stocks)
+		if err != nil {
+			t.Fatalf("Expected the stocks to be saved, got %v", err)
+		}
+		if saved.Inserted != 3 || saved.Updated != 0 || saved.Unchanged != 0 {
+			t.Errorf("Expected 3 inserts, got %+v", saved)
+		}
+		for _, stock := range stocks {
+			if stock.PriceID == 0 || stock.CompanyID == 0 {
+				t.Fatalf("Expected PriceID and CompanyID to be set, got %d and %d", stock.PriceID, stock.CompanyID)
+			}
+		}
+
+		// Saving the same values again changes nothing
+		saved, err = s.stocks.SaveStocksToDatabase(ctx, stocks)
+		if err != nil || saved.Unchanged != 3 {
+			t.Errorf("Expected 3 unchanged rows, got %+v, %v", saved, err)
+		}
+
+		// One changed row and the same day from another source, which is a row of its own
+		changed := conformanceStock(4, conformanceSourceA, 105)
+		other := conformanceStock(4, conformanceSourceB, 101)
+		saved, err = s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{changed, other, stocks[1]})
+		if err != nil {
+			t.Fatalf("Expected the stocks to be saved, got %v", err)
+		}
+		if saved.Inserted != 1 || saved.Updated != 1 || saved.Unchanged != 1 {
+			t.Errorf("Expected 1 insert, 1 update and 1 unchanged row, got %+v", saved)
+		}
+		if changed.PriceID != stocks[0].PriceID {
+			t.Errorf("Expected the update to keep PriceID %d, got %d", stocks[0].PriceID, changed.PriceID)
+		}
+
+		retrieved, err := s.stocks.RetrieveStocksFromDatabase(ctx, conformanceSymbol, january(1), january(31))
+		if err != nil {
+			t.Fatalf("Expected the stocks to be retrieved, got %v", err)
+		}
+		if len(retrieved) != 4 {
+			t.Fatalf("Expected 4 stored rows, got %d", len(retrieved))
+		}
+		for i := 1; i < len(retrieved); i++ {
+			if retrieved[i].Date.After(retrieved[i-1].Date) {
+				t.Errorf("Expected the newest row first, got %v before %v", retrieved[i-1].Date, retrieved[i].Date)
+			}
+		}
+		for _, stock := range retrieved {
+			if stock.Date.Equal(january(4)) && stock.DataSource == conformanceSourceA && stock.ClosePrice != 105 {
+				t.Errorf("Expected the updated close 105, got %v", stock.ClosePrice)
+			}
+			if stock.DataSource != conformanceSourceA && stock.DataSource != conformanceSourceB {
+				t.Errorf("Expected the source name, got %q", stock.DataSource)
+			}
+		}
+
+		// The range is inclusive on both ends
+		retrieved, err = s.stocks.RetrieveStocksFromDatabase(ctx, conformanceSymbol, january(2), january(3))
+		if err != nil || len(retrieved) != 2 {
+			t.Errorf("Expected 2 rows between the 2nd and 3rd, got %d, %v", len(retrieved), err)
+		}
+	})
+
+	t.Run("StoresColumnPrecision", func(t *testing.T) {
+		s := newStores(t)
+
+		stock := conformanceStock(5, conformanceSourceA, 100.123456)
+		if _, err := s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{stock}); err != nil {
+			t.Fatalf("Expected the stock to be saved, got %v", err)
+		}
+
+		retrieved, err := s.stocks.RetrieveStocksFromDatabase(ctx, conformanceSymbol, january(5), january(5))
+		if err != nil || len(retrieved) != 1 {
+			t.Fatalf("Expected 1 row, got %d, %v", len(retrieved), err)
+		}
+		if retrieved[0].ClosePrice != 100.12346 || retrieved[0].OpenPrice != 99.12346 {
+			t.Errorf("Expected prices with 5 decimals, got close %v and open %v", retrieved[0].ClosePrice, retrieved[0].OpenPrice)
+		}
+
+		// The rounded values compare equal to the stored ones
+		saved, err := s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{stock})
+		if err != nil || saved.Unchanged != 1 {
+			t.Errorf("Expected the same values to be unchanged, got %+v, %v", saved, err)
+		}
+	})
+
+	t.Run("CreatesCompaniesAndSources", func(t *testing.T) {
+		s := newStores(t)
+
+		if _, err := s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{conformanceStock(2, conformanceSourceA, 99)}); err != nil {
+			t.Fatalf("Expected the stock to be saved, got %v", err)
+		}
+
+		symbols, err := s.companies.ListActiveSymbols(ctx)
+		if err != nil || !slices.Contains(symbols, conformanceSymbol) {
+			t.Errorf("Expected %s to be an active company, got %v, %v", conformanceSymbol, symbols, err)
+		}
+		if exchange, err := s.companies.GetExchange(ctx, conformanceSymbol); err != nil || exchange != "" {
+			t.Errorf("Expected no exchange for a created company, got %q, %v", exchange, err)
+		}
+
+		ds, err := s.dataSources.GetByName(ctx, conformanceSourceA)
+		if err != nil {
+			t.Fatalf("Expected the source to be registered, got %v", err)
+		}
+		if ds.SourceType != "PRICE" || !ds.IsActive || ds.SourceID == 0 {
+			t.Errorf("Expected an active PRICE source, got %+v", ds)
+		}
+	})
+
+	t.Run("RejectsInvalidStocks", func(t *testing.T) {
+		s := newStores(t)
+
+		valid := conformanceStock(2, conformanceSourceA, 99)
+		valid.Symbol = conformanceRejected
+		invalid := conformanceStock(3, conformanceSourceA, 99)
+		invalid.Symbol = conformanceRejected
+		invalid.ClosePrice = -1
+
+		var validationErr *errors.ModelValidationError
+		_, err := s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{valid, invalid})
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected a ModelValidationError, got %v", err)
+		}
+
+		// Nothing of the batch is stored, not even the company
+		retrieved, err := s.stocks.RetrieveStocksFromDatabase(ctx, conformanceRejected, january(1), january(31))
+		if err != nil || len(retrieved) != 0 {
+			t.Errorf("Expected no stored rows, got %d, %v", len(retrieved), err)
+		}
+		symbols, err := s.companies.ListActiveSymbols(ctx)
+		if err != nil || slices.Contains(symbols, conformanceRejected) {
+			t.Errorf("Expected %s not to be created, got %v, %v", conformanceRejected, symbols, err)
+		}
+
+		_, err = s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{conformanceStock(2, "", 99)})
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected a ModelValidationError for a stock without source, got %v", err)
+		}
+
+		if _, err := s.dataSources.Create(ctx, &models.DataSource{SourceName: conformanceInactive, SourceType: "PRICE"}); err != nil {
+			t.Fatalf("Expected the source to be created, got %v", err)
+		}
+		_, err = s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{conformanceStock(2, conformanceInactive, 99)})
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected a ModelValidationError for an inactive source, got %v", err)
+		}
+	})
+
+	t.Run("StoredDates", func(t *testing.T) {
+		s := newStores(t)
+
+		stocks := []*models.Stock{
+			conformanceStock(5, conformanceSourceA, 100),
+			conformanceStock(3, conformanceSourceA, 100),
+			conformanceStock(4, conformanceSourceB, 100),
+		}
+		if _, err := s.stocks.SaveStocksToDatabase(ctx, stocks); err != nil {
+			t.Fatalf("Expected the stocks to be saved, got %v", err)
+		}
+
+		latest, err := s.stocks.GetLatestStockDate(ctx, conformanceSymbol, []string{conformanceSourceB})
+		if err != nil || !latest.Equal(january(4)) {
+			t.Errorf("Expected the 4th as latest date of %s, got %v, %v", conformanceSourceB, latest, err)
+		}
+		latest, err = s.stocks.GetLatestStockDate(ctx, conformanceSymbol, []string{conformanceSourceA, conformanceSourceB})
+		if err != nil || !latest.Equal(january(5)) {
+			t.Errorf("Expected the 5th as latest date, got %v, %v", latest, err)
+		}
+		latest, err = s.stocks.GetLatestStockDate(ctx, conformanceSymbol, []string{conformanceInactive})
+		if err != nil || !latest.IsZero() {
+			t.Errorf("Expected no latest date for a source without rows, got %v, %v", latest, err)
+		}
+		latest, err = s.stocks.GetLatestStockDate(ctx, "NONE", []string{conformanceSourceA, conformanceSourceB})
+		if err != nil || !latest.IsZero() {
+			t.Errorf("Expected no latest date for a symbol without rows, got %v, %v", latest, err)
+		}
+		latest, err = s.stocks.GetLatestStockDate(ctx, conformanceSymbol, nil)
+		if err != nil || !latest.IsZero() {
+			t.Errorf("Expected no latest date without sources, got %v, %v", latest, err)
+		}
+		latest, err = s.stocks.GetLatestStockDate(ctx, conformanceSymbol, []string{"Unregistered"})
+		if err != nil || !latest.IsZero() {
+			t.Errorf("Expected no latest date for an unregistered source, got %v, %v", latest, err)
+		}
+
+		dates, err := s.stocks.GetStoredDates(ctx, conformanceSymbol, january(1), january(31))
+		if err != nil {
+			t.Fatalf("Expected the stored dates, got %v", err)
+		}
+		sourceA := dates[conformanceSourceA]
+		if len(dates) != 2 || len(sourceA) != 2 || !sourceA[0].Equal(january(3)) || !sourceA[1].Equal(january(5)) {
+			t.Errorf("Expected the 3rd and 5th from %s and one date from %s, got %v", conformanceSourceA, conformanceSourceB, dates)
+		}
+
+		ranges, err := s.stocks.GetStoredDateRanges(ctx, conformanceSymbol)
+		if err != nil {
+			t.Fatalf("Expected the stored date ranges, got %v", err)
+		}
+		rangeA, rangeB := ranges[conformanceSourceA], ranges[conformanceSourceB]
+		if len(ranges) != 2 || !rangeA.First.Equal(january(3)) || !rangeA.Last.Equal(january(5)) ||
+			!rangeB.First.Equal(january(4)) || !rangeB.Last.Equal(january(4)) {
+			t.Errorf("Expected the 3rd to 5th from %s and the 4th from %s, got %v", conformanceSourceA, conformanceSourceB, ranges)
+		}
+	})
+
+	t.Run("UpdateAdjustedCloses", func(t *testing.T) {
+		s := newStores(t)
+
+		stocks := []*models.Stock{conformanceStock(3, conformanceSourceA, 100), conformanceStock(2, conformanceSourceA, 100)}
+		if _, err := s.stocks.SaveStocksToDatabase(ctx, stocks); err != nil {
+			t.Fatalf("Expected the stocks to be saved, got %v", err)
+		}
+
+		stocks[1].AdjustedClose = 98.5
+		updated, err := s.stocks.UpdateAdjustedCloses(ctx, stocks)
+		if err != nil || updated != 1 {
+			t.Errorf("Expected 1 updated row, got %d, %v", updated, err)
+		}
+		updated, err = s.stocks.UpdateAdjustedCloses(ctx, stocks)
+		if err != nil || updated != 0 {
+			t.Errorf("Expected no updated rows the second time, got %d, %v", updated, err)
+		}
+
+		retrieved, err := s.stocks.RetrieveStocksFromDatabase(ctx, conformanceSymbol, january(2), january(2))
+		if err != nil || len(retrieved) != 1 || retrieved[0].AdjustedClose != 98.5 || retrieved[0].ClosePrice != 100 {
+			t.Errorf("Expected adjusted close 98.5 next to close 100, got %v, %v", retrieved, err)
+		}
+	})
+
+	t.Run("Companies", func(t *testing.T) {
+		s := newStores(t)
+
+		var notFound *errors.NotFoundError
+		if _, err := s.companies.GetBySymbol(ctx, conformanceSymbol); !errors.As(err, &notFound) {
+			t.Errorf("Expected a NotFoundError, got %v", err)
+		}
+
+		// A shared database may hold other placeholders, so the limit leaves room for them
+		isPlaceholder := func() bool {
+			placeholders, err := s.companies.ListPlaceholders(ctx, 10000)
+			if err != nil {
+				t.Fatalf("Expected the placeholders to be listed, got %v", err)
+			}
+			return slices.ContainsFunc(placeholders, func(c *models.Company) bool { return c.Symbol == conformanceSymbol })
+		}
+
+		// Storing prices creates a placeholder named after the symbol
+		if _, err := s.stocks.SaveStocksToDatabase(ctx, []*models.Stock{conformanceStock(2, conformanceSourceA, 100)}); err != nil {
+			t.Fatalf("Expected the stock to be saved, got %v", err)
+		}
+		company, err := s.companies.GetBySymbol(ctx, conformanceSymbol)
+		if err != nil {
+			t.Fatalf("Expected the company to be found, got %v", err)
+		}
+		if company.Name != conformanceSymbol || !company.IsActive || !company.IsPlaceholder() || company.Sector != "" {
+			t.Errorf("Expected an active placeholder, got %+v", company)
+		}
+		if !isPlaceholder() {
+			t.Errorf("Expected %s to be listed as placeholder", conformanceSymbol)
+		}
+
+		// Checked placeholders are listed after the ones never checked
+		if err := s.companies.MarkProfileChecked(ctx, conformanceSymbol); err != nil {
+			t.Fatalf("Expected the profile check to be recorded, got %v", err)
+		}
+		if err := s.companies.MarkProfileChecked(ctx, "ZZNONE"); err != nil {
+			t.Errorf("Expected unknown symbols to be ignored, got %v", err)
+		}
+		placeholders, err := s.companies.ListPlaceholders(ctx, 10000)
+		if err != nil {
+			t.Fatalf("Expected the placeholders to be listed, got %v", err)
+		}
+		checked := slices.IndexFunc(placeholders, func(c *models.Company) bool { return c.Symbol == conformanceSymbol })
+		if checked < 0 || placeholders[checked].ProfileChecked.IsZero() {
+			t.Fatalf("Expected %s to be listed as checked placeholder, got %v", conformanceSymbol, placeholders)
+		}
+		if slices.ContainsFunc(placeholders[checked:], func(c *models.Company) bool { return c.ProfileChecked.IsZero() }) {
+			t.Errorf("Expected the placeholders never checked to come first, got %v", placeholders)
+		}
+		company.ProfileChecked = placeholders[checked].ProfileChecked
+
+		company.Name = "Conformance Corp"
+		company.Sector = "Technology"
+		company.Exchange = "NASDAQ"
+		company.ProfileSource = conformanceSourceA
+		company.ProfileUpdated = january(3)
+		updated, err := s.companies.Update(ctx, company)
+		if err != nil {
+			t.Fatalf("Expected the company to be updated, got %v", err)
+		}
+		if updated.CompanyID != company.CompanyID || updated.Name != "Conformance Corp" || updated.Industry != "" ||
+			updated.ProfileSource != conformanceSourceA || !updated.ProfileUpdated.Equal(january(3)) ||
+			!updated.ProfileChecked.Equal(company.ProfileChecked) {
+			t.Errorf("Expected the stored profile, got %+v", updated)
+		}
+		if exchange, err := s.companies.GetExchange(ctx, conformanceSymbol); err != nil || exchange != "NASDAQ" {
+			t.Errorf("Expected NASDAQ, got %q, %v", exchange, err)
+		}
+		if isPlaceholder() {
+			t.Errorf("Expected %s to have left the placeholders", conformanceSymbol)
+		}
+
+		var conflict *errors.ConflictError
+		if err := s.companies.Delete(ctx, conformanceSymbol); !errors.As(err, &conflict) {
+			t.Errorf("Expected a ConflictError deleting a company with prices, got %v", err)
+		}
+
+		created, err := s.companies.Create(ctx, &models.Company{Symbol: conformanceRejected, Name: "Rejected Inc", IsActive: true})
+		if err != nil {
+			t.Fatalf("Expected the company to be created, got %v", err)
+		}
+		if created.CompanyID == 0 || created.Sector != "" || !created.IsPlaceholder() || created.LastUpdated.IsZero() {
+			t.Errorf("Expected the stored company, got %+v", created)
+		}
+		if _, err := s.companies.Create(ctx, &models.Company{Symbol: conformanceRejected, Name: "Again"}); !errors.As(err, &conflict) {
+			t.Errorf("Expected a ConflictError for a duplicate symbol, got %v", err)
+		}
+
+		var invalid *errors.ModelValidationError
+		if _, err := s.companies.Update(ctx, &models.Company{Symbol: conformanceRejected}); !errors.As(err, &invalid) {
+			t.Errorf("Expected a ModelValidationError without a name, got %v", err)
+		}
+
+		if err := s.companies.Delete(ctx, conformanceRejected); err != nil {
+			t.Fatalf("Expected the company to be deleted, got %v", err)
+		}
+		if err := s.companies.Delete(ctx, conformanceRejected); !errors.As(err, &notFound) {
+			t.Errorf("Expected a NotFoundError deleting twice, got %v", err)
+		}
+		if _, err := s.companies.Update(ctx, created); !errors.As(err, &notFound) {
+			t.Errorf("Expected a NotFoundError updating a deleted company, got %v", err)
+		}
+	})
+
+	t.Run("DataSources", func(t *testing.T) {
+		s := newStores(t)
+
+		var notFound *errors.NotFoundError
+		if _, err := s.dataSources.GetByName(ctx, conformanceSourceA); !errors.As(err, &notFound) {
+			t.Errorf("Expected a NotFoundError, got %v", err)
+		}
+
+		created, err := s.dataSources.Create(ctx, &models.DataSource{
+			SourceName:       conformanceSourceA,
+			SourceType:       "PRICE",
+			RateLimitPerDay:  25,
+			ConfigParameters: map[string]any{"interval": 5},
+			IsActive:         true,
+		})
+		if err != nil {
+			t.Fatalf("Expected the source to be created, got %v", err)
+		}
+		if created.SourceID == 0 || created.RateLimitPerDay != 25 || created.ConfigParameters["interval"] != float64(5) {
+			t.Errorf("Expected the stored source with its JSON config, got %+v", created)
+		}
+		if created.CreatedAt.IsZero() || created.LastUpdated.IsZero() {
+			t.Errorf("Expected the timestamps to be set, got %+v", created)
+		}
+
+		// Creating it again returns the existing source unchanged
+		again, err := s.dataSources.Create(ctx, &models.DataSource{SourceName: conformanceSourceA, SourceType: "FUNDAMENTAL"})
+		if err != nil || again.SourceID != created.SourceID || again.SourceType != "PRICE" {
+			t.Errorf("Expected the existing source, got %+v, %v", again, err)
+		}
+	})
+
+	t.Run("Jobs", func(t *testing.T) {
+		s := newStores(t)
+
+		ds, err := s.dataSources.Create(ctx, &models.DataSource{SourceName: conformanceSourceA, SourceType: "PRICE", IsActive: true})
+		if err != nil {
+			t.Fatalf("Expected the source to be created, got %v", err)
+		}
+
+		due := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+		job := &models.DataFetchJob{
+			SourceID:      ds.SourceID,
+			EntityType:    "SYMBOL",
+			EntityValue:   conformanceSymbol,
+			DataType:      "PRICE",
+			Frequency:     models.FrequencyDaily,
+			Parameters:    map[string]any{"days": 5},
+			NextScheduled: due,
+			Status:        models.JobStatusPending,
+			IsActive:      true,
+		}
+		created, err := s.jobs.Create(ctx, job)
+		if err != nil {
+			t.Fatalf("Expected the job to be created, got %v", err)
+		}
+		if created.JobID == 0 || !created.NextScheduled.Equal(due) || created.Parameters["days"] != float64(5) {
+			t.Errorf("Expected the stored job with its JSON parameters, got %+v", created)
+		}
+
+		var conflict *errors.ConflictError
+		if _, err := s.jobs.Create(ctx, job); !errors.As(err, &conflict) {
+			t.Errorf("Expected a ConflictError for a duplicate job, got %v", err)
+		}
+		var validationErr *errors.ModelValidationError
+		missingSource := *job
+		missingSource.SourceID = ds.SourceID + 1000
+		if _, err := s.jobs.Create(ctx, &missingSource); !errors.As(err, &validationErr) {
+			t.Errorf("Expected a ModelValidationError for a missing source, got %v", err)
+		}
+
+		found, err := s.jobs.FindByEntity(ctx, ds.SourceID, "SYMBOL", conformanceSymbol, "PRICE")
+		if err != nil || found == nil || found.JobID != created.JobID {
+			t.Errorf("Expected to find job %d, got %+v, %v", created.JobID, found, err)
+		}
+		found, err = s.jobs.FindByEntity(ctx, ds.SourceID, "SYMBOL", conformanceSymbol, "CORPORATE_ACTIONS")
+		if err != nil || found != nil {
+			t.Errorf("Expected no job, got %+v, %v", found, err)
+		}
+
+		// Updating the definition keeps the execution history
+		created.Frequency = models.FrequencyWeekly
+		updated, err := s.jobs.Update(ctx, created)
+		if err != nil || updated.Frequency != models.FrequencyWeekly || updated.Status != models.JobStatusPending {
+			t.Errorf("Expected the updated job with its status, got %+v, %v", updated, err)
+		}
+
+		paused, err := s.jobs.SetActive(ctx, created.JobID, false)
+		if err != nil || paused.IsActive {
+			t.Fatalf("Expected the job to be paused, got %+v, %v", paused, err)
+		}
+		inactive := false
+		jobs, err := s.jobs.List(ctx, &inactive)
+		if err != nil || !slices.ContainsFunc(jobs, func(j *models.DataFetchJob) bool { return j.JobID == created.JobID }) {
+			t.Errorf("Expected the paused job among the inactive jobs, got %v, %v", jobs, err)
+		}
+
+		// Paused jobs are not claimed
+		claimed, err := s.jobs.ClaimDueJobs(ctx, 100, time.Hour)
+		if err != nil || slices.ContainsFunc(claimed, func(j *models.DataFetchJob) bool { return j.JobID == created.JobID }) {
+			t.Errorf("Expected the paused job not to be claimed, got %v, %v", claimed, err)
+		}
+
+		if _, err := s.jobs.SetActive(ctx, created.JobID, true); err != nil {
+			t.Fatalf("Expected the job to be resumed, got %v", err)
+		}
+		claimed, err = s.jobs.ClaimDueJobs(ctx, 100, time.Hour)
+		index := slices.IndexFunc(claimed, func(j *models.DataFetchJob) bool { return j.JobID == created.JobID })
+		if err != nil || index < 0 {
+			t.Fatalf("Expected the due job to be claimed, got %v, %v", claimed, err)
+		}
+		if claimed[index].Status != models.JobStatusRunning || claimed[index].LastExecution.IsZero() {
+			t.Errorf("Expected a RUNNING job with its last execution, got %+v", claimed[index])
+		}
+
+		// A running job is only claimed again once it is stale
+		claimed, err = s.jobs.ClaimDueJobs(ctx, 100, time.Hour)
+		if err != nil || slices.ContainsFunc(claimed, func(j *models.DataFetchJob) bool { return j.JobID == created.JobID }) {
+			t.Errorf("Expected the running job not to be claimed again, got %v, %v", claimed, err)
+		}
+
+		next := due.Add(24 * time.Hour)
+		if err := s.jobs.FinishJob(ctx, created.JobID, models.JobStatusSuccess, next); err != nil {
+			t.Fatalf("Expected the job to be finished, got %v", err)
+		}
+		finished, err := s.jobs.GetByID(ctx, created.JobID)
+		if err != nil || finished.Status != models.JobStatusSuccess || finished.LastSuccess.IsZero() ||
+			!finished.NextScheduled.Equal(next) || !finished.IsActive {
+			t.Errorf("Expected a successful job scheduled for %v, got %+v, %v", next, finished, err)
+		}
+
+		// A zero next run retires the job
+		if err := s.jobs.FinishJob(ctx, created.JobID, models.JobStatusFailed, time.Time{}); err != nil {
+			t.Fatalf("Expected the job to be finished, got %v", err)
+		}
+		retired, err := s.jobs.GetByID(ctx, created.JobID)
+		if err != nil || retired.IsActive || retired.Status != models.JobStatusFailed || !retired.NextScheduled.Equal(next) {
+			t.Errorf("Expected an inactive failed job, got %+v, %v", retired, err)
+		}
+
+		// A requested run is claimed before the job is due and keeps its schedule
+		if _, err := s.jobs.SetActive(ctx, created.JobID, true); err != nil {
+			t.Fatalf("Expected the job to be resumed, got %v", err)
+		}
+		scheduled, err := s.jobs.ScheduleNow(ctx, created.JobID)
+		if err != nil || scheduled.RunRequested.IsZero() || !scheduled.NextScheduled.Equal(next) {
+			t.Fatalf("Expected a requested run that keeps the schedule %v, got %+v, %v", next, scheduled, err)
+		}
+		if scheduled.Status != models.JobStatusPending {
+			t.Errorf("Expected the failed run to be reset to PENDING, got %s", scheduled.Status)
+		}
+		claimed, err = s.jobs.ClaimDueJobs(ctx, 100, time.Hour)
+		if err != nil || !slices.ContainsFunc(claimed, func(j *models.DataFetchJob) bool { return j.JobID == created.JobID }) {
+			t.Fatalf("Expected the requested job to be claimed, got %v, %v", claimed, err)
+		}
+		if err := s.jobs.FinishJob(ctx, created.JobID, models.JobStatusSuccess, next); err != nil {
+			t.Fatalf("Expected the job to be finished, got %v", err)
+		}
+		finished, err = s.jobs.GetByID(ctx, created.JobID)
+		if err != nil || !finished.RunRequested.IsZero() || !finished.NextScheduled.Equal(next) {
+			t.Errorf("Expected the run request to be cleared and the schedule kept, got %+v, %v", finished, err)
+		}
+		claimed, err = s.jobs.ClaimDueJobs(ctx, 100, time.Hour)
+		if err != nil || slices.ContainsFunc(claimed, func(j *models.DataFetchJob) bool { return j.JobID == created.JobID }) {
+			t.Errorf("Expected the job not to be claimed before it is due, got %v, %v", claimed, err)
+		}
+
+		// A run requested while the job runs keeps it RUNNING
+		if _, err := s.jobs.ScheduleNow(ctx, created.JobID); err != nil {
+			t.Fatalf("Expected the run to be requested, got %v", err)
+		}
+		if _, err := s.jobs.ClaimDueJobs(ctx, 100, time.Hour); err != nil {
+			t.Fatalf("Expected the requested job to be claimed, got %v", err)
+		}
+		scheduled, err = s.jobs.ScheduleNow(ctx, created.JobID)
+		if err != nil || scheduled.Status != models.JobStatusRunning {
+			t.Errorf("Expected the running job to stay RUNNING, got %+v, %v", scheduled, err)
+		}
+	})
+
+	t.Run("JobExecutionLogs", func(t *testing.T) {
+		s := newStores(t)
+
+		ds, err := s.dataSources.Create(ctx, &models.DataSource{SourceName: conformanceSourceA, SourceType: "PRICE", IsActive: true})
+		if err != nil {
+			t.Fatalf("Expected the source to be created, got %v", err)
+		}
+		job, err := s.jobs.Create(ctx, &models.DataFetchJob{
+			SourceID:      ds.SourceID,
+			EntityType:    "SYMBOL",
+			EntityValue:   conformanceSymbol,
+			DataType:      "PRICE",
+			Frequency:     models.FrequencyDaily,
+			NextScheduled: time.Now().UTC(),
+			IsActive:      true,
+		})
+		if err != nil {
+			t.Fatalf("Expected the job to be created, got %v", err)
+		}
+
+		start := time.Now().UTC().Truncate(time.Second)
+		entries := []*models.JobExecutionLog{}
+		for i := 0; i < 3; i++ {
+			entry := &models.JobExecutionLog{
+				JobID:     job.JobID,
+				JobType:   models.JobTypeDataFetch,
+				StartTime: start.Add(time.Duration(i) * time.Minute),
+				Status:    models.JobStatusRunning,
+			}
+			if err := s.logs.Start(ctx, entry); err != nil || entry.LogID == 0 {
+				t.Fatalf("Expected the log to be started, got LogID %d, %v", entry.LogID, err)
+			}
+			entries = append(entries, entry)
+		}
+
+		last := entries[2]
+		last.EndTime = last.StartTime.Add(time.Second)
+		last.Status = models.JobStatusFailed
+		last.RecordsProcessed = 42
+		last.ErrorMessage = "provider failed"
+		last.Details = map[string]any{"fetched": 42}
+		if err := s.logs.Update(ctx, last); err != nil {
+			t.Fatalf("Expected the log to be updated, got %v", err)
+		}
+
+		logs, err := s.logs.ListByJob(ctx, job.JobID, 2)
+		if err != nil || len(logs) != 2 {
+			t.Fatalf("Expected the 2 latest logs, got %v, %v", logs, err)
+		}
+		if logs[0].LogID != last.LogID || logs[1].LogID != entries[1].LogID {
+			t.Errorf("Expected logs %d and %d, newest first, got %d and %d",
+				last.LogID, entries[1].LogID, logs[0].LogID, logs[1].LogID)
+		}
+		if logs[0].Status != models.JobStatusFailed || logs[0].RecordsProcessed != 42 ||
+			logs[0].ErrorMessage != "provider failed" || !logs[0].EndTime.Equal(last.EndTime) ||
+			logs[0].Details["fetched"] != float64(42) {
+			t.Errorf("Expected the updated log, got %+v", logs[0])
+		}
+		if !logs[1].EndTime.IsZero() || logs[1].Details == nil {
+			t.Errorf("Expected a running log without end time, got %+v", logs[1])
+		}
+
+		// Deleting the job deletes its logs
+		if err := s.jobs.Delete(ctx, job.JobID); err != nil {
+			t.Fatalf("Expected the job to be deleted, got %v", err)
+		}
+		var notFound *errors.NotFoundError
+		if _, err := s.jobs.GetByID(ctx, job.JobID); !errors.As(err, &notFound) {
+			t.Errorf("Expected a NotFoundError for the deleted job, got %v", err)
+		}
+		if err := s.jobs.Delete(ctx, job.JobID); !errors.As(err, &notFound) {
+			t.Errorf("Expected a NotFoundError when deleting twice, got %v", err)
+		}
+		logs, err = s.logs.ListByJob(ctx, job.JobID, 10)
+		if err != nil || len(logs) != 0 {
+			t.Errorf("Expected the logs to be deleted, got %v, %v", logs, err)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"time"
+)
+
+// CorporateActionRepository handles DB operations for corporate actions
+type CorporateActionRepository struct {
+	db *sql.DB
+}
+
+// NewCorporateActionRepository creates a new corporate action repository
+func NewCorporateActionRepository(db *sql.DB) *CorporateActionRepository {
+	return &CorporateActionRepository{db: db}
+}
+
+// Upsert stores the actions in a single transaction, replacing the stored action of the same symbol, type and
+// ex-date. Companies that don't exist yet are created. It returns the symbols whose actions were inserted
+// or changed, so that only their prices need to be re-adjusted.
+func (car *CorporateActionRepository) Upsert(ctx context.Context, actions []*models.CorporateAction) ([]string, error) {
+	tx, err := car.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`
+		INSERT INTO corporate_actions
+		(company_id, symbol, action_type, ex_date, ratio, amount, source, last_updated)
+		VALUES ($1, $2, $3, $4, NULLIF($5::NUMERIC, 0), NULLIF($6::NUMERIC, 0), $7, NOW())
+		ON CONFLICT (company_id, action_type, ex_date)
+		DO UPDATE SET
+		ratio = EXCLUDED.ratio,
+		amount = EXCLUDED.amount,
+		source = EXCLUDED.source,
+		last_updated = EXCLUDED.last_updated
+		WHERE (corporate_actions.ratio, corporate_actions.amount)
+		IS DISTINCT FROM (EXCLUDED.ratio, EXCLUDED.amount)
+		RETURNING action_id
+		`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare corporate action upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	changed := []string{}
+	seen := make(map[string]bool)
+	companyIDs := make(map[string]int)
+	for _, action := range actions {
+		if err := action.Validate(); err != nil {
+			return nil, err
+		}
+
+		companyID, ok := companyIDs[action.Symbol]
+		if !ok {
+			if companyID, err = findOrCreateCompany(ctx, tx, action.Symbol); err != nil {
+				return nil, err
+			}
+			companyIDs[action.Symbol] = companyID
+		}
+		action.CompanyID = companyID
+
+		err := stmt.QueryRowContext(
+			ctx,
+			action.CompanyID,
+			action.Symbol,
+			action.ActionType,
+			action.ExDate,
+			action.Ratio,
+			action.Amount,
+			action.Source,
+		).Scan(&action.ActionID)
+
+		// No row means the stored action already matched
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s of %s on %s: %w",
+				action.ActionType, action.Symbol, action.ExDate.Format("2006-01-02"), err)
+		}
+
+		if !seen[action.Symbol] {
+			seen[action.Symbol] = true
+			changed = append(changed, action.Symbol)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+// ListBySymbol returns the actions of symbol with an ex-date after since, oldest first.
+// A zero since returns all actions.
+func (car *CorporateActionRepository) ListBySymbol(
+	ctx context.Context,
+	symbol string,
+	since time.Time,
+) ([]*models.CorporateAction, error) {
+	rows, err := car.db.QueryContext(
+		ctx,
+		`
+		SELECT action_id, company_id, symbol, action_type, ex_date,
+		       COALESCE(ratio, 0), COALESCE(amount, 0), source, last_updated
+		FROM corporate_actions
+		WHERE symbol = $1 AND ex_date > $2
+		ORDER BY ex_date, action_type
+		`,
+		symbol,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []*models.CorporateAction{}
+	for rows.Next() {
+		var a models.CorporateAction
+		err := rows.Scan(
+			&a.ActionID,
+			&a.CompanyID,
+			&a.Symbol,
+			&a.ActionType,
+			&a.ExDate,
+			&a.Ratio,
+			&a.Amount,
+			&a.Source,
+			&a.LastUpdated,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan corporate action row: %w", err)
+		}
+		actions = append(actions, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating corporate action rows: %w", err)
+	}
+	return actions, nil
+}
+
+// findOrCreateCompany returns the company_id of symbol, creating a placeholder company named after
+// the symbol if it doesn't exist yet.
+func findOrCreateCompany(ctx context.Context, tx *sql.Tx, symbol string) (int, error) {
+	var companyID int
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT company_id FROM companies WHERE symbol = $1`,
+		symbol,
+	).Scan(&companyID)
+	if err == nil {
+		return companyID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to check if company exists for symbol %s: %w", symbol, err)
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO companies (symbol, name, is_active, last_updated)
+		VALUES ($1, $1, true, NOW())
+		RETURNING company_id
+		`,
+		symbol,
+	).Scan(&companyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create company for symbol %s: %w", symbol, err)
+	}
+	return companyID, nil
+}
+
+// MergeIntoPrices copies the stored actions of symbol into the dividend_amount and split_coefficient columns
+// of its stock prices on the ex-dates, for every source. It returns the number of price rows changed.
+func (car *CorporateActionRepository) MergeIntoPrices(ctx context.Context, symbol string) (int, error) {
+	// A split and a dividend can share an ex-date, so each column is merged by its own statement
+	statements := []string{
+		`
+		UPDATE stock_prices sp
+		SET dividend_amount = ca.amount, last_updated = NOW()
+		FROM corporate_actions ca
+		WHERE ca.company_id = sp.company_id AND ca.ex_date = sp.date
+		AND ca.action_type = 'DIVIDEND' AND sp.symbol = $1
+		AND sp.dividend_amount IS DISTINCT FROM ca.amount
+		`,
+		`
+		UPDATE stock_prices sp
+		SET split_coefficient = ca.ratio, last_updated = NOW()
+		FROM corporate_actions ca
+		WHERE ca.company_id = sp.company_id AND ca.ex_date = sp.date
+		AND ca.action_type = 'SPLIT' AND sp.symbol = $1
+		AND sp.split_coefficient IS DISTINCT FROM ca.ratio
+		`,
+	}
+
+	changed := 0
+	for _, statement := range statements {
+		result, err := car.db.ExecContext(ctx, statement, symbol)
+		if err != nil {
+			return 0, fmt.Errorf("failed to merge corporate actions of %s into prices: %w", symbol, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		changed += int(rows)
+	}
+	return changed, nil
+}
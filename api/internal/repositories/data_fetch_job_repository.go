@@ -0,0 +1,414 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// dataFetchJobColumns lists the columns scanned by scanDataFetchJob, in order.
+const dataFetchJobColumns = `
+	job_id, source_id, entity_type, entity_value, data_type, frequency, parameters,
+	last_execution, last_success, next_scheduled, run_requested, status, is_active, last_updated
+`
+
+// DataFetchJobRepository handles DB operations for scheduled data fetch jobs
+type DataFetchJobRepository struct {
+	db *sql.DB
+}
+
+// NewDataFetchJobRepository creates a new data fetch job repository
+func NewDataFetchJobRepository(db *sql.DB) *DataFetchJobRepository {
+	return &DataFetchJobRepository{db: db}
+}
+
+// GetByID retrieves a job by its ID. A NotFoundError is returned if there is no such job.
+func (jr *DataFetchJobRepository) GetByID(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	row := jr.db.QueryRowContext(ctx, `SELECT `+dataFetchJobColumns+` FROM data_fetch_jobs WHERE job_id = $1`, jobID)
+
+	job, err := scanDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving job %d: %w", jobID, err)
+	}
+	return job, nil
+}
+
+// FindByEntity returns the job fetching dataType for the given entity from a source, or nil if there is none.
+// These columns make up the fetch_job_unique constraint, so there is at most one such job.
+func (jr *DataFetchJobRepository) FindByEntity(
+	ctx context.Context,
+	sourceID int,
+	entityType, entityValue, dataType string,
+) (*models.DataFetchJob, error) {
+	row := jr.db.QueryRowContext(
+		ctx,
+		`SELECT `+dataFetchJobColumns+` FROM data_fetch_jobs
+		WHERE source_id = $1 AND entity_type = $2 AND entity_value = $3 AND data_type = $4`,
+		sourceID,
+		entityType,
+		entityValue,
+		dataType,
+	)
+
+	job, err := scanDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving %s job for %s: %w", dataType, entityValue, err)
+	}
+	return job, nil
+}
+
+// List returns all jobs ordered by ID. If active is not nil, only jobs with that is_active value are returned.
+func (jr *DataFetchJobRepository) List(ctx context.Context, active *bool) ([]*models.DataFetchJob, error) {
+	rows, err := jr.db.QueryContext(
+		ctx,
+		`SELECT `+dataFetchJobColumns+` FROM data_fetch_jobs
+		WHERE $1::BOOLEAN IS NULL OR COALESCE(is_active, TRUE) = $1
+		ORDER BY job_id`,
+		active,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.DataFetchJob{}
+	for rows.Next() {
+		job, err := scanDataFetchJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Create inserts a new job and returns it as stored. A ConflictError is returned if a job for the same
+// source, entity and data type already exists (the fetch_job_unique constraint).
+func (jr *DataFetchJobRepository) Create(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+
+	parametersJSON, err := encodeParameters(job.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	row := jr.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO data_fetch_jobs
+		(source_id, entity_type, entity_value, data_type, frequency, parameters, next_scheduled,
+		status, is_active, last_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING `+dataFetchJobColumns,
+		job.SourceID,
+		job.EntityType,
+		job.EntityValue,
+		job.DataType,
+		job.Frequency,
+		parametersJSON,
+		job.NextScheduled,
+		job.Status,
+		job.IsActive,
+	)
+
+	created, err := scanDataFetchJob(row)
+	if err != nil {
+		return nil, jobWriteError(job, err)
+	}
+	return created, nil
+}
+
+// Update stores the definition of an existing job: its source, entity, data type, frequency, parameters,
+// next scheduled run and is_active. The execution history columns are left alone.
+func (jr *DataFetchJobRepository) Update(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+
+	parametersJSON, err := encodeParameters(job.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	row := jr.db.QueryRowContext(
+		ctx,
+		`
+		UPDATE data_fetch_jobs
+		SET source_id = $2, entity_type = $3, entity_value = $4, data_type = $5, frequency = $6,
+		parameters = $7, next_scheduled = $8, is_active = $9, last_updated = NOW()
+		WHERE job_id = $1
+		RETURNING `+dataFetchJobColumns,
+		job.JobID,
+		job.SourceID,
+		job.EntityType,
+		job.EntityValue,
+		job.DataType,
+		job.Frequency,
+		parametersJSON,
+		job.NextScheduled,
+		job.IsActive,
+	)
+
+	updated, err := scanDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataFetchJob", job.JobID)
+	}
+	if err != nil {
+		return nil, jobWriteError(job, err)
+	}
+	return updated, nil
+}
+
+// SetActive pauses (false) or resumes (true) a job without touching its schedule.
+func (jr *DataFetchJobRepository) SetActive(ctx context.Context, jobID int, active bool) (*models.DataFetchJob, error) {
+	row := jr.db.QueryRowContext(
+		ctx,
+		`UPDATE data_fetch_jobs SET is_active = $2, last_updated = NOW() WHERE job_id = $1 RETURNING `+dataFetchJobColumns,
+		jobID,
+		active,
+	)
+
+	job, err := scanDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job %d: %w", jobID, err)
+	}
+	return job, nil
+}
+
+// ScheduleNow requests a run of the job, so the scheduler picks it up on its next poll. next_scheduled is left
+// alone, the job keeps its schedule. The outcome of the previous run is reset to PENDING, unless the job is
+// running right now.
+func (jr *DataFetchJobRepository) ScheduleNow(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	row := jr.db.QueryRowContext(
+		ctx,
+		`
+		UPDATE data_fetch_jobs
+		SET run_requested = NOW(), status = CASE WHEN status = $2 THEN status ELSE $3 END, last_updated = NOW()
+		WHERE job_id = $1
+		RETURNING `+dataFetchJobColumns,
+		jobID,
+		models.JobStatusRunning,
+		models.JobStatusPending,
+	)
+
+	job, err := scanDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule job %d: %w", jobID, err)
+	}
+	return job, nil
+}
+
+// Delete removes a job together with its execution logs, which reference it.
+func (jr *DataFetchJobRepository) Delete(ctx context.Context, jobID int) error {
+	tx, err := jr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM job_execution_logs WHERE job_id = $1`, jobID); err != nil {
+		return fmt.Errorf("failed to delete logs of job %d: %w", jobID, err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM data_fetch_jobs WHERE job_id = $1`, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to delete job %d: %w", jobID, err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueJobs marks up to limit active jobs whose next_scheduled time has passed, or whose run was requested,
+// as RUNNING and returns them.
+// Rows are locked with FOR UPDATE SKIP LOCKED, so concurrent schedulers (e.g. several server instances) never
+// claim the same job. Jobs stuck in RUNNING for longer than staleAfter, e.g. because the instance running them
+// crashed, are claimed again.
+func (jr *DataFetchJobRepository) ClaimDueJobs(ctx context.Context, limit int, staleAfter time.Duration) ([]*models.DataFetchJob, error) {
+	rows, err := jr.db.QueryContext(
+		ctx,
+		`
+		UPDATE data_fetch_jobs
+		SET status = $1, last_execution = NOW(), last_updated = NOW()
+		WHERE job_id IN (
+			SELECT job_id
+			FROM data_fetch_jobs
+			WHERE is_active
+			AND (next_scheduled <= NOW() OR run_requested IS NOT NULL)
+			AND (status IS DISTINCT FROM $1 OR last_execution < NOW() - make_interval(secs => $2))
+			ORDER BY LEAST(next_scheduled, run_requested)
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+dataFetchJobColumns,
+		models.JobStatusRunning,
+		staleAfter.Seconds(),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.DataFetchJob{}
+	for rows.Next() {
+		job, err := scanDataFetchJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating claimed jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// FinishJob records the outcome of a claimed job and schedules its next run. A zero nextScheduled time
+// deactivates the job instead, which is how jobs that only run once are retired. The run request the job was
+// claimed for is cleared, one made while it was running is kept.
+func (jr *DataFetchJobRepository) FinishJob(ctx context.Context, jobID int, status string, nextScheduled time.Time) error {
+	_, err := jr.db.ExecContext(
+		ctx,
+		`
+		UPDATE data_fetch_jobs
+		SET status = $2,
+		last_success = CASE WHEN $3 THEN NOW() ELSE last_success END,
+		next_scheduled = CASE WHEN $5 THEN next_scheduled ELSE $4 END,
+		run_requested = CASE WHEN run_requested <= last_execution THEN NULL ELSE run_requested END,
+		is_active = CASE WHEN $5 THEN FALSE ELSE is_active END,
+		last_updated = NOW()
+		WHERE job_id = $1
+		`,
+		jobID,
+		status,
+		status == models.JobStatusSuccess,
+		nextScheduled,
+		nextScheduled.IsZero(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish job %d: %w", jobID, err)
+	}
+	return nil
+}
+
+// jobWriteError translates constraint violations from inserting or updating a job into
+// ConflictError and ModelValidationError.
+func jobWriteError(job *models.DataFetchJob, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505": // unique_violation
+			return jobConflictError(job)
+		case "23503": // foreign_key_violation
+			return jobSourceError(job)
+		}
+	}
+	return fmt.Errorf("failed to save job: %w", err)
+}
+
+// jobConflictError reports a job that would violate the fetch_job_unique constraint.
+func jobConflictError(job *models.DataFetchJob) error {
+	return errors.NewConflictError("DataFetchJob", fmt.Sprintf(
+		"a %s job for %s %s already exists for source %d",
+		job.DataType, job.EntityType, job.EntityValue, job.SourceID))
+}
+
+// jobSourceError reports a job referencing a data source that does not exist.
+func jobSourceError(job *models.DataFetchJob) error {
+	return errors.NewModelValidationError("DataFetchJob", "source_id",
+		fmt.Sprintf("data source %d does not exist", job.SourceID))
+}
+
+// encodeParameters converts the parameters of a job to JSON, storing an empty object for none.
+func encodeParameters(parameters map[string]any) ([]byte, error) {
+	if len(parameters) == 0 {
+		return []byte("{}"), nil
+	}
+
+	encoded, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job parameters: %w", err)
+	}
+	return encoded, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanDataFetchJob scans the dataFetchJobColumns of a single row, mapping NULLs onto zero values.
+func scanDataFetchJob(row rowScanner) (*models.DataFetchJob, error) {
+	var job models.DataFetchJob
+	var parametersJSON []byte
+	var lastExecution, lastSuccess, nextScheduled, runRequested, lastUpdated sql.NullTime
+	var status sql.NullString
+	var isActive sql.NullBool
+
+	err := row.Scan(
+		&job.JobID,
+		&job.SourceID,
+		&job.EntityType,
+		&job.EntityValue,
+		&job.DataType,
+		&job.Frequency,
+		&parametersJSON,
+		&lastExecution,
+		&lastSuccess,
+		&nextScheduled,
+		&runRequested,
+		&status,
+		&isActive,
+		&lastUpdated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.LastExecution = lastExecution.Time
+	job.LastSuccess = lastSuccess.Time
+	job.NextScheduled = nextScheduled.Time
+	job.RunRequested = runRequested.Time
+	job.Status = status.String
+	job.IsActive = !isActive.Valid || isActive.Bool // Column defaults to TRUE
+	job.LastUpdated = lastUpdated.Time
+
+	job.Parameters = make(map[string]any)
+	if len(parametersJSON) > 0 {
+		if err := json.Unmarshal(parametersJSON, &job.Parameters); err != nil {
+			return nil, fmt.Errorf("error parsing parameters of job %d: %w", job.JobID, err)
+		}
+	}
+	return &job, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
 )
 
 // DataSourceRepository handles DB operations for data sources
@@ -18,35 +19,50 @@ func NewDataSourceRepository(db *sql.DB) *DataSourceRepository {
 	return &DataSourceRepository{db: db}
 }
 
-// GetByName retrieves a data source by it name
+// GetByName retrieves a data source by it name. A NotFoundError is returned if no data source has that name.
 func (dsr *DataSourceRepository) GetByName(ctx context.Context, name string) (*models.DataSource, error) {
 	query := `
-		SELECT *
+		SELECT source_id, source_name, source_type, base_url, rate_limit_per_minute,
+		       rate_limit_per_day, config_parameters, is_active, created_at, last_updated
 		FROM data_sources
 		WHERE source_name = $1
 	`
 	var ds models.DataSource
 	var configJSON []byte
 
+	// Everything except the name and type is nullable in the schema
+	var baseURL sql.NullString
+	var perMinute, perDay sql.NullInt64
+	var isActive sql.NullBool
+	var createdAt, lastUpdated sql.NullTime
+
 	err := dsr.db.QueryRowContext(ctx, query, name).Scan(
 		&ds.SourceID,
 		&ds.SourceName,
 		&ds.SourceType,
-		&ds.BaseURL,
-		&ds.RateLimitPerMinute,
-		&ds.RateLimitPerDay,
+		&baseURL,
+		&perMinute,
+		&perDay,
 		&configJSON,
-		&ds.IsActive,
-		&ds.CreatedAt,
+		&isActive,
+		&createdAt,
+		&lastUpdated,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("data source with name '%s' not found", name)
+			return nil, errors.NewNotFoundError("DataSource", name)
 		}
 		return nil, fmt.Errorf("Error retrieving data source: %w", err)
 	}
 
+	ds.BaseURL = baseURL.String
+	ds.RateLimitPerMinute = int(perMinute.Int64)
+	ds.RateLimitPerDay = int(perDay.Int64)
+	ds.IsActive = !isActive.Valid || isActive.Bool // Column defaults to TRUE
+	ds.CreatedAt = createdAt.Time
+	ds.LastUpdated = lastUpdated.Time
+
 	// Parse config parameters
 	if len(configJSON) > 0 {
 		if err := json.Unmarshal(configJSON, &ds.ConfigParameters); err != nil {
@@ -58,3 +74,45 @@ func (dsr *DataSourceRepository) GetByName(ctx context.Context, name string) (*m
 
 	return &ds, nil
 }
+
+// Create registers a new data source and returns it with its generated ID. If a data source with the
+// same name already exists, the existing row is returned unchanged.
+func (dsr *DataSourceRepository) Create(ctx context.Context, ds *models.DataSource) (*models.DataSource, error) {
+	if err := ds.Validate(); err != nil {
+		return nil, err
+	}
+
+	configJSON := []byte("{}")
+	if len(ds.ConfigParameters) > 0 {
+		encoded, err := json.Marshal(ds.ConfigParameters)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding config parameters: %w", err)
+		}
+		configJSON = encoded
+	}
+
+	// ON CONFLICT DO NOTHING keeps concurrent registrations of the same source from failing.
+	// Zero rate limits are stored as NULL, meaning unlimited.
+	_, err := dsr.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO data_sources
+		(source_name, source_type, base_url, rate_limit_per_minute, rate_limit_per_day,
+		config_parameters, is_active, created_at, last_updated)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, 0), NULLIF($5, 0), $6, $7, NOW(), NOW())
+		ON CONFLICT (source_name) DO NOTHING
+		`,
+		ds.SourceName,
+		ds.SourceType,
+		ds.BaseURL,
+		ds.RateLimitPerMinute,
+		ds.RateLimitPerDay,
+		configJSON,
+		ds.IsActive,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data source %s: %w", ds.SourceName, err)
+	}
+
+	return dsr.GetByName(ctx, ds.SourceName)
+}
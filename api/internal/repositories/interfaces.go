@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/clients"
+	"time"
+)
+
+// These interfaces let the services run against any storage backend. The PostgreSQL and SQLite repositories
+// and the in-memory ones created from a MemoryDB implement them with the same semantics, which the conformance
+// tests of this package verify.
+
+// StockStore stores the daily stock prices.
+//
+// SaveStocksToDatabase upserts the stocks on (company_id, date, source_id) in a single transaction. Companies
+// that don't exist yet are created with their symbol as name, and unknown data sources are registered as
+// PRICE sources. Stocks from an inactive source are rejected. Rows whose stored values already match are
+// counted as unchanged and left alone.
+type StockStore interface {
+	SaveStocksToDatabase(ctx context.Context, stocks []*models.Stock) (*SaveResult, error)
+	RetrieveStocksFromDatabase(ctx context.Context, symbol string, startDate, endDate time.Time) ([]*models.Stock, error)
+	GetLatestStockDate(ctx context.Context, symbol string, sourceNames []string) (time.Time, error)
+	GetStoredDates(ctx context.Context, symbol string, startDate, endDate time.Time) (map[string][]time.Time, error)
+	GetStoredDateRanges(ctx context.Context, symbol string) (map[string]StoredRange, error)
+	UpdateAdjustedCloses(ctx context.Context, stocks []*models.Stock) (int, error)
+}
+
+// CompanyStore holds the companies stock prices are stored for. Companies are identified by their symbol.
+//
+// GetBySymbol, Update and Delete return a NotFoundError for unknown symbols. Create returns a ConflictError if
+// the symbol exists already, and Delete one if prices or corporate actions still reference the company.
+// ListPlaceholders returns up to limit active companies without a profile, the ones never checked first, then
+// the ones whose profile was checked longest ago, each in alphabetical order. MarkProfileChecked records that
+// a profile was requested now and ignores unknown symbols.
+type CompanyStore interface {
+	ListActiveSymbols(ctx context.Context) ([]string, error)
+	GetExchange(ctx context.Context, symbol string) (string, error)
+	GetBySymbol(ctx context.Context, symbol string) (*models.Company, error)
+	ListPlaceholders(ctx context.Context, limit int) ([]*models.Company, error)
+	MarkProfileChecked(ctx context.Context, symbol string) error
+	Create(ctx context.Context, company *models.Company) (*models.Company, error)
+	Update(ctx context.Context, company *models.Company) (*models.Company, error)
+	Delete(ctx context.Context, symbol string) error
+}
+
+// DataSourceStore holds the providers stock prices come from.
+type DataSourceStore interface {
+	GetByName(ctx context.Context, name string) (*models.DataSource, error)
+	Create(ctx context.Context, ds *models.DataSource) (*models.DataSource, error)
+}
+
+// DataFetchJobStore holds the scheduled data fetch jobs.
+type DataFetchJobStore interface {
+	GetByID(ctx context.Context, jobID int) (*models.DataFetchJob, error)
+	FindByEntity(ctx context.Context, sourceID int, entityType, entityValue, dataType string) (*models.DataFetchJob, error)
+	List(ctx context.Context, active *bool) ([]*models.DataFetchJob, error)
+	Create(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error)
+	Update(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error)
+	SetActive(ctx context.Context, jobID int, active bool) (*models.DataFetchJob, error)
+	ScheduleNow(ctx context.Context, jobID int) (*models.DataFetchJob, error)
+	Delete(ctx context.Context, jobID int) error
+	ClaimDueJobs(ctx context.Context, limit int, staleAfter time.Duration) ([]*models.DataFetchJob, error)
+	FinishJob(ctx context.Context, jobID int, status string, nextScheduled time.Time) error
+}
+
+// JobExecutionLogStore records the runs of jobs and synchronizations.
+type JobExecutionLogStore interface {
+	Start(ctx context.Context, entry *models.JobExecutionLog) error
+	Update(ctx context.Context, entry *models.JobExecutionLog) error
+	ListByJob(ctx context.Context, jobID, limit int) ([]*models.JobExecutionLog, error)
+}
+
+// BackfillStore holds backfill runs and the chunks they are split into.
+type BackfillStore interface {
+	CreateRun(ctx context.Context, run *models.BackfillRun, chunks []*models.BackfillChunk) (*models.BackfillRun, error)
+	GetRun(ctx context.Context, runID int) (*models.BackfillRun, error)
+	ListRuns(ctx context.Context) ([]*models.BackfillRun, error)
+	SetRunStatus(ctx context.Context, runID int, status string, from ...string) (*models.BackfillRun, error)
+	ClaimChunk(ctx context.Context, staleAfter time.Duration) (*models.BackfillChunk, error)
+	FinishChunk(ctx context.Context, chunk *models.BackfillChunk) error
+}
+
+// CorporateActionStore holds the splits and dividends used to back-adjust stock prices.
+type CorporateActionStore interface {
+	Upsert(ctx context.Context, actions []*models.CorporateAction) ([]string, error)
+	ListBySymbol(ctx context.Context, symbol string, since time.Time) ([]*models.CorporateAction, error)
+	MergeIntoPrices(ctx context.Context, symbol string) (int, error)
+}
+
+// Compile-time checks that the repositories implement the interfaces.
+var (
+	_ StockStore           = (*StockRepository)(nil)
+	_ CompanyStore         = (*CompanyRepository)(nil)
+	_ DataSourceStore      = (*DataSourceRepository)(nil)
+	_ DataFetchJobStore    = (*DataFetchJobRepository)(nil)
+	_ JobExecutionLogStore = (*JobExecutionLogRepository)(nil)
+	_ BackfillStore        = (*BackfillRepository)(nil)
+	_ CorporateActionStore = (*CorporateActionRepository)(nil)
+	_ clients.QuotaStore   = (*ProviderQuotaRepository)(nil)
+
+	_ StockStore           = (*SQLiteStockRepository)(nil)
+	_ CompanyStore         = (*SQLiteCompanyRepository)(nil)
+	_ DataSourceStore      = (*SQLiteDataSourceRepository)(nil)
+	_ DataFetchJobStore    = (*SQLiteDataFetchJobRepository)(nil)
+	_ JobExecutionLogStore = (*SQLiteJobExecutionLogRepository)(nil)
+	_ BackfillStore        = (*SQLiteBackfillRepository)(nil)
+	_ CorporateActionStore = (*SQLiteCorporateActionRepository)(nil)
+	_ clients.QuotaStore   = (*SQLiteProviderQuotaRepository)(nil)
+
+	_ StockStore           = (*MemoryStockRepository)(nil)
+	_ CompanyStore         = (*MemoryCompanyRepository)(nil)
+	_ DataSourceStore      = (*MemoryDataSourceRepository)(nil)
+	_ DataFetchJobStore    = (*MemoryDataFetchJobRepository)(nil)
+	_ JobExecutionLogStore = (*MemoryJobExecutionLogRepository)(nil)
+)
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"pocketanalyst/internal/models"
+)
+
+// JobExecutionLogRepository handles DB operations for job execution logs
+type JobExecutionLogRepository struct {
+	db *sql.DB
+}
+
+// NewJobExecutionLogRepository creates a new job execution log repository
+func NewJobExecutionLogRepository(db *sql.DB) *JobExecutionLogRepository {
+	return &JobExecutionLogRepository{db: db}
+}
+
+// Start inserts the log of a job that just started and sets its LogID. A JobID of 0 is stored as NULL.
+func (lr *JobExecutionLogRepository) Start(ctx context.Context, entry *models.JobExecutionLog) error {
+	detailsJSON, err := encodeDetails(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	err = lr.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO job_execution_logs
+		(job_id, job_type, start_time, status, records_processed, details, last_updated)
+		VALUES (NULLIF($1, 0), $2, $3, $4, 0, $5, NOW())
+		RETURNING log_id
+		`,
+		entry.JobID,
+		entry.JobType,
+		entry.StartTime,
+		entry.Status,
+		detailsJSON,
+	).Scan(&entry.LogID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create job execution log: %w", err)
+	}
+	return nil
+}
+
+// Update stores the end time, status, record count, error message and details of a started log.
+// A zero EndTime is stored as NULL, which lets running jobs report their progress.
+func (lr *JobExecutionLogRepository) Update(ctx context.Context, entry *models.JobExecutionLog) error {
+	detailsJSON, err := encodeDetails(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	_, err = lr.db.ExecContext(
+		ctx,
+		`
+		UPDATE job_execution_logs
+		SET end_time = $2, status = $3, records_processed = $4, error_message = NULLIF($5, ''),
+		details = $6, last_updated = NOW()
+		WHERE log_id = $1
+		`,
+		entry.LogID,
+		sql.NullTime{Time: entry.EndTime, Valid: !entry.EndTime.IsZero()},
+		entry.Status,
+		entry.RecordsProcessed,
+		entry.ErrorMessage,
+		detailsJSON,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update job execution log %d: %w", entry.LogID, err)
+	}
+	return nil
+}
+
+// ListByJob returns the most recent logs of a data fetch job, newest first.
+func (lr *JobExecutionLogRepository) ListByJob(ctx context.Context, jobID, limit int) ([]*models.JobExecutionLog, error) {
+	rows, err := lr.db.QueryContext(
+		ctx,
+		`
+		SELECT log_id, job_id, job_type, start_time, end_time, status, records_processed,
+		       error_message, details, last_updated
+		FROM job_execution_logs
+		WHERE job_id = $1
+		ORDER BY start_time DESC, log_id DESC
+		LIMIT $2
+		`,
+		jobID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs of job %d: %w", jobID, err)
+	}
+	defer rows.Close()
+
+	logs := []*models.JobExecutionLog{}
+	for rows.Next() {
+		var entry models.JobExecutionLog
+		var id sql.NullInt64
+		var endTime, lastUpdated sql.NullTime
+		var recordsProcessed sql.NullInt64
+		var errorMessage sql.NullString
+		var detailsJSON []byte
+
+		err := rows.Scan(
+			&entry.LogID,
+			&id,
+			&entry.JobType,
+			&entry.StartTime,
+			&endTime,
+			&entry.Status,
+			&recordsProcessed,
+			&errorMessage,
+			&detailsJSON,
+			&lastUpdated,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job execution log: %w", err)
+		}
+
+		entry.JobID = int(id.Int64)
+		entry.EndTime = endTime.Time
+		entry.RecordsProcessed = int(recordsProcessed.Int64)
+		entry.ErrorMessage = errorMessage.String
+		entry.LastUpdated = lastUpdated.Time
+
+		entry.Details = make(map[string]any)
+		if len(detailsJSON) > 0 {
+			if err := json.Unmarshal(detailsJSON, &entry.Details); err != nil {
+				return nil, fmt.Errorf("error parsing details of log %d: %w", entry.LogID, err)
+			}
+		}
+		logs = append(logs, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job execution logs: %w", err)
+	}
+	return logs, nil
+}
+
+// encodeDetails converts the details of a log to JSON, storing an empty object for none.
+func encodeDetails(details map[string]any) ([]byte, error) {
+	if len(details) == 0 {
+		return []byte("{}"), nil
+	}
+
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job details: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,405 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"sort"
+	"time"
+)
+
+// MemoryDataFetchJobRepository is the in-memory DataFetchJobStore.
+type MemoryDataFetchJobRepository struct {
+	db *MemoryDB
+}
+
+// NewMemoryDataFetchJobRepository creates a data fetch job repository backed by db.
+func NewMemoryDataFetchJobRepository(db *MemoryDB) *MemoryDataFetchJobRepository {
+	return &MemoryDataFetchJobRepository{db: db}
+}
+
+// GetByID retrieves a job by its ID. A NotFoundError is returned if there is no such job.
+func (r *MemoryDataFetchJobRepository) GetByID(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	job, ok := r.db.jobs[jobID]
+	if !ok {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	return cloneDataFetchJob(job)
+}
+
+// FindByEntity returns the job fetching dataType for the given entity from a source, or nil if there is none.
+func (r *MemoryDataFetchJobRepository) FindByEntity(
+	ctx context.Context,
+	sourceID int,
+	entityType, entityValue, dataType string,
+) (*models.DataFetchJob, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	job := r.db.findJob(sourceID, entityType, entityValue, dataType)
+	if job == nil {
+		return nil, nil
+	}
+	return cloneDataFetchJob(job)
+}
+
+// List returns all jobs ordered by ID. If active is not nil, only jobs with that is_active value are returned.
+func (r *MemoryDataFetchJobRepository) List(ctx context.Context, active *bool) ([]*models.DataFetchJob, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	jobs := []*models.DataFetchJob{}
+	for _, job := range r.db.sortedJobs() {
+		if active != nil && job.IsActive != *active {
+			continue
+		}
+		copied, err := cloneDataFetchJob(job)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, copied)
+	}
+	return jobs, nil
+}
+
+// Create inserts a new job and returns it as stored. A ConflictError is returned if a job for the same
+// source, entity and data type already exists.
+func (r *MemoryDataFetchJobRepository) Create(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if err := r.db.checkJob(job); err != nil {
+		return nil, err
+	}
+
+	stored, err := cloneDataFetchJob(job)
+	if err != nil {
+		return nil, err
+	}
+	r.db.nextJobID++
+	stored.JobID = r.db.nextJobID
+	stored.LastExecution = time.Time{}
+	stored.LastSuccess = time.Time{}
+	stored.NextScheduled = memoryTimestamp(job.NextScheduled)
+	stored.RunRequested = time.Time{}
+	stored.LastUpdated = memoryNow()
+	r.db.jobs[stored.JobID] = stored
+
+	return cloneDataFetchJob(stored)
+}
+
+// Update stores the definition of an existing job: its source, entity, data type, frequency, parameters,
+// next scheduled run and is_active. The execution history is left alone.
+func (r *MemoryDataFetchJobRepository) Update(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.jobs[job.JobID]
+	if !ok {
+		return nil, errors.NewNotFoundError("DataFetchJob", job.JobID)
+	}
+	if err := r.db.checkJob(job); err != nil {
+		return nil, err
+	}
+
+	updated, err := cloneDataFetchJob(job)
+	if err != nil {
+		return nil, err
+	}
+	updated.LastExecution = stored.LastExecution
+	updated.LastSuccess = stored.LastSuccess
+	updated.Status = stored.Status
+	updated.RunRequested = stored.RunRequested
+	updated.NextScheduled = memoryTimestamp(job.NextScheduled)
+	updated.LastUpdated = memoryNow()
+	r.db.jobs[job.JobID] = updated
+
+	return cloneDataFetchJob(updated)
+}
+
+// SetActive pauses (false) or resumes (true) a job without touching its schedule.
+func (r *MemoryDataFetchJobRepository) SetActive(ctx context.Context, jobID int, active bool) (*models.DataFetchJob, error) {
+	return r.modify(jobID, func(job *models.DataFetchJob) {
+		job.IsActive = active
+	})
+}
+
+// ScheduleNow requests a run of the job, so the scheduler picks it up on its next poll. The job keeps its
+// schedule. The outcome of the previous run is reset to PENDING, unless the job is running right now.
+func (r *MemoryDataFetchJobRepository) ScheduleNow(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	return r.modify(jobID, func(job *models.DataFetchJob) {
+		job.RunRequested = memoryNow()
+		if job.Status != models.JobStatusRunning {
+			job.Status = models.JobStatusPending
+		}
+	})
+}
+
+// modify applies change to a stored job and returns the result. A NotFoundError is returned if there is no
+// such job.
+func (r *MemoryDataFetchJobRepository) modify(jobID int, change func(job *models.DataFetchJob)) (*models.DataFetchJob, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	job, ok := r.db.jobs[jobID]
+	if !ok {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	change(job)
+	job.LastUpdated = memoryNow()
+	return cloneDataFetchJob(job)
+}
+
+// Delete removes a job together with its execution logs.
+func (r *MemoryDataFetchJobRepository) Delete(ctx context.Context, jobID int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.jobs[jobID]; !ok {
+		return errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+
+	for logID, entry := range r.db.logs {
+		if entry.JobID == jobID {
+			delete(r.db.logs, logID)
+		}
+	}
+	delete(r.db.jobs, jobID)
+	return nil
+}
+
+// ClaimDueJobs marks up to limit active jobs whose next scheduled time has passed, or whose run was requested,
+// as RUNNING and returns them, earliest first. Jobs stuck in RUNNING for longer than staleAfter are claimed again.
+func (r *MemoryDataFetchJobRepository) ClaimDueJobs(ctx context.Context, limit int, staleAfter time.Duration) ([]*models.DataFetchJob, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := memoryNow()
+	due := []*models.DataFetchJob{}
+	for _, job := range r.db.sortedJobs() {
+		if !job.IsActive || (job.NextScheduled.After(now) && job.RunRequested.IsZero()) {
+			continue
+		}
+		// A running job without a last execution is never stale, like comparing NULL in SQL
+		if job.Status == models.JobStatusRunning &&
+			(job.LastExecution.IsZero() || !job.LastExecution.Before(now.Add(-staleAfter))) {
+			continue
+		}
+		due = append(due, job)
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].DueAt().Before(due[j].DueAt()) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := []*models.DataFetchJob{}
+	for _, job := range due {
+		job.Status = models.JobStatusRunning
+		job.LastExecution = now
+		job.LastUpdated = now
+
+		copied, err := cloneDataFetchJob(job)
+		if err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, copied)
+	}
+	return claimed, nil
+}
+
+// FinishJob records the outcome of a claimed job and schedules its next run. A zero nextScheduled time
+// deactivates the job instead. The run request the job was claimed for is cleared, one made while it was
+// running is kept. Unknown jobs are ignored.
+func (r *MemoryDataFetchJobRepository) FinishJob(ctx context.Context, jobID int, status string, nextScheduled time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	job, ok := r.db.jobs[jobID]
+	if !ok {
+		return nil
+	}
+
+	now := memoryNow()
+	job.Status = status
+	if status == models.JobStatusSuccess {
+		job.LastSuccess = now
+	}
+	if nextScheduled.IsZero() {
+		job.IsActive = false
+	} else {
+		job.NextScheduled = memoryTimestamp(nextScheduled)
+	}
+	if !job.RunRequested.After(job.LastExecution) {
+		job.RunRequested = time.Time{}
+	}
+	job.LastUpdated = now
+	return nil
+}
+
+// findJob returns the job matching the fetch_job_unique constraint, or nil. The caller must hold mu.
+func (m *MemoryDB) findJob(sourceID int, entityType, entityValue, dataType string) *models.DataFetchJob {
+	for _, job := range m.jobs {
+		if job.SourceID == sourceID && job.EntityType == entityType &&
+			job.EntityValue == entityValue && job.DataType == dataType {
+			return job
+		}
+	}
+	return nil
+}
+
+// checkJob enforces the constraints of data_fetch_jobs on job before it is written, returning the errors
+// jobWriteError translates the PostgreSQL violations into. The caller must hold mu.
+func (m *MemoryDB) checkJob(job *models.DataFetchJob) error {
+	if existing := m.findJob(job.SourceID, job.EntityType, job.EntityValue, job.DataType); existing != nil &&
+		existing.JobID != job.JobID {
+		return jobConflictError(job)
+	}
+
+	if _, ok := m.sourceNamesByID()[job.SourceID]; !ok {
+		return jobSourceError(job)
+	}
+	return nil
+}
+
+// sortedJobs returns the stored jobs ordered by ID. The caller must hold mu.
+func (m *MemoryDB) sortedJobs() []*models.DataFetchJob {
+	jobs := make([]*models.DataFetchJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].JobID < jobs[j].JobID })
+	return jobs
+}
+
+// cloneDataFetchJob copies job, passing its parameters through JSON like the parameters column.
+func cloneDataFetchJob(job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	encoded, err := encodeParameters(job.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *job
+	if copied.Parameters, err = decodeJSONColumn(encoded); err != nil {
+		return nil, fmt.Errorf("error parsing parameters of job %d: %w", job.JobID, err)
+	}
+	return &copied, nil
+}
+
+// MemoryJobExecutionLogRepository is the in-memory JobExecutionLogStore.
+type MemoryJobExecutionLogRepository struct {
+	db *MemoryDB
+}
+
+// NewMemoryJobExecutionLogRepository creates a job execution log repository backed by db.
+func NewMemoryJobExecutionLogRepository(db *MemoryDB) *MemoryJobExecutionLogRepository {
+	return &MemoryJobExecutionLogRepository{db: db}
+}
+
+// Start inserts the log of a job that just started and sets its LogID. A JobID of 0 means the run was not
+// started by a data fetch job, any other JobID must exist.
+func (r *MemoryJobExecutionLogRepository) Start(ctx context.Context, entry *models.JobExecutionLog) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.jobs[entry.JobID]; entry.JobID != 0 && !ok {
+		return fmt.Errorf("failed to create job execution log: job %d does not exist", entry.JobID)
+	}
+
+	stored, err := cloneJobExecutionLog(entry)
+	if err != nil {
+		return err
+	}
+	r.db.nextLogID++
+	stored.LogID = r.db.nextLogID
+	stored.StartTime = memoryTimestamp(entry.StartTime)
+	stored.EndTime = time.Time{}
+	stored.RecordsProcessed = 0
+	stored.ErrorMessage = ""
+	stored.LastUpdated = memoryNow()
+	r.db.logs[stored.LogID] = stored
+
+	entry.LogID = stored.LogID
+	return nil
+}
+
+// Update stores the end time, status, record count, error message and details of a started log.
+func (r *MemoryJobExecutionLogRepository) Update(ctx context.Context, entry *models.JobExecutionLog) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.logs[entry.LogID]
+	if !ok {
+		return nil
+	}
+
+	details, err := cloneJobExecutionLog(entry)
+	if err != nil {
+		return err
+	}
+	stored.EndTime = time.Time{}
+	if !entry.EndTime.IsZero() {
+		stored.EndTime = memoryTimestamp(entry.EndTime)
+	}
+	stored.Status = entry.Status
+	stored.RecordsProcessed = entry.RecordsProcessed
+	stored.ErrorMessage = entry.ErrorMessage
+	stored.Details = details.Details
+	stored.LastUpdated = memoryNow()
+	return nil
+}
+
+// ListByJob returns the most recent logs of a data fetch job, newest first.
+func (r *MemoryJobExecutionLogRepository) ListByJob(ctx context.Context, jobID, limit int) ([]*models.JobExecutionLog, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	logs := []*models.JobExecutionLog{}
+	for _, entry := range r.db.logs {
+		if entry.JobID != jobID || jobID == 0 {
+			continue
+		}
+		copied, err := cloneJobExecutionLog(entry)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, copied)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if !logs[i].StartTime.Equal(logs[j].StartTime) {
+			return logs[i].StartTime.After(logs[j].StartTime)
+		}
+		return logs[i].LogID > logs[j].LogID
+	})
+	if limit >= 0 && len(logs) > limit {
+		logs = logs[:limit]
+	}
+	return logs, nil
+}
+
+// cloneJobExecutionLog copies entry, passing its details through JSON like the details column.
+func cloneJobExecutionLog(entry *models.JobExecutionLog) (*models.JobExecutionLog, error) {
+	encoded, err := encodeDetails(entry.Details)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *entry
+	if copied.Details, err = decodeJSONColumn(encoded); err != nil {
+		return nil, fmt.Errorf("error parsing details of log %d: %w", entry.LogID, err)
+	}
+	return &copied, nil
+}
@@ -0,0 +1,554 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryDB holds the tables of the in-memory repositories. It is meant for tests and keeps the semantics of
+// the PostgreSQL schema: the same unique constraints, generated IDs, NULL handling and column precision.
+// All repositories created from one MemoryDB share its data, like repositories sharing a *sql.DB.
+type MemoryDB struct {
+	mu sync.Mutex
+
+	companies     map[string]*models.Company // Keyed by symbol
+	nextCompanyID int
+	dataSources   map[string]*models.DataSource // Keyed by source_name
+	nextSourceID  int
+	prices        map[stockPriceKey]*memoryPrice
+	nextPriceID   int
+	jobs          map[int]*models.DataFetchJob
+	nextJobID     int
+	logs          map[int]*models.JobExecutionLog
+	nextLogID     int
+}
+
+// memoryPrice is a stored row of stock_prices.
+type memoryPrice struct {
+	stock    models.Stock
+	sourceID int
+}
+
+// NewMemoryDB creates an empty in-memory database.
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		companies:   make(map[string]*models.Company),
+		dataSources: make(map[string]*models.DataSource),
+		prices:      make(map[stockPriceKey]*memoryPrice),
+		jobs:        make(map[int]*models.DataFetchJob),
+		logs:        make(map[int]*models.JobExecutionLog),
+	}
+}
+
+// memoryNow returns the current time the way a TIMESTAMP column stores it.
+func memoryNow() time.Time {
+	return memoryTimestamp(time.Now())
+}
+
+// memoryTimestamp converts t to a TIMESTAMP value, which is UTC with microsecond precision.
+func memoryTimestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// memoryDate converts t to a DATE value.
+func memoryDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// roundNumeric rounds to the given number of decimals, like storing into a NUMERIC(p, decimals) column.
+func roundNumeric(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
+
+// decodeJSONColumn decodes a JSONB column the way the PostgreSQL repositories do, so numbers come back
+// as float64 and the caller's map is never shared.
+func decodeJSONColumn(encoded []byte) (map[string]any, error) {
+	decoded := make(map[string]any)
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// MemoryDataSourceRepository is the in-memory DataSourceStore.
+type MemoryDataSourceRepository struct {
+	db *MemoryDB
+}
+
+// NewMemoryDataSourceRepository creates a data source repository backed by db.
+func NewMemoryDataSourceRepository(db *MemoryDB) *MemoryDataSourceRepository {
+	return &MemoryDataSourceRepository{db: db}
+}
+
+// GetByName retrieves a data source by its name. A NotFoundError is returned if no data source has that name.
+func (r *MemoryDataSourceRepository) GetByName(ctx context.Context, name string) (*models.DataSource, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	ds, ok := r.db.dataSources[name]
+	if !ok {
+		return nil, errors.NewNotFoundError("DataSource", name)
+	}
+	return cloneDataSource(ds)
+}
+
+// Create registers a new data source and returns it with its generated ID. If a data source with the
+// same name already exists, the existing one is returned unchanged.
+func (r *MemoryDataSourceRepository) Create(ctx context.Context, ds *models.DataSource) (*models.DataSource, error) {
+	if err := ds.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if existing, ok := r.db.dataSources[ds.SourceName]; ok {
+		return cloneDataSource(existing)
+	}
+
+	stored, err := cloneDataSource(ds)
+	if err != nil {
+		return nil, err
+	}
+	r.db.nextSourceID++
+	stored.SourceID = r.db.nextSourceID
+	stored.CreatedAt = memoryNow()
+	stored.LastUpdated = stored.CreatedAt
+	r.db.dataSources[stored.SourceName] = stored
+
+	return cloneDataSource(stored)
+}
+
+// cloneDataSource copies ds, passing its config parameters through JSON like the config_parameters column.
+func cloneDataSource(ds *models.DataSource) (*models.DataSource, error) {
+	encoded := []byte("{}")
+	if len(ds.ConfigParameters) > 0 {
+		var err error
+		if encoded, err = json.Marshal(ds.ConfigParameters); err != nil {
+			return nil, fmt.Errorf("error encoding config parameters: %w", err)
+		}
+	}
+
+	copied := *ds
+	config, err := decodeJSONColumn(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config parameters: %w", err)
+	}
+	copied.ConfigParameters = config
+	return &copied, nil
+}
+
+// MemoryCompanyRepository is the in-memory CompanyStore.
+type MemoryCompanyRepository struct {
+	db *MemoryDB
+}
+
+// NewMemoryCompanyRepository creates a company repository backed by db.
+func NewMemoryCompanyRepository(db *MemoryDB) *MemoryCompanyRepository {
+	return &MemoryCompanyRepository{db: db}
+}
+
+// ListActiveSymbols returns the symbols of all active companies in alphabetical order.
+func (r *MemoryCompanyRepository) ListActiveSymbols(ctx context.Context) ([]string, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	symbols := []string{}
+	for symbol, company := range r.db.companies {
+		if company.IsActive {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// GetExchange returns the exchange a company is listed on. It is empty for unknown symbols and
+// companies without an exchange.
+func (r *MemoryCompanyRepository) GetExchange(ctx context.Context, symbol string) (string, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if company, ok := r.db.companies[symbol]; ok {
+		return company.Exchange, nil
+	}
+	return "", nil
+}
+
+// GetBySymbol retrieves a company. A NotFoundError is returned if there is no such company.
+func (r *MemoryCompanyRepository) GetBySymbol(ctx context.Context, symbol string) (*models.Company, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	company, ok := r.db.companies[symbol]
+	if !ok {
+		return nil, errors.NewNotFoundError("Company", symbol)
+	}
+	stored := *company
+	return &stored, nil
+}
+
+// ListPlaceholders returns up to limit active companies that never received a profile, the ones checked longest
+// ago first.
+func (r *MemoryCompanyRepository) ListPlaceholders(ctx context.Context, limit int) ([]*models.Company, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	companies := []*models.Company{}
+	for _, company := range r.db.companies {
+		if company.IsActive && company.IsPlaceholder() {
+			stored := *company
+			companies = append(companies, &stored)
+		}
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		if !companies[i].ProfileChecked.Equal(companies[j].ProfileChecked) {
+			return companies[i].ProfileChecked.Before(companies[j].ProfileChecked)
+		}
+		return companies[i].Symbol < companies[j].Symbol
+	})
+
+	if len(companies) > limit {
+		companies = companies[:limit]
+	}
+	return companies, nil
+}
+
+// MarkProfileChecked records that the profile of a company was requested now.
+func (r *MemoryCompanyRepository) MarkProfileChecked(ctx context.Context, symbol string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if company, ok := r.db.companies[symbol]; ok {
+		company.ProfileChecked = memoryNow()
+	}
+	return nil
+}
+
+// Create inserts a new company and returns it as stored. A ConflictError is returned if the symbol exists.
+func (r *MemoryCompanyRepository) Create(ctx context.Context, company *models.Company) (*models.Company, error) {
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.companies[company.Symbol]; ok {
+		return nil, companyConflictError(company.Symbol)
+	}
+
+	r.db.nextCompanyID++
+	stored := *company
+	stored.CompanyID = r.db.nextCompanyID
+	stored.ProfileUpdated = memoryTimestamp(company.ProfileUpdated)
+	stored.ProfileChecked = time.Time{}
+	stored.LastUpdated = memoryNow()
+	r.db.companies[stored.Symbol] = &stored
+
+	created := stored
+	return &created, nil
+}
+
+// Update stores the name, sector, industry, exchange, is_active and profile of an existing company.
+func (r *MemoryCompanyRepository) Update(ctx context.Context, company *models.Company) (*models.Company, error) {
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.companies[company.Symbol]
+	if !ok {
+		return nil, errors.NewNotFoundError("Company", company.Symbol)
+	}
+
+	stored := *company
+	stored.CompanyID = existing.CompanyID
+	stored.ProfileUpdated = memoryTimestamp(company.ProfileUpdated)
+	stored.ProfileChecked = existing.ProfileChecked
+	stored.LastUpdated = memoryNow()
+	r.db.companies[stored.Symbol] = &stored
+
+	updated := stored
+	return &updated, nil
+}
+
+// Delete removes a company. A ConflictError is returned while stock prices reference it.
+func (r *MemoryCompanyRepository) Delete(ctx context.Context, symbol string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.companies[symbol]; !ok {
+		return errors.NewNotFoundError("Company", symbol)
+	}
+	for _, price := range r.db.prices {
+		if price.stock.Symbol == symbol {
+			return companyInUseError(symbol)
+		}
+	}
+
+	delete(r.db.companies, symbol)
+	return nil
+}
+
+// MemoryStockRepository is the in-memory StockStore.
+type MemoryStockRepository struct {
+	db             *MemoryDB
+	dataSourceRepo DataSourceStore
+}
+
+// NewMemoryStockRepository creates a stock repository backed by db. Data sources are resolved through
+// dataSourceRepo, normally a MemoryDataSourceRepository of the same db.
+func NewMemoryStockRepository(db *MemoryDB, dataSourceRepo DataSourceStore) *MemoryStockRepository {
+	return &MemoryStockRepository{db: db, dataSourceRepo: dataSourceRepo}
+}
+
+// SaveStocksToDatabase upserts the stock prices. Every stock is checked before anything is stored, so a
+// rejected stock leaves the database untouched, like the rolled back transaction of StockRepository.
+// Unknown data sources are registered even then, as StockRepository registers them outside its transaction.
+func (r *MemoryStockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*models.Stock) (*SaveResult, error) {
+	sourceIDs := make([]int, len(stocks))
+	for i, stock := range stocks {
+		sourceID, err := lookupSourceID(ctx, r.dataSourceRepo, stock.DataSource)
+		if err != nil {
+			return nil, err
+		}
+		sourceIDs[i] = sourceID
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Companies are only created once the whole batch is known to be valid
+	newCompanies := make(map[string]int)
+	nextCompanyID := r.db.nextCompanyID
+	for _, stock := range stocks {
+		if company, ok := r.db.companies[stock.Symbol]; ok {
+			stock.CompanyID = company.CompanyID
+		} else if companyID, ok := newCompanies[stock.Symbol]; ok {
+			stock.CompanyID = companyID
+		} else {
+			nextCompanyID++
+			newCompanies[stock.Symbol] = nextCompanyID
+			stock.CompanyID = nextCompanyID
+		}
+
+		if err := stock.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	for symbol, companyID := range newCompanies {
+		r.db.companies[symbol] = &models.Company{
+			CompanyID:   companyID,
+			Symbol:      symbol,
+			Name:        symbol,
+			IsActive:    true,
+			LastUpdated: memoryNow(),
+		}
+	}
+	r.db.nextCompanyID = nextCompanyID
+
+	result := &SaveResult{}
+	for i, stock := range stocks {
+		row := storedStock(stock)
+		key := stockPriceKey{companyID: stock.CompanyID, date: row.Date.Format("2006-01-02"), sourceID: sourceIDs[i]}
+
+		existing, ok := r.db.prices[key]
+		switch {
+		case !ok:
+			r.db.nextPriceID++
+			row.PriceID = r.db.nextPriceID
+			r.db.prices[key] = &memoryPrice{stock: row, sourceID: sourceIDs[i]}
+			result.Inserted++
+		case sameStockValues(&existing.stock, &row):
+			// Skipped like the WHERE clause of the upsert, so the PriceID is not reported
+			result.Unchanged++
+			continue
+		default:
+			row.PriceID = existing.stock.PriceID
+			existing.stock = row
+			result.Updated++
+		}
+		stock.PriceID = row.PriceID
+	}
+	return result, nil
+}
+
+// storedStock returns stock as the stock_prices columns store it: a date without time, prices with
+// 5 decimals and a whole volume.
+func storedStock(stock *models.Stock) models.Stock {
+	row := *stock
+	row.Date = memoryDate(stock.Date)
+	row.OpenPrice = roundNumeric(stock.OpenPrice, 5)
+	row.HighPrice = roundNumeric(stock.HighPrice, 5)
+	row.LowPrice = roundNumeric(stock.LowPrice, 5)
+	row.ClosePrice = roundNumeric(stock.ClosePrice, 5)
+	row.AdjustedClose = roundNumeric(stock.AdjustedClose, 5)
+	row.Volume = math.Round(stock.Volume)
+	row.DividendAmount = roundNumeric(stock.DividendAmount, 5)
+	row.SplitCoefficient = roundNumeric(stock.SplitCoefficient, 5)
+	row.LastUpdated = memoryNow()
+	return row
+}
+
+// sameStockValues compares the columns the upsert checks before updating a row.
+func sameStockValues(a, b *models.Stock) bool {
+	return a.OpenPrice == b.OpenPrice &&
+		a.HighPrice == b.HighPrice &&
+		a.LowPrice == b.LowPrice &&
+		a.ClosePrice == b.ClosePrice &&
+		a.AdjustedClose == b.AdjustedClose &&
+		a.Volume == b.Volume &&
+		a.DividendAmount == b.DividendAmount &&
+		a.SplitCoefficient == b.SplitCoefficient
+}
+
+// RetrieveStocksFromDatabase returns the stock prices of symbol between startDate and endDate, newest first.
+func (r *MemoryStockRepository) RetrieveStocksFromDatabase(
+	ctx context.Context,
+	symbol string,
+	startDate, endDate time.Time,
+) ([]*models.Stock, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sourceNames := r.db.sourceNamesByID()
+
+	var stocks []*models.Stock
+	for _, price := range r.db.prices {
+		if price.stock.Symbol != symbol || price.stock.Date.Before(startDate) || price.stock.Date.After(endDate) {
+			continue
+		}
+		stock := price.stock
+		stock.DataSource = sourceNames[price.sourceID]
+		stocks = append(stocks, &stock)
+	}
+
+	sort.Slice(stocks, func(i, j int) bool {
+		if !stocks[i].Date.Equal(stocks[j].Date) {
+			return stocks[i].Date.After(stocks[j].Date)
+		}
+		return stocks[i].DataSource < stocks[j].DataSource
+	})
+	return stocks, nil
+}
+
+// GetLatestStockDate returns the most recent stored trading date for symbol from any of the named data sources.
+// The zero time is returned when nothing has been stored yet.
+func (r *MemoryStockRepository) GetLatestStockDate(ctx context.Context, symbol string, sourceNames []string) (time.Time, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	wanted := make(map[int]bool)
+	for _, name := range sourceNames {
+		if ds, ok := r.db.dataSources[name]; ok {
+			wanted[ds.SourceID] = true
+		}
+	}
+
+	var latest time.Time
+	for _, price := range r.db.prices {
+		if price.stock.Symbol == symbol && wanted[price.sourceID] && price.stock.Date.After(latest) {
+			latest = price.stock.Date
+		}
+	}
+	return latest, nil
+}
+
+// GetStoredDates returns the dates stored for symbol between startDate and endDate, grouped by data source
+// name. The dates of each source are in ascending order.
+func (r *MemoryStockRepository) GetStoredDates(
+	ctx context.Context,
+	symbol string,
+	startDate, endDate time.Time,
+) (map[string][]time.Time, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sourceNames := r.db.sourceNamesByID()
+
+	dates := make(map[string][]time.Time)
+	for _, price := range r.db.prices {
+		if price.stock.Symbol != symbol || price.stock.Date.Before(startDate) || price.stock.Date.After(endDate) {
+			continue
+		}
+		source := sourceNames[price.sourceID]
+		dates[source] = append(dates[source], price.stock.Date)
+	}
+
+	for _, sourceDates := range dates {
+		sort.Slice(sourceDates, func(i, j int) bool { return sourceDates[i].Before(sourceDates[j]) })
+	}
+	return dates, nil
+}
+
+// GetStoredDateRanges returns the first and last date stored for symbol, across its whole history, grouped by
+// data source name.
+func (r *MemoryStockRepository) GetStoredDateRanges(ctx context.Context, symbol string) (map[string]StoredRange, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sourceNames := r.db.sourceNamesByID()
+
+	ranges := make(map[string]StoredRange)
+	for _, price := range r.db.prices {
+		if price.stock.Symbol != symbol {
+			continue
+		}
+		source := sourceNames[price.sourceID]
+		stored, ok := ranges[source]
+		if !ok || price.stock.Date.Before(stored.First) {
+			stored.First = price.stock.Date
+		}
+		if !ok || price.stock.Date.After(stored.Last) {
+			stored.Last = price.stock.Date
+		}
+		ranges[source] = stored
+	}
+	return ranges, nil
+}
+
+// UpdateAdjustedCloses stores the AdjustedClose of each stock by its PriceID. Rows already holding that
+// value are left alone. It returns the number of rows changed.
+func (r *MemoryStockRepository) UpdateAdjustedCloses(ctx context.Context, stocks []*models.Stock) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	byPriceID := make(map[int]*memoryPrice, len(r.db.prices))
+	for _, price := range r.db.prices {
+		byPriceID[price.stock.PriceID] = price
+	}
+
+	changed := 0
+	for _, stock := range stocks {
+		price, ok := byPriceID[stock.PriceID]
+		adjustedClose := roundNumeric(stock.AdjustedClose, 5)
+		if !ok || price.stock.AdjustedClose == adjustedClose {
+			continue
+		}
+		price.stock.AdjustedClose = adjustedClose
+		price.stock.LastUpdated = memoryNow()
+		changed++
+	}
+	return changed, nil
+}
+
+// sourceNamesByID maps every source_id onto its source_name. The caller must hold mu.
+func (m *MemoryDB) sourceNamesByID() map[int]string {
+	names := make(map[int]string, len(m.dataSources))
+	for name, ds := range m.dataSources {
+		names[ds.SourceID] = name
+	}
+	return names
+}
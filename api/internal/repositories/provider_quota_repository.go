@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ProviderQuotaRepository persists daily request counts per data source. It implements clients.QuotaStore,
+// which lets rate limiters keep their daily quota across restarts.
+type ProviderQuotaRepository struct {
+	db *sql.DB
+}
+
+// NewProviderQuotaRepository creates a new provider quota repository
+func NewProviderQuotaRepository(db *sql.DB) *ProviderQuotaRepository {
+	return &ProviderQuotaRepository{db: db}
+}
+
+// GetUsage returns how many requests were made to the provider on the given day.
+func (pqr *ProviderQuotaRepository) GetUsage(ctx context.Context, provider string, day time.Time) (int, error) {
+	var count int
+	err := pqr.db.QueryRowContext(
+		ctx,
+		`SELECT request_count FROM provider_quota_usage WHERE source_name = $1 AND usage_date = $2`,
+		provider,
+		day,
+	).Scan(&count)
+
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query quota usage for %s: %w", provider, err)
+	}
+	return count, nil
+}
+
+// IncrementUsage atomically records one more request to the provider on the given day and returns the new total.
+func (pqr *ProviderQuotaRepository) IncrementUsage(ctx context.Context, provider string, day time.Time) (int, error) {
+	var count int
+	err := pqr.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO provider_quota_usage (source_name, usage_date, request_count, last_updated)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (source_name, usage_date)
+		DO UPDATE SET
+		request_count = provider_quota_usage.request_count + 1,
+		last_updated = EXCLUDED.last_updated
+		RETURNING request_count
+		`,
+		provider,
+		day,
+	).Scan(&count)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to record quota usage for %s: %w", provider, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,298 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"time"
+)
+
+// SQLiteBackfillRepository is the SQLite BackfillStore. The symbols of a run are stored as a JSON array.
+type SQLiteBackfillRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteBackfillRepository creates a backfill repository on a SQLite database.
+func NewSQLiteBackfillRepository(db *sql.DB) *SQLiteBackfillRepository {
+	return &SQLiteBackfillRepository{db: db}
+}
+
+// CreateRun stores a new run together with its chunks in a single transaction and returns it.
+func (r *SQLiteBackfillRepository) CreateRun(
+	ctx context.Context,
+	run *models.BackfillRun,
+	chunks []*models.BackfillChunk,
+) (*models.BackfillRun, error) {
+	if err := run.Validate(); err != nil {
+		return nil, err
+	}
+
+	symbols, err := sqliteJSONList(run.Symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	now := sqliteNow()
+	var runID int
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO backfill_runs (symbols, start_date, end_date, chunk_days, status, created_at, last_updated)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6)
+		RETURNING run_id
+		`,
+		symbols,
+		sqliteDate(run.StartDate),
+		sqliteDate(run.EndDate),
+		run.ChunkDays,
+		models.BackfillStatusRunning,
+		now,
+	).Scan(&runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backfill run: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO backfill_chunks (run_id, symbol, start_date, end_date, status, last_updated)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare chunk insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, chunk := range chunks {
+		_, err := stmt.ExecContext(ctx, runID, chunk.Symbol, sqliteDate(chunk.StartDate), sqliteDate(chunk.EndDate), chunk.Status, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backfill chunk for %s: %w", chunk.Symbol, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetRun(ctx, runID)
+}
+
+// GetRun retrieves a run with its progress. A NotFoundError is returned if there is no such run.
+func (r *SQLiteBackfillRepository) GetRun(ctx context.Context, runID int) (*models.BackfillRun, error) {
+	row := r.db.QueryRowContext(ctx, backfillRunSelect+` WHERE r.run_id = ?1 GROUP BY r.run_id`, runID)
+
+	run, err := scanSQLiteBackfillRun(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("BackfillRun", runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving backfill run %d: %w", runID, err)
+	}
+	return run, nil
+}
+
+// ListRuns returns every run with its progress, newest first.
+func (r *SQLiteBackfillRepository) ListRuns(ctx context.Context) ([]*models.BackfillRun, error) {
+	rows, err := r.db.QueryContext(ctx, backfillRunSelect+` GROUP BY r.run_id ORDER BY r.run_id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backfill runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*models.BackfillRun{}
+	for rows.Next() {
+		run, err := scanSQLiteBackfillRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backfill run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating backfill runs: %w", err)
+	}
+	return runs, nil
+}
+
+// SetRunStatus moves a run to status if its current status is one of from. A ModelValidationError is
+// returned if the run is in any other status.
+func (r *SQLiteBackfillRepository) SetRunStatus(ctx context.Context, runID int, status string, from ...string) (*models.BackfillRun, error) {
+	statuses, err := sqliteJSONList(from)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+		UPDATE backfill_runs SET status = ?2, last_updated = ?4
+		WHERE run_id = ?1 AND status IN (SELECT value FROM json_each(?3))
+		`,
+		runID,
+		status,
+		statuses,
+		sqliteNow(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update backfill run %d: %w", runID, err)
+	}
+
+	run, err := r.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return nil, errors.NewModelValidationError("BackfillRun", "status",
+			fmt.Sprintf("cannot change a %s run to %s", run.Status, status))
+	}
+	return run, nil
+}
+
+// ClaimChunk marks the next pending chunk of a running run as RUNNING and returns it, or nil if there is
+// nothing to do. SQLite runs one write at a time, so concurrent workers never claim the same chunk. Chunks
+// stuck in RUNNING for longer than staleAfter are claimed again.
+func (r *SQLiteBackfillRepository) ClaimChunk(ctx context.Context, staleAfter time.Duration) (*models.BackfillChunk, error) {
+	now := time.Now()
+	var chunk models.BackfillChunk
+	var startDate, endDate sqliteTime
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		UPDATE backfill_chunks
+		SET status = 'RUNNING', last_updated = ?2
+		WHERE chunk_id = (
+			SELECT c.chunk_id
+			FROM backfill_chunks c
+			JOIN backfill_runs r ON r.run_id = c.run_id
+			WHERE r.status = ?1
+			AND (c.status = 'PENDING' OR (c.status = 'RUNNING' AND c.last_updated < ?3))
+			ORDER BY c.run_id, c.chunk_id
+			LIMIT 1
+		)
+		RETURNING chunk_id, run_id, symbol, start_date, end_date, status, attempts, records_processed
+		`,
+		models.BackfillStatusRunning,
+		sqliteTimestamp(now),
+		sqliteTimestamp(now.Add(-staleAfter)),
+	).Scan(
+		&chunk.ChunkID,
+		&chunk.RunID,
+		&chunk.Symbol,
+		&startDate,
+		&endDate,
+		&chunk.Status,
+		&chunk.Attempts,
+		&chunk.RecordsProcessed,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim backfill chunk: %w", err)
+	}
+
+	chunk.StartDate = startDate.Time
+	chunk.EndDate = endDate.Time
+	return &chunk, nil
+}
+
+// FinishChunk stores the status, attempts, record count and error message of a claimed chunk. Once no chunk
+// of its run is left to do, the run is marked COMPLETED, or FAILED if any chunk failed.
+func (r *SQLiteBackfillRepository) FinishChunk(ctx context.Context, chunk *models.BackfillChunk) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	now := sqliteNow()
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		UPDATE backfill_chunks
+		SET status = ?2, attempts = ?3, records_processed = ?4, error_message = NULLIF(?5, ''), last_updated = ?6
+		WHERE chunk_id = ?1
+		`,
+		chunk.ChunkID,
+		chunk.Status,
+		chunk.Attempts,
+		chunk.RecordsProcessed,
+		chunk.ErrorMessage,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update backfill chunk %d: %w", chunk.ChunkID, err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		UPDATE backfill_runs
+		SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM backfill_chunks WHERE run_id = ?1 AND status = 'FAILED') THEN ?2
+			ELSE ?3
+		END,
+		last_updated = ?6
+		WHERE run_id = ?1
+		AND status IN (?4, ?5)
+		AND NOT EXISTS (SELECT 1 FROM backfill_chunks WHERE run_id = ?1 AND status IN ('PENDING', 'RUNNING'))
+		`,
+		chunk.RunID,
+		models.BackfillStatusFailed,
+		models.BackfillStatusCompleted,
+		models.BackfillStatusRunning,
+		models.BackfillStatusPaused,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update backfill run %d: %w", chunk.RunID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// scanSQLiteBackfillRun scans a row selected by backfillRunSelect, which SQLite runs as it is.
+func scanSQLiteBackfillRun(row rowScanner) (*models.BackfillRun, error) {
+	var run models.BackfillRun
+	var symbolsJSON []byte
+	var startDate, endDate, createdAt, lastUpdated sqliteTime
+
+	err := row.Scan(
+		&run.RunID,
+		&symbolsJSON,
+		&startDate,
+		&endDate,
+		&run.ChunkDays,
+		&run.Status,
+		&createdAt,
+		&lastUpdated,
+		&run.TotalChunks,
+		&run.PendingChunks,
+		&run.RunningChunks,
+		&run.CompletedChunks,
+		&run.FailedChunks,
+		&run.RecordsProcessed,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(symbolsJSON, &run.Symbols); err != nil {
+		return nil, fmt.Errorf("error parsing symbols of backfill run %d: %w", run.RunID, err)
+	}
+	run.StartDate = startDate.Time
+	run.EndDate = endDate.Time
+	run.CreatedAt = createdAt.Time
+	run.LastUpdated = lastUpdated.Time
+	return &run, nil
+}
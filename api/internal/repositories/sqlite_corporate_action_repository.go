@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"time"
+)
+
+// SQLiteCorporateActionRepository is the SQLite CorporateActionStore.
+type SQLiteCorporateActionRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCorporateActionRepository creates a corporate action repository on a SQLite database.
+func NewSQLiteCorporateActionRepository(db *sql.DB) *SQLiteCorporateActionRepository {
+	return &SQLiteCorporateActionRepository{db: db}
+}
+
+// Upsert stores the actions in a single transaction, replacing the stored action of the same symbol, type and
+// ex-date. Companies that don't exist yet are created. It returns the symbols whose actions were inserted
+// or changed.
+func (r *SQLiteCorporateActionRepository) Upsert(ctx context.Context, actions []*models.CorporateAction) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Ratios and amounts are rounded like the NUMERIC columns of PostgreSQL, so unchanged values compare equal
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`
+		INSERT INTO corporate_actions
+		(company_id, symbol, action_type, ex_date, ratio, amount, source, last_updated)
+		VALUES (?1, ?2, ?3, ?4, NULLIF(?5, 0), NULLIF(?6, 0), ?7, ?8)
+		ON CONFLICT (company_id, action_type, ex_date)
+		DO UPDATE SET
+		ratio = excluded.ratio,
+		amount = excluded.amount,
+		source = excluded.source,
+		last_updated = excluded.last_updated
+		WHERE (corporate_actions.ratio, corporate_actions.amount)
+		IS NOT (excluded.ratio, excluded.amount)
+		RETURNING action_id
+		`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare corporate action upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	changed := []string{}
+	seen := make(map[string]bool)
+	companyIDs := make(map[string]int)
+	now := sqliteNow()
+	for _, action := range actions {
+		if err := action.Validate(); err != nil {
+			return nil, err
+		}
+
+		companyID, ok := companyIDs[action.Symbol]
+		if !ok {
+			if companyID, err = sqliteCompanyID(ctx, tx, action.Symbol); err != nil {
+				return nil, err
+			}
+			companyIDs[action.Symbol] = companyID
+		}
+		action.CompanyID = companyID
+
+		err := stmt.QueryRowContext(
+			ctx,
+			action.CompanyID,
+			action.Symbol,
+			action.ActionType,
+			sqliteDate(action.ExDate),
+			roundNumeric(action.Ratio, 8),
+			roundNumeric(action.Amount, 5),
+			action.Source,
+			now,
+		).Scan(&action.ActionID)
+
+		// No row means the stored action already matched
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s of %s on %s: %w",
+				action.ActionType, action.Symbol, action.ExDate.Format("2006-01-02"), err)
+		}
+
+		if !seen[action.Symbol] {
+			seen[action.Symbol] = true
+			changed = append(changed, action.Symbol)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+// ListBySymbol returns the actions of symbol with an ex-date after since, oldest first.
+// A zero since returns all actions.
+func (r *SQLiteCorporateActionRepository) ListBySymbol(
+	ctx context.Context,
+	symbol string,
+	since time.Time,
+) ([]*models.CorporateAction, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT action_id, company_id, symbol, action_type, ex_date,
+		       COALESCE(ratio, 0), COALESCE(amount, 0), source, last_updated
+		FROM corporate_actions
+		WHERE symbol = ?1 AND ex_date > ?2
+		ORDER BY ex_date, action_type
+		`,
+		symbol,
+		sqliteDate(since),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []*models.CorporateAction{}
+	for rows.Next() {
+		var a models.CorporateAction
+		var exDate, lastUpdated sqliteTime
+		err := rows.Scan(
+			&a.ActionID,
+			&a.CompanyID,
+			&a.Symbol,
+			&a.ActionType,
+			&exDate,
+			&a.Ratio,
+			&a.Amount,
+			&a.Source,
+			&lastUpdated,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan corporate action row: %w", err)
+		}
+
+		a.ExDate = exDate.Time
+		a.LastUpdated = lastUpdated.Time
+		actions = append(actions, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating corporate action rows: %w", err)
+	}
+	return actions, nil
+}
+
+// MergeIntoPrices copies the stored actions of symbol into the dividend_amount and split_coefficient columns
+// of its stock prices on the ex-dates, for every source. It returns the number of price rows changed.
+func (r *SQLiteCorporateActionRepository) MergeIntoPrices(ctx context.Context, symbol string) (int, error) {
+	// A split and a dividend can share an ex-date, so each column is merged by its own statement
+	statements := []string{
+		`
+		UPDATE stock_prices AS sp
+		SET dividend_amount = ca.amount, last_updated = ?2
+		FROM corporate_actions AS ca
+		WHERE ca.company_id = sp.company_id AND ca.ex_date = sp.date
+		AND ca.action_type = 'DIVIDEND' AND sp.symbol = ?1
+		AND sp.dividend_amount IS NOT ca.amount
+		`,
+		`
+		UPDATE stock_prices AS sp
+		SET split_coefficient = ca.ratio, last_updated = ?2
+		FROM corporate_actions AS ca
+		WHERE ca.company_id = sp.company_id AND ca.ex_date = sp.date
+		AND ca.action_type = 'SPLIT' AND sp.symbol = ?1
+		AND sp.split_coefficient IS NOT ca.ratio
+		`,
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	changed := 0
+	now := sqliteNow()
+	for _, statement := range statements {
+		result, err := tx.ExecContext(ctx, statement, symbol, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to merge corporate actions of %s into prices: %w", symbol, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		changed += int(rows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
@@ -0,0 +1,549 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"sort"
+	"time"
+)
+
+// SQLiteDataFetchJobRepository is the SQLite DataFetchJobStore.
+type SQLiteDataFetchJobRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteDataFetchJobRepository creates a data fetch job repository on a SQLite database.
+func NewSQLiteDataFetchJobRepository(db *sql.DB) *SQLiteDataFetchJobRepository {
+	return &SQLiteDataFetchJobRepository{db: db}
+}
+
+// GetByID retrieves a job by its ID. A NotFoundError is returned if there is no such job.
+func (r *SQLiteDataFetchJobRepository) GetByID(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+dataFetchJobColumns+` FROM data_fetch_jobs WHERE job_id = ?1`, jobID)
+
+	job, err := scanSQLiteDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving job %d: %w", jobID, err)
+	}
+	return job, nil
+}
+
+// FindByEntity returns the job fetching dataType for the given entity from a source, or nil if there is none.
+func (r *SQLiteDataFetchJobRepository) FindByEntity(
+	ctx context.Context,
+	sourceID int,
+	entityType, entityValue, dataType string,
+) (*models.DataFetchJob, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT `+dataFetchJobColumns+` FROM data_fetch_jobs
+		WHERE source_id = ?1 AND entity_type = ?2 AND entity_value = ?3 AND data_type = ?4`,
+		sourceID,
+		entityType,
+		entityValue,
+		dataType,
+	)
+
+	job, err := scanSQLiteDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving %s job for %s: %w", dataType, entityValue, err)
+	}
+	return job, nil
+}
+
+// List returns all jobs ordered by ID. If active is not nil, only jobs with that is_active value are returned.
+func (r *SQLiteDataFetchJobRepository) List(ctx context.Context, active *bool) ([]*models.DataFetchJob, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+dataFetchJobColumns+` FROM data_fetch_jobs
+		WHERE ?1 IS NULL OR COALESCE(is_active, TRUE) = ?1
+		ORDER BY job_id`,
+		active,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	return collectSQLiteDataFetchJobs(rows)
+}
+
+// Create inserts a new job and returns it as stored. A ConflictError is returned if a job for the same
+// source, entity and data type already exists.
+func (r *SQLiteDataFetchJobRepository) Create(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+
+	parametersJSON, err := encodeParameters(job.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.write(ctx, job, false, func(tx *sql.Tx) *sql.Row {
+		return tx.QueryRowContext(
+			ctx,
+			`
+			INSERT INTO data_fetch_jobs
+			(source_id, entity_type, entity_value, data_type, frequency, parameters, next_scheduled,
+			status, is_active, last_updated)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)
+			RETURNING `+dataFetchJobColumns,
+			job.SourceID,
+			job.EntityType,
+			job.EntityValue,
+			job.DataType,
+			job.Frequency,
+			string(parametersJSON),
+			sqliteNullTimestamp(job.NextScheduled),
+			job.Status,
+			job.IsActive,
+			sqliteNow(),
+		)
+	})
+}
+
+// Update stores the definition of an existing job: its source, entity, data type, frequency, parameters,
+// next scheduled run and is_active. The execution history columns are left alone.
+func (r *SQLiteDataFetchJobRepository) Update(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+
+	parametersJSON, err := encodeParameters(job.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.write(ctx, job, true, func(tx *sql.Tx) *sql.Row {
+		return tx.QueryRowContext(
+			ctx,
+			`
+			UPDATE data_fetch_jobs
+			SET source_id = ?2, entity_type = ?3, entity_value = ?4, data_type = ?5, frequency = ?6,
+			parameters = ?7, next_scheduled = ?8, is_active = ?9, last_updated = ?10
+			WHERE job_id = ?1
+			RETURNING `+dataFetchJobColumns,
+			job.JobID,
+			job.SourceID,
+			job.EntityType,
+			job.EntityValue,
+			job.DataType,
+			job.Frequency,
+			string(parametersJSON),
+			sqliteNullTimestamp(job.NextScheduled),
+			job.IsActive,
+			sqliteNow(),
+		)
+	})
+}
+
+// write checks the constraints of data_fetch_jobs and runs the insert or update returned by statement in
+// one transaction. The constraints are checked up front, as the errors of SQLite drivers have no portable
+// codes to translate like jobWriteError does. Updates of unknown jobs return a NotFoundError.
+func (r *SQLiteDataFetchJobRepository) write(
+	ctx context.Context,
+	job *models.DataFetchJob,
+	update bool,
+	statement func(tx *sql.Tx) *sql.Row,
+) (*models.DataFetchJob, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	if update {
+		var jobs int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM data_fetch_jobs WHERE job_id = ?1`, job.JobID).Scan(&jobs); err != nil {
+			return nil, fmt.Errorf("failed to check job %d: %w", job.JobID, err)
+		}
+		if jobs == 0 {
+			return nil, errors.NewNotFoundError("DataFetchJob", job.JobID)
+		}
+	}
+
+	var existingID int
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT job_id FROM data_fetch_jobs
+		WHERE source_id = ?1 AND entity_type = ?2 AND entity_value = ?3 AND data_type = ?4`,
+		job.SourceID,
+		job.EntityType,
+		job.EntityValue,
+		job.DataType,
+	).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check for an existing job: %w", err)
+	}
+	if err == nil && existingID != job.JobID {
+		return nil, jobConflictError(job)
+	}
+
+	var sources int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM data_sources WHERE source_id = ?1`, job.SourceID).Scan(&sources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check data source %d: %w", job.SourceID, err)
+	}
+	if sources == 0 {
+		return nil, jobSourceError(job)
+	}
+
+	written, err := scanSQLiteDataFetchJob(statement(tx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return written, nil
+}
+
+// SetActive pauses (false) or resumes (true) a job without touching its schedule.
+func (r *SQLiteDataFetchJobRepository) SetActive(ctx context.Context, jobID int, active bool) (*models.DataFetchJob, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`UPDATE data_fetch_jobs SET is_active = ?2, last_updated = ?3 WHERE job_id = ?1 RETURNING `+dataFetchJobColumns,
+		jobID,
+		active,
+		sqliteNow(),
+	)
+
+	job, err := scanSQLiteDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job %d: %w", jobID, err)
+	}
+	return job, nil
+}
+
+// ScheduleNow requests a run of the job, so the scheduler picks it up on its next poll. next_scheduled is left
+// alone, the job keeps its schedule. The outcome of the previous run is reset to PENDING, unless the job is
+// running right now.
+func (r *SQLiteDataFetchJobRepository) ScheduleNow(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`
+		UPDATE data_fetch_jobs
+		SET run_requested = ?2, status = CASE WHEN status = ?3 THEN status ELSE ?4 END, last_updated = ?2
+		WHERE job_id = ?1
+		RETURNING `+dataFetchJobColumns,
+		jobID,
+		sqliteNow(),
+		models.JobStatusRunning,
+		models.JobStatusPending,
+	)
+
+	job, err := scanSQLiteDataFetchJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule job %d: %w", jobID, err)
+	}
+	return job, nil
+}
+
+// Delete removes a job together with its execution logs, which reference it.
+func (r *SQLiteDataFetchJobRepository) Delete(ctx context.Context, jobID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM job_execution_logs WHERE job_id = ?1`, jobID); err != nil {
+		return fmt.Errorf("failed to delete logs of job %d: %w", jobID, err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM data_fetch_jobs WHERE job_id = ?1`, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to delete job %d: %w", jobID, err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return errors.NewNotFoundError("DataFetchJob", jobID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueJobs marks up to limit active jobs whose next_scheduled time has passed, or whose run was requested,
+// as RUNNING and returns them, earliest first. SQLite runs one write at a time, so a single UPDATE claims the jobs without the row locks
+// of DataFetchJobRepository. Jobs stuck in RUNNING for longer than staleAfter are claimed again.
+func (r *SQLiteDataFetchJobRepository) ClaimDueJobs(ctx context.Context, limit int, staleAfter time.Duration) ([]*models.DataFetchJob, error) {
+	now := time.Now()
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		UPDATE data_fetch_jobs
+		SET status = ?1, last_execution = ?2, last_updated = ?2
+		WHERE job_id IN (
+			SELECT job_id
+			FROM data_fetch_jobs
+			WHERE is_active
+			AND (next_scheduled <= ?2 OR run_requested IS NOT NULL)
+			AND (status IS NOT ?1 OR last_execution < ?3)
+			ORDER BY MIN(next_scheduled, COALESCE(run_requested, next_scheduled))
+			LIMIT ?4
+		)
+		RETURNING `+dataFetchJobColumns,
+		models.JobStatusRunning,
+		sqliteTimestamp(now),
+		sqliteTimestamp(now.Add(-staleAfter)),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due jobs: %w", err)
+	}
+
+	jobs, err := collectSQLiteDataFetchJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING yields the rows in no particular order
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].DueAt().Before(jobs[j].DueAt()) })
+	return jobs, nil
+}
+
+// FinishJob records the outcome of a claimed job and schedules its next run. A zero nextScheduled time
+// deactivates the job instead, which is how jobs that only run once are retired. The run request the job was
+// claimed for is cleared, one made while it was running is kept.
+func (r *SQLiteDataFetchJobRepository) FinishJob(ctx context.Context, jobID int, status string, nextScheduled time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		UPDATE data_fetch_jobs
+		SET status = ?2,
+		last_success = CASE WHEN ?3 THEN ?6 ELSE last_success END,
+		next_scheduled = CASE WHEN ?5 THEN next_scheduled ELSE ?4 END,
+		run_requested = CASE WHEN run_requested <= last_execution THEN NULL ELSE run_requested END,
+		is_active = CASE WHEN ?5 THEN FALSE ELSE is_active END,
+		last_updated = ?6
+		WHERE job_id = ?1
+		`,
+		jobID,
+		status,
+		status == models.JobStatusSuccess,
+		sqliteNullTimestamp(nextScheduled),
+		nextScheduled.IsZero(),
+		sqliteNow(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish job %d: %w", jobID, err)
+	}
+	return nil
+}
+
+// collectSQLiteDataFetchJobs scans and closes rows of dataFetchJobColumns.
+func collectSQLiteDataFetchJobs(rows *sql.Rows) ([]*models.DataFetchJob, error) {
+	defer rows.Close()
+
+	jobs := []*models.DataFetchJob{}
+	for rows.Next() {
+		job, err := scanSQLiteDataFetchJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// scanSQLiteDataFetchJob scans the dataFetchJobColumns of a single row, mapping NULLs onto zero values.
+func scanSQLiteDataFetchJob(row rowScanner) (*models.DataFetchJob, error) {
+	var job models.DataFetchJob
+	var parametersJSON []byte
+	var lastExecution, lastSuccess, nextScheduled, runRequested, lastUpdated sqliteTime
+	var status sql.NullString
+	var isActive sql.NullBool
+
+	err := row.Scan(
+		&job.JobID,
+		&job.SourceID,
+		&job.EntityType,
+		&job.EntityValue,
+		&job.DataType,
+		&job.Frequency,
+		&parametersJSON,
+		&lastExecution,
+		&lastSuccess,
+		&nextScheduled,
+		&runRequested,
+		&status,
+		&isActive,
+		&lastUpdated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.LastExecution = lastExecution.Time
+	job.LastSuccess = lastSuccess.Time
+	job.NextScheduled = nextScheduled.Time
+	job.RunRequested = runRequested.Time
+	job.Status = status.String
+	job.IsActive = !isActive.Valid || isActive.Bool // Column defaults to TRUE
+	job.LastUpdated = lastUpdated.Time
+
+	job.Parameters = make(map[string]any)
+	if len(parametersJSON) > 0 {
+		if err := json.Unmarshal(parametersJSON, &job.Parameters); err != nil {
+			return nil, fmt.Errorf("error parsing parameters of job %d: %w", job.JobID, err)
+		}
+	}
+	return &job, nil
+}
+
+// SQLiteJobExecutionLogRepository is the SQLite JobExecutionLogStore.
+type SQLiteJobExecutionLogRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteJobExecutionLogRepository creates a job execution log repository on a SQLite database.
+func NewSQLiteJobExecutionLogRepository(db *sql.DB) *SQLiteJobExecutionLogRepository {
+	return &SQLiteJobExecutionLogRepository{db: db}
+}
+
+// Start inserts the log of a job that just started and sets its LogID. A JobID of 0 is stored as NULL.
+func (r *SQLiteJobExecutionLogRepository) Start(ctx context.Context, entry *models.JobExecutionLog) error {
+	detailsJSON, err := encodeDetails(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO job_execution_logs
+		(job_id, job_type, start_time, status, records_processed, details, last_updated)
+		VALUES (NULLIF(?1, 0), ?2, ?3, ?4, 0, ?5, ?6)
+		RETURNING log_id
+		`,
+		entry.JobID,
+		entry.JobType,
+		sqliteTimestamp(entry.StartTime),
+		entry.Status,
+		string(detailsJSON),
+		sqliteNow(),
+	).Scan(&entry.LogID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create job execution log: %w", err)
+	}
+	return nil
+}
+
+// Update stores the end time, status, record count, error message and details of a started log.
+// A zero EndTime is stored as NULL, which lets running jobs report their progress.
+func (r *SQLiteJobExecutionLogRepository) Update(ctx context.Context, entry *models.JobExecutionLog) error {
+	detailsJSON, err := encodeDetails(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		`
+		UPDATE job_execution_logs
+		SET end_time = ?2, status = ?3, records_processed = ?4, error_message = NULLIF(?5, ''),
+		details = ?6, last_updated = ?7
+		WHERE log_id = ?1
+		`,
+		entry.LogID,
+		sqliteNullTimestamp(entry.EndTime),
+		entry.Status,
+		entry.RecordsProcessed,
+		entry.ErrorMessage,
+		string(detailsJSON),
+		sqliteNow(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update job execution log %d: %w", entry.LogID, err)
+	}
+	return nil
+}
+
+// ListByJob returns the most recent logs of a data fetch job, newest first.
+func (r *SQLiteJobExecutionLogRepository) ListByJob(ctx context.Context, jobID, limit int) ([]*models.JobExecutionLog, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT log_id, job_id, job_type, start_time, end_time, status, records_processed,
+		       error_message, details, last_updated
+		FROM job_execution_logs
+		WHERE job_id = ?1
+		ORDER BY start_time DESC, log_id DESC
+		LIMIT ?2
+		`,
+		jobID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs of job %d: %w", jobID, err)
+	}
+	defer rows.Close()
+
+	logs := []*models.JobExecutionLog{}
+	for rows.Next() {
+		var entry models.JobExecutionLog
+		var id, recordsProcessed sql.NullInt64
+		var startTime, endTime, lastUpdated sqliteTime
+		var errorMessage sql.NullString
+		var detailsJSON []byte
+
+		err := rows.Scan(
+			&entry.LogID,
+			&id,
+			&entry.JobType,
+			&startTime,
+			&endTime,
+			&entry.Status,
+			&recordsProcessed,
+			&errorMessage,
+			&detailsJSON,
+			&lastUpdated,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job execution log: %w", err)
+		}
+
+		entry.JobID = int(id.Int64)
+		entry.StartTime = startTime.Time
+		entry.EndTime = endTime.Time
+		entry.RecordsProcessed = int(recordsProcessed.Int64)
+		entry.ErrorMessage = errorMessage.String
+		entry.LastUpdated = lastUpdated.Time
+
+		entry.Details = make(map[string]any)
+		if len(detailsJSON) > 0 {
+			if err := json.Unmarshal(detailsJSON, &entry.Details); err != nil {
+				return nil, fmt.Errorf("error parsing details of log %d: %w", entry.LogID, err)
+			}
+		}
+		logs = append(logs, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job execution logs: %w", err)
+	}
+	return logs, nil
+}
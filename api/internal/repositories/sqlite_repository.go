@@ -0,0 +1,897 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"time"
+)
+
+// The SQLite repositories implement the store interfaces on a SQLite database migrated with the sqlite
+// dialect of the migrations package. They only use database/sql, so any SQLite driver works.
+//
+// SQLite has no date and timestamp types. Dates are stored as 'YYYY-MM-DD' text and timestamps as UTC text
+// with a fixed number of fractional digits, so that comparing the text compares the times. Parameters are
+// numbered (?1, ?2, ...), which SQLite binds by position however often they appear in a statement.
+//
+// SQLite allows a single writer at a time, and the database is normally opened with a single connection.
+// While a repository holds a transaction open, it must run every statement on it. Anything needing the
+// *sql.DB, like resolving data sources, happens before the transaction begins.
+
+const (
+	sqliteDateLayout      = "2006-01-02"
+	sqliteTimestampLayout = "2006-01-02 15:04:05.000000"
+)
+
+// sqliteDate converts t to the text of a date column.
+func sqliteDate(t time.Time) string {
+	return t.Format(sqliteDateLayout)
+}
+
+// sqliteTimestamp converts t to the text of a timestamp column.
+func sqliteTimestamp(t time.Time) string {
+	return t.UTC().Format(sqliteTimestampLayout)
+}
+
+// sqliteNullTimestamp converts t to the text of a timestamp column, or NULL for the zero time.
+func sqliteNullTimestamp(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: sqliteTimestamp(t), Valid: true}
+}
+
+// sqliteNow returns the current time as the text of a timestamp column.
+func sqliteNow() string {
+	return sqliteTimestamp(time.Now())
+}
+
+// sqliteTime scans a date or timestamp column. NULL scans as the zero time.
+type sqliteTime struct {
+	time.Time
+}
+
+// Scan implements sql.Scanner. Besides the stored text it accepts a time.Time, which some drivers return
+// for columns declared as DATE or TIMESTAMP.
+func (t *sqliteTime) Scan(value any) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v.UTC()
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a time", value)
+	}
+
+	layout := "2006-01-02 15:04:05.999999999" // Also reads CURRENT_TIMESTAMP defaults without fractions
+	if len(text) == len(sqliteDateLayout) {
+		layout = sqliteDateLayout
+	}
+	parsed, err := time.Parse(layout, text)
+	if err != nil {
+		return fmt.Errorf("invalid date or timestamp %q: %w", text, err)
+	}
+	t.Time = parsed
+	return nil
+}
+
+// sqliteJSONList encodes values as the JSON array that SQLite's json_each expands, which stands in for
+// PostgreSQL's ANY($1) with an array parameter.
+func sqliteJSONList(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("error encoding list: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// sqliteCompanyID returns the company_id of symbol, creating a placeholder company named after the symbol
+// if it doesn't exist yet.
+func sqliteCompanyID(ctx context.Context, tx *sql.Tx, symbol string) (int, error) {
+	_, err := tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO companies (symbol, name, is_active, last_updated)
+		VALUES (?1, ?1, TRUE, ?2)
+		ON CONFLICT (symbol) DO NOTHING
+		`,
+		symbol,
+		sqliteNow(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create company for symbol %s: %w", symbol, err)
+	}
+
+	var companyID int
+	err = tx.QueryRowContext(ctx, `SELECT company_id FROM companies WHERE symbol = ?1`, symbol).Scan(&companyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query company for symbol %s: %w", symbol, err)
+	}
+	return companyID, nil
+}
+
+// SQLiteDataSourceRepository is the SQLite DataSourceStore.
+type SQLiteDataSourceRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteDataSourceRepository creates a data source repository on a SQLite database.
+func NewSQLiteDataSourceRepository(db *sql.DB) *SQLiteDataSourceRepository {
+	return &SQLiteDataSourceRepository{db: db}
+}
+
+// GetByName retrieves a data source by its name. A NotFoundError is returned if no data source has that name.
+func (r *SQLiteDataSourceRepository) GetByName(ctx context.Context, name string) (*models.DataSource, error) {
+	var ds models.DataSource
+	var configJSON []byte
+	var baseURL sql.NullString
+	var perMinute, perDay sql.NullInt64
+	var isActive sql.NullBool
+	var createdAt, lastUpdated sqliteTime
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		SELECT source_id, source_name, source_type, base_url, rate_limit_per_minute,
+		       rate_limit_per_day, config_parameters, is_active, created_at, last_updated
+		FROM data_sources
+		WHERE source_name = ?1
+		`,
+		name,
+	).Scan(
+		&ds.SourceID,
+		&ds.SourceName,
+		&ds.SourceType,
+		&baseURL,
+		&perMinute,
+		&perDay,
+		&configJSON,
+		&isActive,
+		&createdAt,
+		&lastUpdated,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("DataSource", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving data source: %w", err)
+	}
+
+	ds.BaseURL = baseURL.String
+	ds.RateLimitPerMinute = int(perMinute.Int64)
+	ds.RateLimitPerDay = int(perDay.Int64)
+	ds.IsActive = !isActive.Valid || isActive.Bool // Column defaults to TRUE
+	ds.CreatedAt = createdAt.Time
+	ds.LastUpdated = lastUpdated.Time
+
+	ds.ConfigParameters = make(map[string]any)
+	if len(configJSON) > 0 {
+		if err := json.Unmarshal(configJSON, &ds.ConfigParameters); err != nil {
+			return nil, fmt.Errorf("error parsing config parameters: %w", err)
+		}
+	}
+	return &ds, nil
+}
+
+// Create registers a new data source and returns it with its generated ID. If a data source with the
+// same name already exists, the existing row is returned unchanged.
+func (r *SQLiteDataSourceRepository) Create(ctx context.Context, ds *models.DataSource) (*models.DataSource, error) {
+	if err := ds.Validate(); err != nil {
+		return nil, err
+	}
+
+	configJSON := []byte("{}")
+	if len(ds.ConfigParameters) > 0 {
+		encoded, err := json.Marshal(ds.ConfigParameters)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding config parameters: %w", err)
+		}
+		configJSON = encoded
+	}
+
+	// Zero rate limits are stored as NULL, meaning unlimited
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO data_sources
+		(source_name, source_type, base_url, rate_limit_per_minute, rate_limit_per_day,
+		config_parameters, is_active, created_at, last_updated)
+		VALUES (?1, ?2, NULLIF(?3, ''), NULLIF(?4, 0), NULLIF(?5, 0), ?6, ?7, ?8, ?8)
+		ON CONFLICT (source_name) DO NOTHING
+		`,
+		ds.SourceName,
+		ds.SourceType,
+		ds.BaseURL,
+		ds.RateLimitPerMinute,
+		ds.RateLimitPerDay,
+		string(configJSON),
+		ds.IsActive,
+		sqliteNow(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data source %s: %w", ds.SourceName, err)
+	}
+
+	return r.GetByName(ctx, ds.SourceName)
+}
+
+// SQLiteCompanyRepository is the SQLite CompanyStore.
+type SQLiteCompanyRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCompanyRepository creates a company repository on a SQLite database.
+func NewSQLiteCompanyRepository(db *sql.DB) *SQLiteCompanyRepository {
+	return &SQLiteCompanyRepository{db: db}
+}
+
+// ListActiveSymbols returns the symbols of all active companies in alphabetical order.
+func (r *SQLiteCompanyRepository) ListActiveSymbols(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT symbol FROM companies WHERE COALESCE(is_active, TRUE) ORDER BY symbol`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active companies: %w", err)
+	}
+	defer rows.Close()
+
+	symbols := []string{}
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, fmt.Errorf("failed to scan company symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating companies: %w", err)
+	}
+	return symbols, nil
+}
+
+// GetExchange returns the exchange a company is listed on. It is empty for unknown symbols and
+// companies without an exchange.
+func (r *SQLiteCompanyRepository) GetExchange(ctx context.Context, symbol string) (string, error) {
+	var exchange sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT exchange FROM companies WHERE symbol = ?1`, symbol).Scan(&exchange)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query exchange of %s: %w", symbol, err)
+	}
+	return exchange.String, nil
+}
+
+// GetBySymbol retrieves a company. A NotFoundError is returned if there is no such company.
+func (r *SQLiteCompanyRepository) GetBySymbol(ctx context.Context, symbol string) (*models.Company, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+companyColumns+` FROM companies WHERE symbol = ?1`, symbol)
+
+	company, err := scanSQLiteCompany(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("Company", symbol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving company %s: %w", symbol, err)
+	}
+	return company, nil
+}
+
+// ListPlaceholders returns up to limit active companies that never received a profile, the ones checked longest
+// ago first. SQLite sorts NULLs first.
+func (r *SQLiteCompanyRepository) ListPlaceholders(ctx context.Context, limit int) ([]*models.Company, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+companyColumns+` FROM companies
+		WHERE profile_updated IS NULL AND COALESCE(is_active, TRUE)
+		ORDER BY profile_checked, symbol
+		LIMIT ?1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query placeholder companies: %w", err)
+	}
+	defer rows.Close()
+
+	companies := []*models.Company{}
+	for rows.Next() {
+		company, err := scanSQLiteCompany(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan company: %w", err)
+		}
+		companies = append(companies, company)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating companies: %w", err)
+	}
+	return companies, nil
+}
+
+// MarkProfileChecked records that the profile of a company was requested now.
+func (r *SQLiteCompanyRepository) MarkProfileChecked(ctx context.Context, symbol string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE companies SET profile_checked = ?2 WHERE symbol = ?1`, symbol, sqliteNow())
+	if err != nil {
+		return fmt.Errorf("failed to mark the profile of %s as checked: %w", symbol, err)
+	}
+	return nil
+}
+
+// Create inserts a new company and returns it as stored. A ConflictError is returned if the symbol exists.
+func (r *SQLiteCompanyRepository) Create(ctx context.Context, company *models.Company) (*models.Company, error) {
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO companies
+		(symbol, name, sector, industry, exchange, is_active, profile_source, profile_updated, last_updated)
+		VALUES (?1, ?2, NULLIF(?3, ''), NULLIF(?4, ''), NULLIF(?5, ''), ?6, NULLIF(?7, ''), ?8, ?9)
+		ON CONFLICT (symbol) DO NOTHING
+		RETURNING `+companyColumns,
+		company.Symbol,
+		company.Name,
+		company.Sector,
+		company.Industry,
+		company.Exchange,
+		company.IsActive,
+		company.ProfileSource,
+		sqliteNullTimestamp(company.ProfileUpdated),
+		sqliteNow(),
+	)
+
+	// No row means the symbol was taken
+	created, err := scanSQLiteCompany(row)
+	if err == sql.ErrNoRows {
+		return nil, companyConflictError(company.Symbol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create company %s: %w", company.Symbol, err)
+	}
+	return created, nil
+}
+
+// Update stores the name, sector, industry, exchange, is_active and profile of an existing company.
+func (r *SQLiteCompanyRepository) Update(ctx context.Context, company *models.Company) (*models.Company, error) {
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`
+		UPDATE companies
+		SET name = ?2, sector = NULLIF(?3, ''), industry = NULLIF(?4, ''), exchange = NULLIF(?5, ''),
+		is_active = ?6, profile_source = NULLIF(?7, ''), profile_updated = ?8, last_updated = ?9
+		WHERE symbol = ?1
+		RETURNING `+companyColumns,
+		company.Symbol,
+		company.Name,
+		company.Sector,
+		company.Industry,
+		company.Exchange,
+		company.IsActive,
+		company.ProfileSource,
+		sqliteNullTimestamp(company.ProfileUpdated),
+		sqliteNow(),
+	)
+
+	updated, err := scanSQLiteCompany(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("Company", company.Symbol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update company %s: %w", company.Symbol, err)
+	}
+	return updated, nil
+}
+
+// Delete removes a company. A ConflictError is returned while stock prices or corporate actions reference it,
+// which is checked up front like the constraints of SQLiteDataFetchJobRepository.
+func (r *SQLiteCompanyRepository) Delete(ctx context.Context, symbol string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	var inUse bool
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		SELECT EXISTS (SELECT 1 FROM stock_prices WHERE symbol = ?1)
+		OR EXISTS (SELECT 1 FROM corporate_actions WHERE symbol = ?1)
+		`,
+		symbol,
+	).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("failed to check references to company %s: %w", symbol, err)
+	}
+	if inUse {
+		return companyInUseError(symbol)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM companies WHERE symbol = ?1`, symbol)
+	if err != nil {
+		return fmt.Errorf("failed to delete company %s: %w", symbol, err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return errors.NewNotFoundError("Company", symbol)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// scanSQLiteCompany scans the companyColumns of a single row, mapping NULLs onto zero values.
+func scanSQLiteCompany(row rowScanner) (*models.Company, error) {
+	var company models.Company
+	var sector, industry, exchange, profileSource sql.NullString
+	var isActive sql.NullBool
+	var profileUpdated, profileChecked, lastUpdated sqliteTime
+
+	err := row.Scan(
+		&company.CompanyID,
+		&company.Symbol,
+		&company.Name,
+		&sector,
+		&industry,
+		&exchange,
+		&isActive,
+		&profileSource,
+		&profileUpdated,
+		&profileChecked,
+		&lastUpdated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	company.Sector = sector.String
+	company.Industry = industry.String
+	company.Exchange = exchange.String
+	company.IsActive = !isActive.Valid || isActive.Bool // Column defaults to TRUE
+	company.ProfileSource = profileSource.String
+	company.ProfileUpdated = profileUpdated.Time
+	company.ProfileChecked = profileChecked.Time
+	company.LastUpdated = lastUpdated.Time
+	return &company, nil
+}
+
+// SQLiteStockRepository is the SQLite StockStore.
+type SQLiteStockRepository struct {
+	db             *sql.DB
+	dataSourceRepo DataSourceStore
+
+	sourceIDs *sourceIDCache
+}
+
+// NewSQLiteStockRepository creates a stock repository on a SQLite database. Data sources are resolved
+// through dataSourceRepo, normally a SQLiteDataSourceRepository of the same database.
+func NewSQLiteStockRepository(db *sql.DB, dataSourceRepo DataSourceStore) *SQLiteStockRepository {
+	return &SQLiteStockRepository{
+		db:             db,
+		dataSourceRepo: dataSourceRepo,
+		sourceIDs:      newSourceIDCache(),
+	}
+}
+
+// resolveSourceID returns the source_id for the named data source, caching the IDs of active sources.
+func (r *SQLiteStockRepository) resolveSourceID(ctx context.Context, sourceName string) (int, error) {
+	return r.sourceIDs.resolve(ctx, r.dataSourceRepo, sourceName)
+}
+
+// SaveStocksToDatabase upserts the stock prices in a single transaction, with the same ON CONFLICT statement
+// as StockRepository. SQLite has no xmax to tell inserts from updates, so every row is looked up first.
+// Data sources are resolved before the transaction begins, as the data source repository needs a connection
+// of its own.
+func (r *SQLiteStockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*models.Stock) (*SaveResult, error) {
+	sourceIDs := make([]int, len(stocks))
+	for i, stock := range stocks {
+		sourceID, err := r.resolveSourceID(ctx, stock.DataSource)
+		if err != nil {
+			return nil, err
+		}
+		sourceIDs[i] = sourceID
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	companyIDs := make(map[string]int)
+	for _, stock := range stocks {
+		companyID, ok := companyIDs[stock.Symbol]
+		if !ok {
+			if companyID, err = sqliteCompanyID(ctx, tx, stock.Symbol); err != nil {
+				return nil, err
+			}
+			companyIDs[stock.Symbol] = companyID
+		}
+		stock.CompanyID = companyID
+
+		if err := stock.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	existsStmt, err := tx.PrepareContext(
+		ctx,
+		`SELECT COUNT(*) FROM stock_prices WHERE company_id = ?1 AND date = ?2 AND source_id = ?3`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare stock price lookup: %w", err)
+	}
+	defer existsStmt.Close()
+
+	// The WHERE clause skips rows whose values did not change, in which case nothing is returned
+	upsertStmt, err := tx.PrepareContext(
+		ctx,
+		`
+		INSERT INTO stock_prices
+		(company_id, symbol, date, open_price, high_price, low_price,
+		close_price, adjusted_close, volume, dividend_amount,
+		split_coefficient, source_id, last_updated)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13)
+		ON CONFLICT (company_id, date, source_id)
+		DO UPDATE SET
+		open_price = excluded.open_price,
+		high_price = excluded.high_price,
+		low_price = excluded.low_price,
+		close_price = excluded.close_price,
+		adjusted_close = excluded.adjusted_close,
+		volume = excluded.volume,
+		dividend_amount = excluded.dividend_amount,
+		split_coefficient = excluded.split_coefficient,
+		last_updated = excluded.last_updated
+		WHERE (stock_prices.open_price, stock_prices.high_price, stock_prices.low_price,
+		stock_prices.close_price, stock_prices.adjusted_close, stock_prices.volume,
+		stock_prices.dividend_amount, stock_prices.split_coefficient)
+		IS NOT
+		(excluded.open_price, excluded.high_price, excluded.low_price,
+		excluded.close_price, excluded.adjusted_close, excluded.volume,
+		excluded.dividend_amount, excluded.split_coefficient)
+		RETURNING price_id
+		`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare stock price upsert: %w", err)
+	}
+	defer upsertStmt.Close()
+
+	result := &SaveResult{}
+	now := sqliteNow()
+	for i, stock := range stocks {
+		date := sqliteDate(stock.Date)
+
+		var existing int
+		if err := existsStmt.QueryRowContext(ctx, stock.CompanyID, date, sourceIDs[i]).Scan(&existing); err != nil {
+			return nil, fmt.Errorf("failed to look up stock price for %s on %s: %w", stock.Symbol, date, err)
+		}
+
+		// REAL columns are rounded like the NUMERIC columns of PostgreSQL, so unchanged values compare equal
+		var priceID int
+		err = upsertStmt.QueryRowContext(
+			ctx,
+			stock.CompanyID,
+			stock.Symbol,
+			date,
+			roundNumeric(stock.OpenPrice, 5),
+			roundNumeric(stock.HighPrice, 5),
+			roundNumeric(stock.LowPrice, 5),
+			roundNumeric(stock.ClosePrice, 5),
+			roundNumeric(stock.AdjustedClose, 5),
+			int64(math.Round(stock.Volume)),
+			roundNumeric(stock.DividendAmount, 5),
+			roundNumeric(stock.SplitCoefficient, 5),
+			sourceIDs[i],
+			now,
+		).Scan(&priceID)
+
+		// No row means the stored values already matched, so the upsert was skipped
+		if err == sql.ErrNoRows {
+			result.Unchanged++
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert stock price for %s on %s: %w", stock.Symbol, date, err)
+		}
+
+		stock.PriceID = priceID
+		if existing == 0 {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// RetrieveStocksFromDatabase returns the stock prices of symbol between startDate and endDate, newest first.
+func (r *SQLiteStockRepository) RetrieveStocksFromDatabase(
+	ctx context.Context,
+	symbol string,
+	startDate, endDate time.Time,
+) ([]*models.Stock, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT sp.price_id, sp.company_id, sp.symbol, sp.date,
+		       sp.open_price, sp.high_price, sp.low_price, sp.close_price,
+		       sp.adjusted_close, sp.volume, sp.dividend_amount,
+		       sp.split_coefficient, ds.source_name, sp.last_updated
+		FROM stock_prices sp
+		JOIN data_sources ds ON sp.source_id = ds.source_id
+		WHERE sp.symbol = ?1 AND sp.date BETWEEN ?2 AND ?3
+		ORDER BY sp.date DESC, ds.source_name
+		`,
+		symbol,
+		sqliteDate(startDate),
+		sqliteDate(endDate),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock prices: %w", err)
+	}
+	defer rows.Close()
+
+	var stocks []*models.Stock
+	for rows.Next() {
+		var s models.Stock
+		var date, lastUpdated sqliteTime
+		err := rows.Scan(
+			&s.PriceID,
+			&s.CompanyID,
+			&s.Symbol,
+			&date,
+			&s.OpenPrice,
+			&s.HighPrice,
+			&s.LowPrice,
+			&s.ClosePrice,
+			&s.AdjustedClose,
+			&s.Volume,
+			&s.DividendAmount,
+			&s.SplitCoefficient,
+			&s.DataSource,
+			&lastUpdated,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stock price row: %w", err)
+		}
+
+		s.Date = date.Time
+		s.LastUpdated = lastUpdated.Time
+		stocks = append(stocks, &s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock price rows: %w", err)
+	}
+	return stocks, nil
+}
+
+// GetLatestStockDate returns the most recent stored trading date for symbol from any of the named data sources.
+// The zero time is returned when nothing has been stored yet.
+func (r *SQLiteStockRepository) GetLatestStockDate(ctx context.Context, symbol string, sourceNames []string) (time.Time, error) {
+	names, err := sqliteJSONList(sourceNames)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// MAX returns NULL when there are no rows, which scans as the zero time
+	var latest sqliteTime
+	err = r.db.QueryRowContext(
+		ctx,
+		`
+		SELECT MAX(sp.date)
+		FROM stock_prices sp
+		JOIN data_sources ds ON sp.source_id = ds.source_id
+		WHERE sp.symbol = ?1 AND ds.source_name IN (SELECT value FROM json_each(?2))
+		`,
+		symbol,
+		names,
+	).Scan(&latest)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query latest stock date for %s: %w", symbol, err)
+	}
+	return latest.Time, nil
+}
+
+// GetStoredDates returns the dates stored for symbol between startDate and endDate, grouped by data source
+// name. The dates of each source are in ascending order.
+func (r *SQLiteStockRepository) GetStoredDates(
+	ctx context.Context,
+	symbol string,
+	startDate, endDate time.Time,
+) (map[string][]time.Time, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT ds.source_name, sp.date
+		FROM stock_prices sp
+		JOIN data_sources ds ON sp.source_id = ds.source_id
+		WHERE sp.symbol = ?1 AND sp.date BETWEEN ?2 AND ?3
+		ORDER BY ds.source_name, sp.date
+		`,
+		symbol,
+		sqliteDate(startDate),
+		sqliteDate(endDate),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored dates for %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	dates := make(map[string][]time.Time)
+	for rows.Next() {
+		var source string
+		var date sqliteTime
+		if err := rows.Scan(&source, &date); err != nil {
+			return nil, fmt.Errorf("failed to scan stored date: %w", err)
+		}
+		dates[source] = append(dates[source], date.Time)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stored dates: %w", err)
+	}
+	return dates, nil
+}
+
+// GetStoredDateRanges returns the first and last date stored for symbol, across its whole history, grouped by
+// data source name.
+func (r *SQLiteStockRepository) GetStoredDateRanges(ctx context.Context, symbol string) (map[string]StoredRange, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT ds.source_name, MIN(sp.date), MAX(sp.date)
+		FROM stock_prices sp
+		JOIN data_sources ds ON sp.source_id = ds.source_id
+		WHERE sp.symbol = ?1
+		GROUP BY ds.source_name
+		`,
+		symbol,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored date ranges for %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	ranges := make(map[string]StoredRange)
+	for rows.Next() {
+		var source string
+		var first, last sqliteTime
+		if err := rows.Scan(&source, &first, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan stored date range: %w", err)
+		}
+		ranges[source] = StoredRange{First: first.Time, Last: last.Time}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stored date ranges: %w", err)
+	}
+	return ranges, nil
+}
+
+// UpdateAdjustedCloses stores the AdjustedClose of each stock by its PriceID in a single transaction.
+// Rows already holding that value are left alone. It returns the number of rows changed.
+func (r *SQLiteStockRepository) UpdateAdjustedCloses(ctx context.Context, stocks []*models.Stock) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`
+		UPDATE stock_prices
+		SET adjusted_close = ?2, last_updated = ?3
+		WHERE price_id = ?1 AND adjusted_close IS NOT ?2
+		`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare adjusted close update: %w", err)
+	}
+	defer stmt.Close()
+
+	changed := 0
+	now := sqliteNow()
+	for _, stock := range stocks {
+		result, err := stmt.ExecContext(ctx, stock.PriceID, roundNumeric(stock.AdjustedClose, 5), now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update adjusted close of %s on %s: %w",
+				stock.Symbol, stock.Date.Format("2006-01-02"), err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		changed += int(rows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+// SQLiteProviderQuotaRepository persists daily request counts per data source in a SQLite database.
+// It implements clients.QuotaStore.
+type SQLiteProviderQuotaRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteProviderQuotaRepository creates a provider quota repository on a SQLite database.
+func NewSQLiteProviderQuotaRepository(db *sql.DB) *SQLiteProviderQuotaRepository {
+	return &SQLiteProviderQuotaRepository{db: db}
+}
+
+// GetUsage returns how many requests were made to the provider on the given day.
+func (r *SQLiteProviderQuotaRepository) GetUsage(ctx context.Context, provider string, day time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT request_count FROM provider_quota_usage WHERE source_name = ?1 AND usage_date = ?2`,
+		provider,
+		sqliteDate(day),
+	).Scan(&count)
+
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query quota usage for %s: %w", provider, err)
+	}
+	return count, nil
+}
+
+// IncrementUsage atomically records one more request to the provider on the given day and returns the new total.
+func (r *SQLiteProviderQuotaRepository) IncrementUsage(ctx context.Context, provider string, day time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO provider_quota_usage (source_name, usage_date, request_count, last_updated)
+		VALUES (?1, ?2, 1, ?3)
+		ON CONFLICT (source_name, usage_date)
+		DO UPDATE SET
+		request_count = provider_quota_usage.request_count + 1,
+		last_updated = excluded.last_updated
+		RETURNING request_count
+		`,
+		provider,
+		sqliteDate(day),
+		sqliteNow(),
+	).Scan(&count)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to record quota usage for %s: %w", provider, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,251 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// stockPriceKey identifies a row of stock_prices by its unique constraint.
+type stockPriceKey struct {
+	companyID int
+	date      string
+	sourceID  int
+}
+
+// bulkUpsertStocks stores the stock prices with a constant number of statements: the companies are resolved
+// once per symbol, the rows are streamed into a temporary staging table with COPY and merged into stock_prices
+// with a single INSERT ... ON CONFLICT. It has the same semantics as upsertStocksRowByRow, including the
+// auto-created companies, the skipped unchanged rows and the PriceIDs set on the stocks. If the same day of a
+// source occurs more than once, the last occurrence wins and the earlier ones are reported as superseded.
+func (sr *StockRepository) bulkUpsertStocks(ctx context.Context, stocks []*models.Stock) (*SaveResult, error) {
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	companyIDs, err := resolveCompanyIDs(ctx, tx, stocks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Key every row before loading it, a key may only occur once in the merge
+	latest := make(map[stockPriceKey]int, len(stocks))
+	keys := make([]stockPriceKey, len(stocks))
+	for i, stock := range stocks {
+		sourceID, err := sr.resolveSourceID(ctx, stock.DataSource)
+		if err != nil {
+			return nil, err
+		}
+
+		stock.CompanyID = companyIDs[stock.Symbol]
+		if err := stock.Validate(); err != nil {
+			return nil, err
+		}
+
+		keys[i] = stockPriceKey{companyID: stock.CompanyID, date: stock.Date.Format("2006-01-02"), sourceID: sourceID}
+		latest[keys[i]] = i
+	}
+
+	// The staging table only lives until the transaction ends
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		CREATE TEMP TABLE stock_prices_staging (
+			company_id INTEGER,
+			symbol VARCHAR(20),
+			date DATE,
+			open_price NUMERIC,
+			high_price NUMERIC,
+			low_price NUMERIC,
+			close_price NUMERIC,
+			adjusted_close NUMERIC,
+			volume NUMERIC,
+			dividend_amount NUMERIC,
+			split_coefficient NUMERIC,
+			source_id INTEGER
+		) ON COMMIT DROP
+		`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stock price staging table: %w", err)
+	}
+
+	if err := copyStocks(ctx, tx, stocks, keys, latest); err != nil {
+		return nil, err
+	}
+
+	// Same upsert as the row by row path, only fed from the staging table. Volume is staged as NUMERIC
+	// and rounded here, like a parameter bound to the BIGINT column would be.
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+		INSERT INTO stock_prices
+		(company_id, symbol, date, open_price, high_price, low_price,
+		close_price, adjusted_close, volume, dividend_amount,
+		split_coefficient, source_id, last_updated)
+		SELECT company_id, symbol, date, open_price, high_price, low_price,
+		close_price, adjusted_close, ROUND(volume)::BIGINT, dividend_amount,
+		split_coefficient, source_id, NOW()
+		FROM stock_prices_staging
+		ON CONFLICT (company_id, date, source_id)
+		DO UPDATE SET
+		open_price = EXCLUDED.open_price,
+		high_price = EXCLUDED.high_price,
+		low_price = EXCLUDED.low_price,
+		close_price = EXCLUDED.close_price,
+		adjusted_close = EXCLUDED.adjusted_close,
+		volume = EXCLUDED.volume,
+		dividend_amount = EXCLUDED.dividend_amount,
+		split_coefficient = EXCLUDED.split_coefficient,
+		last_updated = EXCLUDED.last_updated
+		WHERE (stock_prices.open_price, stock_prices.high_price, stock_prices.low_price,
+		stock_prices.close_price, stock_prices.adjusted_close, stock_prices.volume,
+		stock_prices.dividend_amount, stock_prices.split_coefficient)
+		IS DISTINCT FROM
+		(EXCLUDED.open_price, EXCLUDED.high_price, EXCLUDED.low_price,
+		EXCLUDED.close_price, EXCLUDED.adjusted_close, EXCLUDED.volume,
+		EXCLUDED.dividend_amount, EXCLUDED.split_coefficient)
+		RETURNING price_id, company_id, date, source_id, (xmax = 0) AS inserted
+		`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge staged stock prices: %w", err)
+	}
+	defer rows.Close()
+
+	// Rows missing from the result were skipped because their values did not change
+	result := &SaveResult{}
+	for rows.Next() {
+		var priceID int
+		var key stockPriceKey
+		var date time.Time
+		var inserted bool
+		if err := rows.Scan(&priceID, &key.companyID, &date, &key.sourceID, &inserted); err != nil {
+			return nil, fmt.Errorf("failed to scan merged stock price: %w", err)
+		}
+		key.date = date.Format("2006-01-02")
+
+		if index, ok := latest[key]; ok {
+			stocks[index].PriceID = priceID
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating merged stock prices: %w", err)
+	}
+	result.Superseded = len(stocks) - len(latest)
+	result.Unchanged = len(latest) - result.Inserted - result.Updated
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// resolveCompanyIDs returns the company_id of every symbol in stocks, keyed by symbol. Missing companies are
+// created first, all with a single statement, so the cost doesn't grow with the number of rows.
+func resolveCompanyIDs(ctx context.Context, tx *sql.Tx, stocks []*models.Stock) (map[string]int, error) {
+	symbols := []string{}
+	seen := make(map[string]bool)
+	for _, stock := range stocks {
+		if !seen[stock.Symbol] {
+			seen[stock.Symbol] = true
+			symbols = append(symbols, stock.Symbol)
+		}
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO companies (symbol, name, is_active, last_updated)
+		SELECT symbol, symbol, true, NOW() FROM unnest($1::TEXT[]) AS symbol
+		ON CONFLICT (symbol) DO NOTHING
+		`,
+		pq.Array(symbols),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create companies: %w", err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT symbol, company_id FROM companies WHERE symbol = ANY($1)`,
+		pq.Array(symbols),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query companies: %w", err)
+	}
+	defer rows.Close()
+
+	companyIDs := make(map[string]int, len(symbols))
+	for rows.Next() {
+		var symbol string
+		var companyID int
+		if err := rows.Scan(&symbol, &companyID); err != nil {
+			return nil, fmt.Errorf("failed to scan company: %w", err)
+		}
+		companyIDs[symbol] = companyID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating companies: %w", err)
+	}
+	return companyIDs, nil
+}
+
+// copyStocks streams the last occurrence of every key into the staging table with COPY.
+func copyStocks(
+	ctx context.Context,
+	tx *sql.Tx,
+	stocks []*models.Stock,
+	keys []stockPriceKey,
+	latest map[stockPriceKey]int,
+) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("stock_prices_staging",
+		"company_id", "symbol", "date", "open_price", "high_price", "low_price", "close_price",
+		"adjusted_close", "volume", "dividend_amount", "split_coefficient", "source_id"))
+	if err != nil {
+		return fmt.Errorf("failed to start copying stock prices: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, stock := range stocks {
+		if latest[keys[i]] != i {
+			continue // Superseded by a later row for the same day and source
+		}
+
+		_, err := stmt.ExecContext(
+			ctx,
+			stock.CompanyID,
+			stock.Symbol,
+			keys[i].date,
+			stock.OpenPrice,
+			stock.HighPrice,
+			stock.LowPrice,
+			stock.ClosePrice,
+			stock.AdjustedClose,
+			stock.Volume,
+			stock.DividendAmount,
+			stock.SplitCoefficient,
+			keys[i].sourceID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to copy stock price for %s on %s: %w", stock.Symbol, keys[i].date, err)
+		}
+	}
+
+	// Flush the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to copy stock prices: %w", err)
+	}
+	return nil
+}
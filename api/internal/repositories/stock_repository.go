@@ -5,28 +5,150 @@ import (
 	"database/sql"
 	"fmt"
 	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"sync"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // StockRepository handles database operations for stocks
 type StockRepository struct {
-	db *sql.DB
+	db             *sql.DB
+	dataSourceRepo DataSourceStore
+
+	sourceIDs *sourceIDCache
+}
+
+// NewStockRepository creates a new stock repository. The data source repository is used to map
+// each stock's DataSource name onto its source_id.
+func NewStockRepository(db *sql.DB, dataSourceRepo DataSourceStore) *StockRepository {
+	return &StockRepository{
+		db:             db,
+		dataSourceRepo: dataSourceRepo,
+		sourceIDs:      newSourceIDCache(),
+	}
 }
 
-// NewStockRepository creates a new stock repository
-func NewStockRepository(db *sql.DB) *StockRepository {
-	return &StockRepository{db: db}
+// resolveSourceID returns the source_id for the named data source, caching the IDs of active sources.
+func (sr *StockRepository) resolveSourceID(ctx context.Context, sourceName string) (int, error) {
+	return sr.sourceIDs.resolve(ctx, sr.dataSourceRepo, sourceName)
+}
+
+// sourceIDCacheTTL is how long a cached source_id is used before the data source is looked up again, which
+// bounds how long rows keep being stored for a data source after it was deactivated.
+const sourceIDCacheTTL = time.Minute
+
+// sourceIDCache caches the source_id of active data sources, keyed by source_name.
+type sourceIDCache struct {
+	now func() time.Time // Replaceable clock for tests
+
+	mu      sync.RWMutex
+	entries map[string]cachedSourceID
 }
 
+// cachedSourceID is a source_id together with the time it has to be looked up again.
+type cachedSourceID struct {
+	sourceID int
+	expires  time.Time
+}
+
+// newSourceIDCache creates an empty sourceIDCache.
+func newSourceIDCache() *sourceIDCache {
+	return &sourceIDCache{now: time.Now, entries: make(map[string]cachedSourceID)}
+}
+
+// resolve returns the source_id for the named data source, looking it up through dataSourceRepo unless it
+// was cached within the last sourceIDCacheTTL.
+func (c *sourceIDCache) resolve(ctx context.Context, dataSourceRepo DataSourceStore, sourceName string) (int, error) {
+	c.mu.RLock()
+	entry, cached := c.entries[sourceName]
+	c.mu.RUnlock()
+	if cached && c.now().Before(entry.expires) {
+		return entry.sourceID, nil
+	}
+
+	// Inactive sources are rejected and so never cached, which lets re-activating them take effect immediately
+	sourceID, err := lookupSourceID(ctx, dataSourceRepo, sourceName)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.entries[sourceName] = cachedSourceID{sourceID: sourceID, expires: c.now().Add(sourceIDCacheTTL)}
+	c.mu.Unlock()
+
+	return sourceID, nil
+}
+
+// lookupSourceID returns the source_id for the named data source. Sources that have never been seen are
+// registered as PRICE sources. Stocks without a source or from an inactive source are rejected.
+func lookupSourceID(ctx context.Context, dataSourceRepo DataSourceStore, sourceName string) (int, error) {
+	if sourceName == "" {
+		return 0, errors.NewModelValidationError("Stock", "data_source", "data source is unknown")
+	}
+
+	ds, err := dataSourceRepo.GetByName(ctx, sourceName)
+	var notFound *errors.NotFoundError
+	if errors.As(err, &notFound) {
+		// Auto-register the provider so its rows get their own source_id
+		ds, err = dataSourceRepo.Create(ctx, &models.DataSource{
+			SourceName: sourceName,
+			SourceType: "PRICE",
+			IsActive:   true,
+		})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve data source %s: %w", sourceName, err)
+	}
+
+	if !ds.IsActive {
+		return 0, errors.NewModelValidationError("Stock", "data_source",
+			fmt.Sprintf("data source %s is not active", sourceName))
+	}
+	return ds.SourceID, nil
+}
+
+// SaveResult reports what an upsert did with each stock price it was given.
+type SaveResult struct {
+	Inserted   int `json:"inserted"`
+	Updated    int `json:"updated"`
+	Unchanged  int `json:"unchanged"`
+	Superseded int `json:"superseded,omitempty"` // Dropped for a later row of the same day and source in the batch
+}
+
+// StoredRange is the first and last date a data source stored prices of a symbol for.
+type StoredRange struct {
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+}
+
+// Total returns the number of stock prices that were processed.
+func (r *SaveResult) Total() int {
+	return r.Inserted + r.Updated + r.Unchanged + r.Superseded
+}
+
+// bulkUpsertThreshold is the batch size from which SaveStocksToDatabase switches to the COPY based bulk path.
+// Below it, the row by row statements are cheaper than creating and filling the staging table.
+const bulkUpsertThreshold = 50
+
 // SaveStocksToDatabase stores multiple stock price records. Using a transaction, all operations will
-// either succeed or fail together.
-func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*models.Stock) (int, error) {
+// either succeed or fail together. Large batches, e.g. a full history, are stored through bulkUpsertStocks.
+func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*models.Stock) (*SaveResult, error) {
+	if len(stocks) >= bulkUpsertThreshold {
+		return sr.bulkUpsertStocks(ctx, stocks)
+	}
+	return sr.upsertStocksRowByRow(ctx, stocks)
+}
+
+// upsertStocksRowByRow stores the stock prices with one company lookup and one upsert per row.
+func (sr *StockRepository) upsertStocksRowByRow(ctx context.Context, stocks []*models.Stock) (*SaveResult, error) {
 	// Begin transaction
 	// Transaction will ensures all stock prices are inserted/updated or none are.
 	// This preserves data consistency in case of errors.
 	tx, err := sr.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	// If commit doesn't occur, rollback will occur.
@@ -34,11 +156,19 @@ func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*m
 	defer tx.Rollback()
 
 	// Process each stock
-	for _, stock := range stocks {
+	sourceIDs := make([]int, len(stocks))
+	for i, stock := range stocks {
+
+		// Map the provider name onto its source_id before touching the stock_prices table
+		sourceID, err := sr.resolveSourceID(ctx, stock.DataSource)
+		if err != nil {
+			return nil, err
+		}
+		sourceIDs[i] = sourceID
 
 		// Check if company exists in the companies table
 		var companyID int
-		err := tx.QueryRowContext(
+		err = tx.QueryRowContext(
 			ctx,
 			`SELECT company_id FROM companies WHERE symbol = $1`,
 			stock.Symbol,
@@ -57,12 +187,12 @@ func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*m
 			).Scan(&companyID)
 
 			if err != nil {
-				return 0, fmt.Errorf("Failed to create company for symbol %s: %w",
+				return nil, fmt.Errorf("Failed to create company for symbol %s: %w",
 					stock.Symbol, err)
 
 			}
 		} else if err != nil {
-			return 0, fmt.Errorf("Failed to check if company exists for symbol %s: %w",
+			return nil, fmt.Errorf("Failed to check if company exists for symbol %s: %w",
 				stock.Symbol, err)
 		}
 
@@ -71,7 +201,7 @@ func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*m
 
 		// MOVED: Validate the stock data using model's validation AFTER setting the company ID.
 		if err := stock.Validate(); err != nil {
-			return 0, err
+			return nil, err
 		}
 
 	}
@@ -82,6 +212,8 @@ func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*m
 	// More efficient than	constructing the query for each stock
 	// Defer stmt.Close() to ensure the statement is properly closed when we're done to prevent resource leaks
 	// ON CONFLICT statement will prevent duplicate entries and EXCLUDED uses the value from row tried to insert
+	// The WHERE clause skips rows whose values did not change, in which case nothing is returned.
+	// xmax is only 0 for freshly inserted rows, which lets us tell inserts and updates apart.
 	stmt, err := tx.PrepareContext(
 		ctx,
 		`
@@ -101,25 +233,30 @@ func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*m
 		dividend_amount = EXCLUDED.dividend_amount,
 		split_coefficient = EXCLUDED.split_coefficient,
 		last_updated = EXCLUDED.last_updated
-		RETURNING price_id
+		WHERE (stock_prices.open_price, stock_prices.high_price, stock_prices.low_price,
+		stock_prices.close_price, stock_prices.adjusted_close, stock_prices.volume,
+		stock_prices.dividend_amount, stock_prices.split_coefficient)
+		IS DISTINCT FROM
+		(EXCLUDED.open_price, EXCLUDED.high_price, EXCLUDED.low_price,
+		EXCLUDED.close_price, EXCLUDED.adjusted_close, EXCLUDED.volume,
+		EXCLUDED.dividend_amount, EXCLUDED.split_coefficient)
+		RETURNING price_id, (xmax = 0) AS inserted
 		`,
 	)
 	if err != nil {
-		return 0, fmt.Errorf("Failed to prepare stock price insert statement: %w", err)
+		return nil, fmt.Errorf("Failed to prepare stock price insert statement: %w", err)
 	}
 
 	// Close prepared statement when we are done with it.
 	defer stmt.Close()
 
 	// Insert each stock price.
-	for _, stock := range stocks {
-		// Source ID for Alpha Vantage (assuming it's 1)
-		// TODO: make this a more flexible implementation to dynamically select the correct source ID.
-		const sourceID = 3
-
+	result := &SaveResult{}
+	for i, stock := range stocks {
 		// Execute the prepared statement with values for this stock
 		// RETURNING price_id gives us back the auto-generated PK
 		var priceID int
+		var inserted bool
 		err = stmt.QueryRowContext(
 			ctx,
 			stock.CompanyID,
@@ -133,28 +270,40 @@ func (sr *StockRepository) SaveStocksToDatabase(ctx context.Context, stocks []*m
 			stock.Volume,
 			stock.DividendAmount,
 			stock.SplitCoefficient,
-			sourceID,
-		).Scan(&priceID)
+			sourceIDs[i],
+		).Scan(&priceID, &inserted)
+
+		// No row means the stored values already matched, so the upsert was skipped.
+		if err == sql.ErrNoRows {
+			result.Unchanged++
+			continue
+		}
 
 		// Handle the error
 		if err != nil {
-			return 0, fmt.Errorf("failed to insert stock price for %s on %s: %w",
+			return nil, fmt.Errorf("failed to insert stock price for %s on %s: %w",
 				stock.Symbol, stock.Date.Format("2006-01-02"), err)
 		}
 
 		// Update the stock object with the Generated ID
 		// This keeps our in-memory data in sync with the DB.
 		stock.PriceID = priceID
+
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
 	}
 
 	// Commit the transaction
 	// This makes all our changes permanent in the DB.
 	// If this fails, the deferred rollback will undo everything.
 	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("Failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("Failed to commit transaction: %w", err)
 	}
 
-	return len(stocks), nil
+	return result, nil
 }
 
 // Retrieves stock prices for a symbol within a date range. The date range helps limit the data returned to what
@@ -229,3 +378,143 @@ func (sr *StockRepository) RetrieveStocksFromDatabase(
 
 	return stocks, nil
 }
+
+// GetLatestStockDate returns the most recent stored trading date for symbol from any of the named data sources.
+// The zero time is returned when nothing has been stored yet.
+func (sr *StockRepository) GetLatestStockDate(ctx context.Context, symbol string, sourceNames []string) (time.Time, error) {
+	query := `
+		SELECT MAX(sp.date)
+		FROM stock_prices sp
+		JOIN data_sources ds ON sp.source_id = ds.source_id
+		WHERE sp.symbol = $1 AND ds.source_name = ANY($2)
+	`
+
+	// MAX returns NULL when there are no rows, so scan into a nullable time
+	var latest sql.NullTime
+	if err := sr.db.QueryRowContext(ctx, query, symbol, pq.Array(sourceNames)).Scan(&latest); err != nil {
+		return time.Time{}, fmt.Errorf("failed to query latest stock date for %s: %w", symbol, err)
+	}
+
+	if !latest.Valid {
+		return time.Time{}, nil
+	}
+	return latest.Time, nil
+}
+
+// GetStoredDates returns the dates stored for symbol between startDate and endDate, grouped by data source
+// name. The dates of each source are in ascending order.
+func (sr *StockRepository) GetStoredDates(
+	ctx context.Context,
+	symbol string,
+	startDate, endDate time.Time,
+) (map[string][]time.Time, error) {
+	rows, err := sr.db.QueryContext(
+		ctx,
+		`
+		SELECT ds.source_name, sp.date
+		FROM stock_prices sp
+		JOIN data_sources ds ON sp.source_id = ds.source_id
+		WHERE sp.symbol = $1 AND sp.date BETWEEN $2 AND $3
+		ORDER BY ds.source_name, sp.date
+		`,
+		symbol,
+		startDate,
+		endDate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored dates for %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	dates := make(map[string][]time.Time)
+	for rows.Next() {
+		var source string
+		var date time.Time
+		if err := rows.Scan(&source, &date); err != nil {
+			return nil, fmt.Errorf("failed to scan stored date: %w", err)
+		}
+		dates[source] = append(dates[source], date)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stored dates: %w", err)
+	}
+	return dates, nil
+}
+
+// GetStoredDateRanges returns the first and last date stored for symbol, across its whole history, grouped by
+// data source name.
+func (sr *StockRepository) GetStoredDateRanges(ctx context.Context, symbol string) (map[string]StoredRange, error) {
+	rows, err := sr.db.QueryContext(
+		ctx,
+		`
+		SELECT ds.source_name, MIN(sp.date), MAX(sp.date)
+		FROM stock_prices sp
+		JOIN data_sources ds ON sp.source_id = ds.source_id
+		WHERE sp.symbol = $1
+		GROUP BY ds.source_name
+		`,
+		symbol,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored date ranges for %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	ranges := make(map[string]StoredRange)
+	for rows.Next() {
+		var source string
+		var stored StoredRange
+		if err := rows.Scan(&source, &stored.First, &stored.Last); err != nil {
+			return nil, fmt.Errorf("failed to scan stored date range: %w", err)
+		}
+		ranges[source] = stored
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stored date ranges: %w", err)
+	}
+	return ranges, nil
+}
+
+// UpdateAdjustedCloses stores the AdjustedClose of each stock by its PriceID in a single transaction.
+// Rows already holding that value are left alone. It returns the number of rows changed.
+func (sr *StockRepository) UpdateAdjustedCloses(ctx context.Context, stocks []*models.Stock) (int, error) {
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`
+		UPDATE stock_prices
+		SET adjusted_close = $2, last_updated = NOW()
+		WHERE price_id = $1 AND adjusted_close IS DISTINCT FROM $2
+		`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare adjusted close update: %w", err)
+	}
+	defer stmt.Close()
+
+	changed := 0
+	for _, stock := range stocks {
+		result, err := stmt.ExecContext(ctx, stock.PriceID, stock.AdjustedClose)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update adjusted close of %s on %s: %w",
+				stock.Symbol, stock.Date.Format("2006-01-02"), err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		changed += int(rows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
@@ -1 +1,215 @@
 package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"testing"
+	"time"
+)
+
+// openTestDB connects to the database in TEST_DATABASE_URL, which must be migrated to the latest schema.
+// Tests and benchmarks needing a database are skipped when it is not set.
+func openTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		tb.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		tb.Fatalf("Failed to connect to test database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+// deleteSymbol removes everything stored for symbol, so that runs start from an empty table.
+func deleteSymbol(tb testing.TB, db *sql.DB, symbol string) {
+	tb.Helper()
+
+	for _, query := range []string{
+		`DELETE FROM stock_prices WHERE symbol = $1`,
+		`DELETE FROM companies WHERE symbol = $1`,
+	} {
+		if _, err := db.Exec(query, symbol); err != nil {
+			tb.Fatalf("Failed to delete test data of %s: %v", symbol, err)
+		}
+	}
+}
+
+// generateStocks returns n consecutive daily bars for symbol, newest first like the providers return them.
+func generateStocks(symbol string, n int) []*models.Stock {
+	start := time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC)
+	stocks := make([]*models.Stock, n)
+	for i := range stocks {
+		price := 100 + float64(i%50)
+		stocks[n-1-i] = &models.Stock{
+			Symbol:           symbol,
+			Date:             start.AddDate(0, 0, i),
+			OpenPrice:        price,
+			HighPrice:        price + 2,
+			LowPrice:         price - 2,
+			ClosePrice:       price + 1,
+			AdjustedClose:    price + 1,
+			Volume:           float64(1000000 + i),
+			SplitCoefficient: 1,
+			DataSource:       "RepositoryTest",
+		}
+	}
+	return stocks
+}
+
+// TestBulkUpsertStocks verifies the COPY based path reports inserts, updates and unchanged rows like the
+// row by row path, and sets the PriceID of every stored stock.
+func TestBulkUpsertStocks(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewStockRepository(db, NewDataSourceRepository(db))
+	ctx := context.Background()
+
+	const symbol = "ZZBULK"
+	deleteSymbol(t, db, symbol)
+	t.Cleanup(func() { deleteSymbol(t, db, symbol) })
+
+	stocks := generateStocks(symbol, 120)
+	saved, err := repo.bulkUpsertStocks(ctx, stocks)
+	if err != nil {
+		t.Fatalf("Expected the first upsert to succeed, got %v", err)
+	}
+	if saved.Inserted != 120 || saved.Updated != 0 || saved.Unchanged != 0 {
+		t.Errorf("Expected 120 inserts, got %+v", saved)
+	}
+	for _, stock := range stocks {
+		if stock.PriceID == 0 || stock.CompanyID == 0 {
+			t.Fatalf("Expected PriceID and CompanyID to be set, got %v and %v", stock.PriceID, stock.CompanyID)
+		}
+	}
+
+	// Change 10 rows, the rest is stored already
+	for _, stock := range stocks[:10] {
+		stock.ClosePrice += 0.5
+	}
+	saved, err = repo.bulkUpsertStocks(ctx, stocks)
+	if err != nil {
+		t.Fatalf("Expected the second upsert to succeed, got %v", err)
+	}
+	if saved.Inserted != 0 || saved.Updated != 10 || saved.Unchanged != 110 {
+		t.Errorf("Expected 10 updates and 110 unchanged rows, got %+v", saved)
+	}
+
+	// The row by row path must agree that nothing changed
+	saved, err = repo.upsertStocksRowByRow(ctx, stocks)
+	if err != nil {
+		t.Fatalf("Expected the row by row upsert to succeed, got %v", err)
+	}
+	if saved.Unchanged != 120 {
+		t.Errorf("Expected 120 unchanged rows from the row by row path, got %+v", saved)
+	}
+
+	// Later corrections of 5 days in the same batch supersede the earlier rows
+	for _, stock := range stocks[:5] {
+		corrected := *stock
+		corrected.ClosePrice += 0.25
+		stocks = append(stocks, &corrected)
+	}
+	saved, err = repo.bulkUpsertStocks(ctx, stocks)
+	if err != nil {
+		t.Fatalf("Expected the upsert with duplicates to succeed, got %v", err)
+	}
+	if saved.Updated != 5 || saved.Unchanged != 115 || saved.Superseded != 5 || saved.Total() != len(stocks) {
+		t.Errorf("Expected 5 updates, 115 unchanged and 5 superseded rows, got %+v", saved)
+	}
+}
+
+// benchmarkUpsert stores a fresh history of each size with upsert, reporting the throughput in rows per second.
+func benchmarkUpsert(b *testing.B, upsert func(*StockRepository, context.Context, []*models.Stock) (*SaveResult, error)) {
+	db := openTestDB(b)
+	repo := NewStockRepository(db, NewDataSourceRepository(db))
+	ctx := context.Background()
+
+	for _, size := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			const symbol = "ZZBENCH"
+			b.Cleanup(func() { deleteSymbol(b, db, symbol) })
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				deleteSymbol(b, db, symbol)
+				stocks := generateStocks(symbol, size)
+				b.StartTimer()
+
+				if _, err := upsert(repo, ctx, stocks); err != nil {
+					b.Fatalf("Upsert failed: %v", err)
+				}
+			}
+			b.ReportMetric(float64(size*b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
+
+// BenchmarkUpsertStocksRowByRow measures the per-row company lookup and prepared INSERT.
+func BenchmarkUpsertStocksRowByRow(b *testing.B) {
+	benchmarkUpsert(b, (*StockRepository).upsertStocksRowByRow)
+}
+
+// BenchmarkBulkUpsertStocks measures the COPY into a staging table followed by a single merge.
+func BenchmarkBulkUpsertStocks(b *testing.B) {
+	benchmarkUpsert(b, (*StockRepository).bulkUpsertStocks)
+}
+
+// stubDataSourceStore serves fixed data sources.
+type stubDataSourceStore struct {
+	sources map[string]*models.DataSource
+}
+
+func (s *stubDataSourceStore) GetByName(ctx context.Context, name string) (*models.DataSource, error) {
+	ds, ok := s.sources[name]
+	if !ok {
+		return nil, errors.NewNotFoundError("DataSource", name)
+	}
+	copied := *ds
+	return &copied, nil
+}
+
+func (s *stubDataSourceStore) Create(ctx context.Context, ds *models.DataSource) (*models.DataSource, error) {
+	return nil, fmt.Errorf("unexpected create of %s", ds.SourceName)
+}
+
+// TestSourceIDCache verifies a deactivated data source is rejected once its cached source_id expired, and a
+// re-activated one is accepted right away.
+func TestSourceIDCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	fmp := &models.DataSource{SourceID: 7, SourceName: "FMP", SourceType: "PRICE", IsActive: true}
+	store := &stubDataSourceStore{sources: map[string]*models.DataSource{"FMP": fmp}}
+	cache := newSourceIDCache()
+	cache.now = func() time.Time { return now }
+
+	if sourceID, err := cache.resolve(ctx, store, "FMP"); err != nil || sourceID != 7 {
+		t.Fatalf("Expected source_id 7, got %d, %v", sourceID, err)
+	}
+
+	fmp.IsActive = false
+	if sourceID, err := cache.resolve(ctx, store, "FMP"); err != nil || sourceID != 7 {
+		t.Errorf("Expected the cached source_id until it expires, got %d, %v", sourceID, err)
+	}
+
+	now = now.Add(sourceIDCacheTTL)
+	var validationErr *errors.ModelValidationError
+	if _, err := cache.resolve(ctx, store, "FMP"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ModelValidationError for the deactivated source, got %v", err)
+	}
+
+	fmp.IsActive = true
+	if sourceID, err := cache.resolve(ctx, store, "FMP"); err != nil || sourceID != 7 {
+		t.Errorf("Expected the re-activated source to be accepted, got %d, %v", sourceID, err)
+	}
+}
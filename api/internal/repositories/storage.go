@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"database/sql"
+	"pocketanalyst/pkg/clients"
+)
+
+// Storage bundles the repositories of one storage backend.
+type Storage struct {
+	DataSources      DataSourceStore
+	Stocks           StockStore
+	Companies        CompanyStore
+	Quotas           clients.QuotaStore
+	Jobs             DataFetchJobStore
+	Logs             JobExecutionLogStore
+	Backfills        BackfillStore
+	CorporateActions CorporateActionStore
+}
+
+// NewPostgresStorage creates the repositories of a PostgreSQL database.
+func NewPostgresStorage(db *sql.DB) *Storage {
+	dataSources := NewDataSourceRepository(db)
+	return &Storage{
+		DataSources:      dataSources,
+		Stocks:           NewStockRepository(db, dataSources),
+		Companies:        NewCompanyRepository(db),
+		Quotas:           NewProviderQuotaRepository(db),
+		Jobs:             NewDataFetchJobRepository(db),
+		Logs:             NewJobExecutionLogRepository(db),
+		Backfills:        NewBackfillRepository(db),
+		CorporateActions: NewCorporateActionRepository(db),
+	}
+}
+
+// NewSQLiteStorage creates the repositories of a SQLite database. The database should be opened with a single
+// connection, see sqlite_repository.go.
+func NewSQLiteStorage(db *sql.DB) *Storage {
+	dataSources := NewSQLiteDataSourceRepository(db)
+	return &Storage{
+		DataSources:      dataSources,
+		Stocks:           NewSQLiteStockRepository(db, dataSources),
+		Companies:        NewSQLiteCompanyRepository(db),
+		Quotas:           NewSQLiteProviderQuotaRepository(db),
+		Jobs:             NewSQLiteDataFetchJobRepository(db),
+		Logs:             NewSQLiteJobExecutionLogRepository(db),
+		Backfills:        NewSQLiteBackfillRepository(db),
+		CorporateActions: NewSQLiteCorporateActionRepository(db),
+	}
+}
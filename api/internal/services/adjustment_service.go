@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/calendar"
+	"pocketanalyst/pkg/clients"
+	"pocketanalyst/pkg/errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManualActionSource is the source of corporate actions entered through the API rather than fetched.
+const ManualActionSource = "Manual"
+
+// AdjustmentResult reports what recording corporate actions changed.
+type AdjustmentResult struct {
+	Provider       string   `json:"provider,omitempty"` // Provider the actions were fetched from
+	Actions        int      `json:"actions"`            // Actions received
+	ChangedSymbols []string `json:"changed_symbols"`    // Symbols with new or changed actions
+	PricesUpdated  int      `json:"prices_updated"`     // Stored adjusted closes that were recomputed
+}
+
+// AdjustmentService maintains the corporate actions and back-adjusts stock prices with them.
+// Prices are stored as reported, adjusted_close is derived from close_price and the actions after that day.
+type AdjustmentService struct {
+	stockRepo     repositories.StockStore
+	actionRepo    repositories.CorporateActionStore
+	actionClients []clients.CorporateActionsClient
+}
+
+// NewAdjustmentService creates a new AdjustmentService. Corporate actions are fetched from actionClients,
+// in order of preference.
+func NewAdjustmentService(
+	stockRepo repositories.StockStore,
+	actionRepo repositories.CorporateActionStore,
+	actionClients []clients.CorporateActionsClient,
+) *AdjustmentService {
+	return &AdjustmentService{
+		stockRepo:     stockRepo,
+		actionRepo:    actionRepo,
+		actionClients: actionClients,
+	}
+}
+
+// SyncCorporateActions fetches the splits and dividends of symbol between from and to from the first provider
+// that answers, then records them like RecordCorporateActions. A zero from and to fetch all actions.
+func (as *AdjustmentService) SyncCorporateActions(
+	ctx context.Context,
+	symbol string,
+	from, to time.Time,
+) (*AdjustmentResult, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, errors.NewModelValidationError("AdjustmentService", "symbol", "symbol cannot be empty")
+	}
+	if len(as.actionClients) == 0 {
+		return nil, errors.NewServiceError("Fetching corporate actions",
+			fmt.Errorf("none of the configured providers reports corporate actions"))
+	}
+
+	var errs []error
+	for _, client := range as.actionClients {
+		actions, err := client.FetchCorporateActions(ctx, symbol, from, to)
+		if err != nil {
+			// No point in trying other providers once the caller is gone
+			if ctx.Err() != nil {
+				return nil, errors.NewServiceError("Fetching corporate actions", err)
+			}
+			log.Printf("Provider %s failed to report corporate actions of %s, trying next provider: %v",
+				client.GetProviderName(), symbol, err)
+			errs = append(errs, fmt.Errorf("%s: %w", client.GetProviderName(), err))
+			continue
+		}
+
+		result, err := as.RecordCorporateActions(ctx, actions)
+		if err != nil {
+			return nil, err
+		}
+		result.Provider = client.GetProviderName()
+		return result, nil
+	}
+	return nil, errors.NewServiceError("Fetching corporate actions", errors.Join(errs...))
+}
+
+// RecordCorporateActions stores the actions and recomputes the adjusted closes of every symbol whose
+// actions were added or changed. Actions without a source are recorded as manual entries.
+func (as *AdjustmentService) RecordCorporateActions(
+	ctx context.Context,
+	actions []*models.CorporateAction,
+) (*AdjustmentResult, error) {
+	for _, action := range actions {
+		action.Symbol = strings.ToUpper(strings.TrimSpace(action.Symbol))
+		action.ActionType = strings.ToUpper(strings.TrimSpace(action.ActionType))
+		action.ExDate = time.Date(action.ExDate.Year(), action.ExDate.Month(), action.ExDate.Day(), 0, 0, 0, 0, time.UTC)
+		if action.Source == "" {
+			action.Source = ManualActionSource
+		}
+		if err := action.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	changed, err := as.actionRepo.Upsert(ctx, actions)
+	if err != nil {
+		return nil, errors.NewServiceError("Storing corporate actions", err)
+	}
+
+	result := &AdjustmentResult{Actions: len(actions), ChangedSymbols: changed}
+	for _, symbol := range changed {
+		updated, err := as.RecomputeAdjustedCloses(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+		result.PricesUpdated += updated
+	}
+	return result, nil
+}
+
+// ListCorporateActions returns the actions of symbol, oldest first.
+func (as *AdjustmentService) ListCorporateActions(ctx context.Context, symbol string) ([]*models.CorporateAction, error) {
+	if strings.TrimSpace(symbol) == "" {
+		return nil, errors.NewModelValidationError("AdjustmentService", "symbol", "symbol cannot be empty")
+	}
+
+	actions, err := as.actionRepo.ListBySymbol(ctx, strings.ToUpper(strings.TrimSpace(symbol)), time.Time{})
+	if err != nil {
+		return nil, errors.NewServiceError("Retrieving corporate actions", err)
+	}
+	return actions, nil
+}
+
+// RecomputeAdjustedCloses merges the actions of symbol into its stored prices, back-adjusts its complete history
+// and stores every adjusted close that changed. It returns the number of prices updated.
+func (as *AdjustmentService) RecomputeAdjustedCloses(ctx context.Context, symbol string) (int, error) {
+	actions, err := as.actionRepo.ListBySymbol(ctx, symbol, time.Time{})
+	if err != nil {
+		return 0, errors.NewServiceError("Retrieving corporate actions", err)
+	}
+
+	// Keep the dividend and split columns of the prices in line with the actions
+	if _, err := as.actionRepo.MergeIntoPrices(ctx, symbol); err != nil {
+		return 0, errors.NewServiceError("Merging corporate actions into prices", err)
+	}
+
+	stocks, err := as.stockRepo.RetrieveStocksFromDatabase(ctx, symbol, time.Time{}, time.Now().UTC())
+	if err != nil {
+		return 0, errors.NewServiceError("Retrieving stock prices for adjustment", err)
+	}
+
+	updated, err := as.stockRepo.UpdateAdjustedCloses(ctx, AdjustStocks(stocks, actions))
+	if err != nil {
+		return 0, errors.NewServiceError("Storing adjusted closes", err)
+	}
+	if updated > 0 {
+		log.Printf("Recomputed %d adjusted closes of %s", updated, symbol)
+	}
+	return updated, nil
+}
+
+// RecomputeIfAdjusted recomputes the adjusted closes of symbol, but only if it has corporate actions.
+// Without actions the adjusted closes reported by the providers are kept.
+func (as *AdjustmentService) RecomputeIfAdjusted(ctx context.Context, symbol string) error {
+	actions, err := as.actionRepo.ListBySymbol(ctx, symbol, time.Time{})
+	if err != nil {
+		return errors.NewServiceError("Retrieving corporate actions", err)
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+
+	_, err = as.RecomputeAdjustedCloses(ctx, symbol)
+	return err
+}
+
+// ApplyStoredActions sets the dividend amount, split coefficient and adjusted close of freshly fetched stocks of
+// symbol to the values RecomputeAdjustedCloses derives from the stored actions, so storing them leaves prices
+// that didn't change alone. Without actions the values reported by the providers are kept.
+func (as *AdjustmentService) ApplyStoredActions(ctx context.Context, symbol string, stocks []*models.Stock) error {
+	actions, err := as.actionRepo.ListBySymbol(ctx, symbol, time.Time{})
+	if err != nil {
+		return errors.NewServiceError("Retrieving corporate actions", err)
+	}
+	if len(actions) == 0 || len(stocks) == 0 {
+		return nil
+	}
+
+	// Merge the actions into the columns of their ex-dates like MergeIntoPrices
+	for _, stock := range stocks {
+		for _, action := range actions {
+			if !action.ExDate.Equal(calendar.Date(stock.Date)) {
+				continue
+			}
+			switch action.ActionType {
+			case models.CorporateActionDividend:
+				stock.DividendAmount = action.Amount
+			case models.CorporateActionSplit:
+				stock.SplitCoefficient = action.Ratio
+			}
+		}
+	}
+
+	// Dividend factors depend on the closes of other days, so adjust the fetched stocks along with the stored ones
+	stored, err := as.stockRepo.RetrieveStocksFromDatabase(ctx, symbol, time.Time{}, time.Now().UTC())
+	if err != nil {
+		return errors.NewServiceError("Retrieving stock prices for adjustment", err)
+	}
+
+	fetched := make(map[string]bool, len(stocks))
+	for _, stock := range stocks {
+		fetched[stock.DataSource+stock.Date.Format("2006-01-02")] = true
+	}
+	combined := append([]*models.Stock{}, stocks...)
+	for _, stock := range stored {
+		if !fetched[stock.DataSource+stock.Date.Format("2006-01-02")] {
+			combined = append(combined, stock)
+		}
+	}
+
+	for i, adjusted := range AdjustStocks(combined, actions)[:len(stocks)] {
+		stocks[i].AdjustedClose = adjusted.AdjustedClose
+	}
+	return nil
+}
+
+// GetAdjustedHistory returns the stock prices of symbol between startDate and endDate with back-adjusted
+// open, high, low, close and volume, newest first like GetStockHistory.
+func (as *AdjustmentService) GetAdjustedHistory(
+	ctx context.Context,
+	symbol string,
+	startDate, endDate time.Time,
+) ([]*models.Stock, error) {
+	actions, err := as.actionRepo.ListBySymbol(ctx, symbol, startDate)
+	if err != nil {
+		return nil, errors.NewServiceError("Retrieving corporate actions", err)
+	}
+
+	// Every action after the range still adjusts it, and a dividend needs the close before its ex-date
+	stocks, err := as.stockRepo.RetrieveStocksFromDatabase(ctx, symbol, startDate, time.Now().UTC())
+	if err != nil {
+		return nil, errors.NewServiceError("Retrieving stock history", err)
+	}
+
+	adjusted := []*models.Stock{}
+	for _, stock := range AdjustStocks(stocks, actions) {
+		if !stock.Date.After(endDate) {
+			adjusted = append(adjusted, stock)
+		}
+	}
+	return adjusted, nil
+}
+
+// AdjustStocks returns back-adjusted copies of stocks, leaving the originals untouched. The prices of each
+// day are multiplied by the factors of all actions with a later ex-date:
+//
+//   - a split with ratio r divides prices by r and multiplies volume by r
+//   - a dividend d multiplies prices by 1 - d/c, where c is the close of the last day before its ex-date
+//
+// Each data source is adjusted on its own, using its own closes. The result keeps the order of stocks.
+func AdjustStocks(stocks []*models.Stock, actions []*models.CorporateAction) []*models.Stock {
+	// Apply the actions from the newest ex-date back
+	sortedActions := make([]*models.CorporateAction, len(actions))
+	copy(sortedActions, actions)
+	sort.SliceStable(sortedActions, func(i, j int) bool { return sortedActions[i].ExDate.After(sortedActions[j].ExDate) })
+
+	adjusted := make([]*models.Stock, len(stocks))
+	bySource := make(map[string][]int)
+	for i, stock := range stocks {
+		copied := *stock
+		adjusted[i] = &copied
+		bySource[stock.DataSource] = append(bySource[stock.DataSource], i)
+	}
+
+	for _, indexes := range bySource {
+		// Walk the days of this source from newest to oldest
+		sort.Slice(indexes, func(i, j int) bool { return adjusted[indexes[i]].Date.After(adjusted[indexes[j]].Date) })
+
+		priceFactor, volumeFactor := 1.0, 1.0
+		next := 0
+		for _, index := range indexes {
+			stock := adjusted[index]
+
+			// Every action with an ex-date after this day applies from here on back
+			for ; next < len(sortedActions) && sortedActions[next].ExDate.After(stock.Date); next++ {
+				action := sortedActions[next]
+				switch action.ActionType {
+				case models.CorporateActionSplit:
+					priceFactor /= action.Ratio
+					volumeFactor *= action.Ratio
+				case models.CorporateActionDividend:
+					// This is the last day before the ex-date, so its close is the one the dividend is paid from
+					if stock.ClosePrice > action.Amount {
+						priceFactor *= 1 - action.Amount/stock.ClosePrice
+					}
+				}
+			}
+
+			stock.OpenPrice = roundPrice(stock.OpenPrice * priceFactor)
+			stock.HighPrice = roundPrice(stock.HighPrice * priceFactor)
+			stock.LowPrice = roundPrice(stock.LowPrice * priceFactor)
+			stock.ClosePrice = roundPrice(stock.ClosePrice * priceFactor)
+			stock.AdjustedClose = stock.ClosePrice
+			stock.Volume = math.Round(stock.Volume * volumeFactor)
+		}
+	}
+	return adjusted
+}
+
+// roundPrice rounds to the 5 decimals stored in stock_prices, so unchanged prices compare equal.
+func roundPrice(price float64) float64 {
+	return math.Round(price*1e5) / 1e5
+}
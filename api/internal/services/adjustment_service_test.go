@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"pocketanalyst/internal/migrations"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver for the services that need corporate actions
+)
+
+// newSQLiteStorage creates the repositories of a migrated SQLite database in a temporary directory.
+func newSQLiteStorage(t *testing.T) *repositories.Storage {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db, migrations.SQLite)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate SQLite database: %v", err)
+	}
+	return repositories.NewSQLiteStorage(db)
+}
+
+// TestAdjustStocks verifies splits and dividends are applied to all earlier days, each source on its own,
+// and that the original stocks are left untouched.
+func TestAdjustStocks(t *testing.T) {
+	bar := func(d int, source string, closePrice float64) *models.Stock {
+		return &models.Stock{
+			Symbol:        "AAPL",
+			Date:          day(d),
+			OpenPrice:     closePrice,
+			HighPrice:     closePrice,
+			LowPrice:      closePrice,
+			ClosePrice:    closePrice,
+			AdjustedClose: closePrice,
+			Volume:        1000,
+			DataSource:    source,
+		}
+	}
+
+	// A dividend of 1 goes ex on the 5th, then a 2-for-1 split on the 7th
+	stocks := []*models.Stock{
+		bar(8, "FMP", 50), bar(7, "FMP", 50), bar(6, "FMP", 100), bar(5, "FMP", 100), bar(4, "FMP", 100),
+		bar(4, "CSV", 200),
+	}
+	actions := []*models.CorporateAction{
+		{Symbol: "AAPL", ActionType: models.CorporateActionDividend, ExDate: day(5), Amount: 1},
+		{Symbol: "AAPL", ActionType: models.CorporateActionSplit, ExDate: day(7), Ratio: 2},
+	}
+
+	adjusted := AdjustStocks(stocks, actions)
+
+	expected := []struct {
+		closePrice float64
+		volume     float64
+	}{
+		{50, 1000}, {50, 1000}, {50, 2000}, {50, 2000}, {49.5, 2000},
+		{99.5, 2000}, // The CSV dividend factor uses the CSV close: 200 * 0.5 * (1 - 1/200)
+	}
+	for i, want := range expected {
+		got := adjusted[i]
+		if got.ClosePrice != want.closePrice || got.AdjustedClose != want.closePrice || got.Volume != want.volume {
+			t.Errorf("%s %s: expected close %v and volume %v, got %v and %v", got.DataSource,
+				got.Date.Format("2006-01-02"), want.closePrice, want.volume, got.ClosePrice, got.Volume)
+		}
+	}
+
+	if stocks[4].ClosePrice != 100 || stocks[4].Volume != 1000 {
+		t.Errorf("Expected the original stocks to be unchanged, got %v", stocks[4])
+	}
+}
+
+// TestStockService_SyncKeepsAdjustedValues verifies an incremental sync of a symbol with corporate actions leaves
+// the derived dividend amounts and adjusted closes alone instead of rewriting them with the provider's values.
+func TestStockService_SyncKeepsAdjustedValues(t *testing.T) {
+	ctx := context.Background()
+	client := &stubClient{}
+	for d := 8; d >= 4; d-- {
+		client.stocks = append(client.stocks, &models.Stock{
+			Symbol:           "TEST",
+			Date:             day(d),
+			OpenPrice:        100,
+			HighPrice:        100,
+			LowPrice:         100,
+			ClosePrice:       100,
+			AdjustedClose:    100,
+			Volume:           1000,
+			SplitCoefficient: 1,
+			DataSource:       "Stub",
+		})
+	}
+
+	storage := newSQLiteStorage(t)
+	adjustments := NewAdjustmentService(storage.Stocks, storage.CorporateActions, nil)
+	service := NewStockService(storage.Stocks, storage.Companies, storage.Logs, adjustments, client, 2)
+
+	if _, err := service.SynchronizeStockData(ctx, "TEST"); err != nil {
+		t.Fatalf("Expected the first sync to succeed, got %v", err)
+	}
+	dividend := &models.CorporateAction{Symbol: "TEST", ActionType: models.CorporateActionDividend, ExDate: day(7), Amount: 1}
+	if _, err := adjustments.RecordCorporateActions(ctx, []*models.CorporateAction{dividend}); err != nil {
+		t.Fatalf("Expected the dividend to be recorded, got %v", err)
+	}
+
+	result, err := service.SynchronizeStockData(ctx, "TEST")
+	if err != nil {
+		t.Fatalf("Expected the second sync to succeed, got %v", err)
+	}
+	if result.Unchanged != 3 || result.Updated != 0 || result.Inserted != 0 {
+		t.Errorf("Expected the 3 overlap days to be unchanged, got %+v", result)
+	}
+
+	history, err := storage.Stocks.RetrieveStocksFromDatabase(ctx, "TEST", day(1), day(31))
+	if err != nil || len(history) != 5 {
+		t.Fatalf("Expected 5 stored days, got %v, %v", history, err)
+	}
+	// Newest first: the 7th carries the dividend, the days before it are adjusted for it
+	if history[1].DividendAmount != 1 || history[1].AdjustedClose != 100 || history[2].AdjustedClose != 99 {
+		t.Errorf("Expected the dividend on the 7th and an adjusted close of 99 on the 6th, got %v and %v",
+			history[1], history[2])
+	}
+}
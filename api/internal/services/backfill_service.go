@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"log"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"pocketanalyst/pkg/errors/client_errors"
+	"sync"
+	"time"
+)
+
+// BackfillConfig controls how backfill runs are split up and worked off.
+type BackfillConfig struct {
+	Workers          int           // Chunks fetched in parallel by this instance
+	PollInterval     time.Duration // How often idle workers look for new chunks
+	ChunkTimeout     time.Duration // Maximum run time of a single chunk
+	MaxAttempts      int           // Attempts per chunk before it is marked FAILED
+	DefaultChunkDays int           // Chunk length for runs that don't specify one
+}
+
+// BackfillService loads long date ranges for many symbols. Each run is split into chunks of one symbol and
+// date range that are checkpointed in the database, so a run survives restarts and resumes with the chunks
+// that are not done yet. Workers claim chunks with row locks, so several instances can share the work.
+type BackfillService struct {
+	backfillRepo repositories.BackfillStore
+	stockService *StockService
+	config       BackfillConfig
+
+	wake   chan struct{} // Signals that chunks became available before the next poll
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// NewBackfillService creates a new BackfillService fetching chunks through stockService.
+func NewBackfillService(
+	backfillRepo repositories.BackfillStore,
+	stockService *StockService,
+	config BackfillConfig,
+) *BackfillService {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 30 * time.Second
+	}
+	if config.ChunkTimeout <= 0 {
+		config.ChunkTimeout = 10 * time.Minute
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.DefaultChunkDays <= 0 {
+		config.DefaultChunkDays = 365
+	}
+
+	return &BackfillService{
+		backfillRepo: backfillRepo,
+		stockService: stockService,
+		config:       config,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// StartBackfill creates a run loading startDate to endDate for symbols, or for all active companies if
+// allActive is set. A chunkDays of 0 uses the configured default. Providers without date range support
+// download the full history for every chunk, so with them each symbol is loaded in a single chunk.
+func (bs *BackfillService) StartBackfill(
+	ctx context.Context,
+	symbols []string,
+	allActive bool,
+	startDate, endDate time.Time,
+	chunkDays int,
+) (*models.BackfillRun, error) {
+	if allActive {
+		var err error
+		if symbols, err = bs.stockService.ActiveSymbols(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if chunkDays == 0 {
+		chunkDays = bs.config.DefaultChunkDays
+	}
+	if !bs.stockService.SupportsDateRange() && !startDate.After(endDate) {
+		chunkDays = rangeDays(startDate, endDate)
+	}
+
+	run := &models.BackfillRun{
+		Symbols:   normalizeSymbols(symbols),
+		StartDate: startDate,
+		EndDate:   endDate,
+		ChunkDays: chunkDays,
+	}
+	return bs.createRun(ctx, run, run.Chunks())
+}
+
+// StartTargetedBackfill creates a run that only fetches the given symbol and date ranges, e.g. to fill gaps.
+// Ranges longer than the default chunk length are split up. Without date range support the ranges of each
+// symbol are merged into a single chunk instead, as every chunk downloads the symbol's full history.
+func (bs *BackfillService) StartTargetedBackfill(ctx context.Context, ranges []*models.BackfillChunk) (*models.BackfillRun, error) {
+	if len(ranges) == 0 {
+		return nil, errors.NewModelValidationError("BackfillService", "ranges", "at least one date range is required")
+	}
+
+	if !bs.stockService.SupportsDateRange() {
+		ranges = mergeSymbolRanges(ranges)
+	}
+
+	run := &models.BackfillRun{ChunkDays: bs.config.DefaultChunkDays}
+	chunks := []*models.BackfillChunk{}
+	symbols := []string{}
+	for _, r := range ranges {
+		symbols = append(symbols, r.Symbol)
+		if run.StartDate.IsZero() || r.StartDate.Before(run.StartDate) {
+			run.StartDate = r.StartDate
+		}
+		if r.EndDate.After(run.EndDate) {
+			run.EndDate = r.EndDate
+		}
+
+		// Split the range like a run of its own would be split
+		split := &models.BackfillRun{Symbols: []string{r.Symbol}, StartDate: r.StartDate, EndDate: r.EndDate, ChunkDays: run.ChunkDays}
+		if !bs.stockService.SupportsDateRange() {
+			split.ChunkDays = max(split.ChunkDays, rangeDays(r.StartDate, r.EndDate))
+		}
+		chunks = append(chunks, split.Chunks()...)
+	}
+	run.Symbols = normalizeSymbols(symbols)
+
+	return bs.createRun(ctx, run, chunks)
+}
+
+// mergeSymbolRanges merges the ranges of each symbol into one range from the earliest start to the latest end,
+// keeping the order in which the symbols first appear.
+func mergeSymbolRanges(ranges []*models.BackfillChunk) []*models.BackfillChunk {
+	merged := []*models.BackfillChunk{}
+	bySymbol := map[string]*models.BackfillChunk{}
+	for _, r := range ranges {
+		existing, ok := bySymbol[r.Symbol]
+		if !ok {
+			copied := *r
+			bySymbol[r.Symbol] = &copied
+			merged = append(merged, &copied)
+			continue
+		}
+		if r.StartDate.Before(existing.StartDate) {
+			existing.StartDate = r.StartDate
+		}
+		if r.EndDate.After(existing.EndDate) {
+			existing.EndDate = r.EndDate
+		}
+	}
+	return merged
+}
+
+// rangeDays returns the number of calendar days from start to end, both inclusive.
+func rangeDays(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// createRun stores a run with its chunks and wakes up the workers.
+func (bs *BackfillService) createRun(
+	ctx context.Context,
+	run *models.BackfillRun,
+	chunks []*models.BackfillChunk,
+) (*models.BackfillRun, error) {
+	created, err := bs.backfillRepo.CreateRun(ctx, run, chunks)
+	if err != nil {
+		return nil, serviceError("Creating backfill run", err)
+	}
+
+	bs.Wake()
+	return created, nil
+}
+
+// ListBackfills returns every run with its progress, newest first.
+func (bs *BackfillService) ListBackfills(ctx context.Context) ([]*models.BackfillRun, error) {
+	runs, err := bs.backfillRepo.ListRuns(ctx)
+	if err != nil {
+		return nil, errors.NewServiceError("Listing backfill runs", err)
+	}
+	return runs, nil
+}
+
+// GetBackfill returns a run with its progress.
+func (bs *BackfillService) GetBackfill(ctx context.Context, runID int) (*models.BackfillRun, error) {
+	run, err := bs.backfillRepo.GetRun(ctx, runID)
+	if err != nil {
+		return nil, serviceError("Retrieving backfill run", err)
+	}
+	return run, nil
+}
+
+// PauseBackfill stops handing out the chunks of a running run. Chunks already being fetched finish.
+func (bs *BackfillService) PauseBackfill(ctx context.Context, runID int) (*models.BackfillRun, error) {
+	run, err := bs.backfillRepo.SetRunStatus(ctx, runID, models.BackfillStatusPaused, models.BackfillStatusRunning)
+	if err != nil {
+		return nil, serviceError("Pausing backfill run", err)
+	}
+	return run, nil
+}
+
+// ResumeBackfill continues a paused run from its last checkpoint.
+func (bs *BackfillService) ResumeBackfill(ctx context.Context, runID int) (*models.BackfillRun, error) {
+	run, err := bs.backfillRepo.SetRunStatus(ctx, runID, models.BackfillStatusRunning, models.BackfillStatusPaused)
+	if err != nil {
+		return nil, serviceError("Resuming backfill run", err)
+	}
+
+	bs.Wake()
+	return run, nil
+}
+
+// CancelBackfill stops a running or paused run for good. Chunks already being fetched finish.
+func (bs *BackfillService) CancelBackfill(ctx context.Context, runID int) (*models.BackfillRun, error) {
+	run, err := bs.backfillRepo.SetRunStatus(ctx, runID, models.BackfillStatusCancelled,
+		models.BackfillStatusRunning, models.BackfillStatusPaused)
+	if err != nil {
+		return nil, serviceError("Cancelling backfill run", err)
+	}
+	return run, nil
+}
+
+// Start launches the workers in the background. Runs left unfinished by a previous process are picked up
+// where they stopped. Calling Start on running workers does nothing.
+func (bs *BackfillService) Start() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bs.cancel = cancel
+
+	for range bs.config.Workers {
+		bs.wg.Add(1)
+		go bs.work(ctx)
+	}
+	log.Printf("Backfill workers started: %d", bs.config.Workers)
+}
+
+// Stop interrupts the workers and waits for them to exit. Interrupted chunks are retried once they go stale.
+func (bs *BackfillService) Stop() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.cancel == nil {
+		return
+	}
+
+	bs.cancel()
+	bs.wg.Wait()
+	bs.cancel = nil
+	log.Println("Backfill workers stopped")
+}
+
+// Wake makes idle workers look for chunks right away. It never blocks.
+func (bs *BackfillService) Wake() {
+	select {
+	case bs.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work fetches chunks until ctx is cancelled, sleeping whenever there is nothing to do.
+func (bs *BackfillService) work(ctx context.Context) {
+	defer bs.wg.Done()
+
+	for ctx.Err() == nil {
+		delay := bs.processNextChunk(ctx)
+		if delay == 0 {
+			continue
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-bs.wake:
+			timer.Stop()
+			bs.Wake() // Pass the signal on to the other idle workers
+		case <-timer.C:
+		}
+	}
+}
+
+// processNextChunk claims and fetches one chunk. It returns how long the worker should wait before
+// claiming the next one, which is 0 while there is more work.
+func (bs *BackfillService) processNextChunk(ctx context.Context) time.Duration {
+	// Chunks interrupted for longer than a chunk may take were abandoned and are claimed again
+	chunk, err := bs.backfillRepo.ClaimChunk(ctx, 2*bs.config.ChunkTimeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Backfill worker failed to claim a chunk: %v", err)
+		}
+		return bs.config.PollInterval
+	}
+	if chunk == nil {
+		return bs.config.PollInterval
+	}
+
+	chunkCtx, cancel := context.WithTimeout(ctx, bs.config.ChunkTimeout)
+	result, err := bs.stockService.SynchronizeStockRange(chunkCtx, chunk.Symbol, chunk.StartDate, chunk.EndDate)
+	cancel()
+
+	// Leave a chunk interrupted by shutdown as RUNNING, it is retried once it goes stale
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	delay := time.Duration(0)
+	switch {
+	case err == nil:
+		chunk.Status = models.JobStatusSuccess
+		chunk.RecordsProcessed = result.Fetched
+		chunk.ErrorMessage = ""
+	case isRateLimitError(err):
+		// Running out of quota is not the chunk's fault, retry it once the quota allows
+		chunk.Status = models.JobStatusPending
+		chunk.ErrorMessage = err.Error()
+		delay = rateLimitDelay(err, bs.config.PollInterval)
+	default:
+		chunk.Attempts++
+		chunk.ErrorMessage = err.Error()
+		chunk.Status = models.JobStatusPending
+		if chunk.Attempts >= bs.config.MaxAttempts {
+			chunk.Status = models.JobStatusFailed
+		}
+		log.Printf("Backfill of %s from %s to %s failed (attempt %d of %d): %v", chunk.Symbol,
+			chunk.StartDate.Format("2006-01-02"), chunk.EndDate.Format("2006-01-02"),
+			chunk.Attempts, bs.config.MaxAttempts, err)
+	}
+
+	if err := bs.backfillRepo.FinishChunk(ctx, chunk); err != nil {
+		log.Printf("Backfill worker failed to checkpoint chunk %d: %v", chunk.ChunkID, err)
+		return bs.config.PollInterval
+	}
+	return delay
+}
+
+// isRateLimitError reports whether err was caused by a provider rate limit.
+func isRateLimitError(err error) bool {
+	var rateLimitErr *client_errors.RateLimitExceededError
+	return errors.As(err, &rateLimitErr)
+}
+
+// rateLimitDelay returns how long to wait after a rate limit error, at least fallback.
+func rateLimitDelay(err error, fallback time.Duration) time.Duration {
+	var rateLimitErr *client_errors.RateLimitExceededError
+	if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > fallback {
+		return rateLimitErr.RetryAfter
+	}
+	return fallback
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/clients"
+	"testing"
+	"time"
+)
+
+// rangeClient is a stubClient whose provider filters date ranges server-side.
+type rangeClient struct {
+	*stubClient
+}
+
+func (c rangeClient) SupportsDateRange() bool {
+	return true
+}
+
+// newBackfillService creates a BackfillService fetching through client on top of a SQLite database.
+func newBackfillService(t *testing.T, client clients.StockDataClient) *BackfillService {
+	storage := newSQLiteStorage(t)
+	stockService := NewStockService(storage.Stocks, storage.Companies, storage.Logs, nil, client, 0)
+	return NewBackfillService(storage.Backfills, stockService, BackfillConfig{DefaultChunkDays: 30})
+}
+
+// TestBackfillService_StartBackfill verifies runs are only split into several chunks per symbol if the
+// provider supports date ranges, as every chunk downloads the full history otherwise.
+func TestBackfillService_StartBackfill(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		service  *BackfillService
+		expected int
+	}{
+		{"Ranges", newBackfillService(t, rangeClient{&stubClient{}}), 26},
+		{"FullHistory", newBackfillService(t, &stubClient{}), 2},
+	}
+	for _, test := range tests {
+		run, err := test.service.StartBackfill(ctx, []string{"AAPL", "MSFT"}, false, start, end, 0)
+		if err != nil {
+			t.Fatalf("%s: expected the run to be created, got %v", test.name, err)
+		}
+		if run.TotalChunks != test.expected {
+			t.Errorf("%s: expected %d chunks, got %d", test.name, test.expected, run.TotalChunks)
+		}
+	}
+}
+
+// TestBackfillService_StartTargetedBackfill verifies the ranges of a symbol are merged into one chunk if the
+// provider doesn't support date ranges.
+func TestBackfillService_StartTargetedBackfill(t *testing.T) {
+	ctx := context.Background()
+	ranges := func() []*models.BackfillChunk {
+		return []*models.BackfillChunk{
+			{Symbol: "AAPL", StartDate: day(11), EndDate: day(12)},
+			{Symbol: "MSFT", StartDate: day(4), EndDate: day(5)},
+			{Symbol: "AAPL", StartDate: day(4), EndDate: day(6)},
+		}
+	}
+
+	service := newBackfillService(t, rangeClient{&stubClient{}})
+	run, err := service.StartTargetedBackfill(ctx, ranges())
+	if err != nil || run.TotalChunks != 3 {
+		t.Fatalf("Expected a run with 3 chunks, got %+v, %v", run, err)
+	}
+
+	service = newBackfillService(t, &stubClient{})
+	run, err = service.StartTargetedBackfill(ctx, ranges())
+	if err != nil || run.TotalChunks != 2 {
+		t.Fatalf("Expected a run with 2 chunks, got %+v, %v", run, err)
+	}
+
+	chunks := map[string]*models.BackfillChunk{}
+	for range run.TotalChunks {
+		chunk, err := service.backfillRepo.ClaimChunk(ctx, time.Hour)
+		if err != nil || chunk == nil {
+			t.Fatalf("Expected a chunk to be claimed, got %v, %v", chunk, err)
+		}
+		chunks[chunk.Symbol] = chunk
+	}
+	if aapl := chunks["AAPL"]; aapl == nil || !aapl.StartDate.Equal(day(4)) || !aapl.EndDate.Equal(day(12)) {
+		t.Errorf("Expected AAPL to be loaded from the 4th to the 12th, got %+v", aapl)
+	}
+	if msft := chunks["MSFT"]; msft == nil || !msft.StartDate.Equal(day(4)) || !msft.EndDate.Equal(day(5)) {
+		t.Errorf("Expected MSFT to be loaded from the 4th to the 5th, got %+v", msft)
+	}
+}
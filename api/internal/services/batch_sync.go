@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/pkg/errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Outcomes of a single symbol in a batch synchronization
+const (
+	SymbolSyncSucceeded = "success"
+	SymbolSyncFailed    = "failed"
+	SymbolSyncSkipped   = "skipped" // Not attempted because the provider quota ran out or the batch was cancelled
+)
+
+// SymbolSyncResult is the outcome of one symbol in a batch synchronization.
+type SymbolSyncResult struct {
+	Symbol string      `json:"symbol"`
+	Status string      `json:"status"`
+	Result *SyncResult `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// BatchSyncResult summarizes a batch synchronization, with one entry per symbol in the requested order.
+type BatchSyncResult struct {
+	Requested int                `json:"requested"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Skipped   int                `json:"skipped"`
+	Results   []SymbolSyncResult `json:"results"`
+}
+
+// SynchronizeStockDataBatch synchronizes every symbol using at most concurrency workers. The workers share
+// the provider clients, so the per-provider rate limiters throttle the batch as a whole. A failing symbol
+// doesn't stop the batch, but once a provider quota is exhausted the remaining symbols are skipped.
+func (s *StockService) SynchronizeStockDataBatch(ctx context.Context, symbols []string, concurrency int) (*BatchSyncResult, error) {
+	symbols = normalizeSymbols(symbols)
+	if len(symbols) == 0 {
+		return nil, errors.NewModelValidationError("StockService", "symbols", "at least one symbol is required")
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]SymbolSyncResult, len(symbols))
+	indexes := make(chan int)
+
+	// Set once a rate limit makes further requests pointless. Symbols already in flight are left to finish.
+	var quotaExhausted atomic.Bool
+
+	var wg sync.WaitGroup
+	for range min(concurrency, len(symbols)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = s.synchronizeBatchSymbol(ctx, symbols[i], &quotaExhausted)
+			}
+		}()
+	}
+
+	for i := range symbols {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	batch := &BatchSyncResult{Requested: len(symbols), Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case SymbolSyncSucceeded:
+			batch.Succeeded++
+		case SymbolSyncFailed:
+			batch.Failed++
+		case SymbolSyncSkipped:
+			batch.Skipped++
+		}
+	}
+	return batch, nil
+}
+
+// ActiveSymbols returns the symbols of all active companies.
+func (s *StockService) ActiveSymbols(ctx context.Context) ([]string, error) {
+	symbols, err := s.companyRepo.ListActiveSymbols(ctx)
+	if err != nil {
+		return nil, errors.NewServiceError("Listing active companies", err)
+	}
+	return symbols, nil
+}
+
+// synchronizeBatchSymbol synchronizes a single symbol of a batch, unless the batch was cancelled or the
+// provider quota is exhausted.
+func (s *StockService) synchronizeBatchSymbol(ctx context.Context, symbol string, quotaExhausted *atomic.Bool) SymbolSyncResult {
+	if ctx.Err() != nil {
+		return SymbolSyncResult{Symbol: symbol, Status: SymbolSyncSkipped, Error: ctx.Err().Error()}
+	}
+	if quotaExhausted.Load() {
+		return SymbolSyncResult{Symbol: symbol, Status: SymbolSyncSkipped, Error: "skipped after the provider quota was exhausted"}
+	}
+
+	result, err := s.SynchronizeStockData(ctx, symbol)
+	if err != nil {
+		if isRateLimitError(err) {
+			quotaExhausted.Store(true)
+		}
+		return SymbolSyncResult{Symbol: symbol, Status: SymbolSyncFailed, Error: err.Error()}
+	}
+	return SymbolSyncResult{Symbol: symbol, Status: SymbolSyncSucceeded, Result: result}
+}
+
+// normalizeSymbols upper-cases the symbols and drops blanks and duplicates, keeping the original order.
+func normalizeSymbols(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	normalized := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		normalized = append(normalized, symbol)
+	}
+	return normalized
+}
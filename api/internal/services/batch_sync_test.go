@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"pocketanalyst/pkg/errors/client_errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newBatchClient serves two days of prices for each symbol.
+func newBatchClient(symbols ...string) *stubClient {
+	client := &stubClient{failures: map[string]error{}}
+	for _, symbol := range symbols {
+		for d := 4; d <= 5; d++ {
+			client.stocks = append(client.stocks, &models.Stock{
+				Symbol:           symbol,
+				Date:             day(d),
+				OpenPrice:        100,
+				HighPrice:        102,
+				LowPrice:         99,
+				ClosePrice:       101,
+				AdjustedClose:    101,
+				Volume:           1000,
+				SplitCoefficient: 1,
+				DataSource:       "Stub",
+			})
+		}
+	}
+	return client
+}
+
+// TestNormalizeSymbols verifies symbols are upper-cased and blanks and duplicates are dropped in order.
+func TestNormalizeSymbols(t *testing.T) {
+	normalized := normalizeSymbols([]string{" msft", "AAPL", "", "Msft ", "  ", "ibm", "aapl"})
+	if expected := []string{"MSFT", "AAPL", "IBM"}; !slices.Equal(normalized, expected) {
+		t.Errorf("Expected %v, got %v", expected, normalized)
+	}
+
+	if normalized := normalizeSymbols(nil); normalized == nil || len(normalized) != 0 {
+		t.Errorf("Expected an empty slice, got %#v", normalized)
+	}
+}
+
+// TestStockService_SynchronizeStockDataBatch verifies every symbol is reported in the requested order and a
+// failing symbol doesn't stop the batch.
+func TestStockService_SynchronizeStockDataBatch(t *testing.T) {
+	client := newBatchClient("AAPL", "MSFT", "IBM")
+	client.failures["MSFT"] = client_errors.NewAPIError("provider failed")
+	service := newMemoryStockService(client, 0)
+
+	batch, err := service.SynchronizeStockDataBatch(context.Background(), []string{"ibm", "MSFT", "aapl", "IBM"}, 3)
+	if err != nil {
+		t.Fatalf("Expected the batch to succeed, got %v", err)
+	}
+	if batch.Requested != 3 || batch.Succeeded != 2 || batch.Failed != 1 || batch.Skipped != 0 {
+		t.Errorf("Expected 3 requested, 2 succeeded and 1 failed, got %+v", batch)
+	}
+
+	expected := []struct{ symbol, status string }{
+		{"IBM", SymbolSyncSucceeded},
+		{"MSFT", SymbolSyncFailed},
+		{"AAPL", SymbolSyncSucceeded},
+	}
+	for i, result := range batch.Results {
+		if result.Symbol != expected[i].symbol || result.Status != expected[i].status {
+			t.Errorf("Expected %s to be %s at %d, got %+v", expected[i].symbol, expected[i].status, i, result)
+		}
+	}
+	if result := batch.Results[0]; result.Result == nil || result.Result.Inserted != 2 {
+		t.Errorf("Expected 2 inserted rows for IBM, got %+v", result.Result)
+	}
+	if result := batch.Results[1]; result.Result != nil || result.Error == "" {
+		t.Errorf("Expected an error without a result for MSFT, got %+v", result)
+	}
+
+	var validationErr *errors.ModelValidationError
+	if _, err := service.SynchronizeStockDataBatch(context.Background(), []string{" ", ""}, 3); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ModelValidationError for a batch without symbols, got %v", err)
+	}
+}
+
+// TestStockService_SynchronizeStockDataBatch_QuotaExhausted verifies the symbols after a rate limit error are
+// skipped without requesting them from the provider.
+func TestStockService_SynchronizeStockDataBatch_QuotaExhausted(t *testing.T) {
+	client := newBatchClient("AAPL", "MSFT", "IBM", "GOOG")
+	client.failures["MSFT"] = client_errors.NewRateLimitExceededError("Stub", "daily", 2, time.Hour)
+	service := newMemoryStockService(client, 0)
+
+	batch, err := service.SynchronizeStockDataBatch(context.Background(), []string{"AAPL", "MSFT", "IBM", "GOOG"}, 1)
+	if err != nil {
+		t.Fatalf("Expected the batch to succeed, got %v", err)
+	}
+	if batch.Succeeded != 1 || batch.Failed != 1 || batch.Skipped != 2 {
+		t.Errorf("Expected 1 succeeded, 1 failed and 2 skipped, got %+v", batch)
+	}
+	for _, result := range batch.Results[2:] {
+		if result.Status != SymbolSyncSkipped || result.Error == "" {
+			t.Errorf("Expected %s to be skipped with a reason, got %+v", result.Symbol, result)
+		}
+	}
+	if expected := []string{"AAPL", "MSFT"}; !slices.Equal(client.requested, expected) {
+		t.Errorf("Expected only %v to be requested, got %v", expected, client.requested)
+	}
+
+	// A cancelled batch skips everything
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	batch, err = service.SynchronizeStockDataBatch(ctx, []string{"IBM", "GOOG"}, 2)
+	if err != nil || batch.Skipped != 2 {
+		t.Errorf("Expected both symbols of a cancelled batch to be skipped, got %+v, %v", batch, err)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/clients"
+	"pocketanalyst/pkg/errors"
+	"strings"
+	"time"
+)
+
+// ManualProfileSource is the profile source of companies edited through the API. Enrichment leaves them alone.
+const ManualProfileSource = "Manual"
+
+// DefaultEnrichLimit is the number of placeholder companies enriched per call when no limit is given.
+const DefaultEnrichLimit = 50
+
+// EnrichmentResult reports which placeholder companies received a profile.
+type EnrichmentResult struct {
+	Checked  int               `json:"checked"`  // Placeholders a profile was requested for
+	Enriched []string          `json:"enriched"` // Symbols whose profile was stored
+	Failed   map[string]string `json:"failed"`   // Error per symbol no provider had a profile for
+}
+
+// CompanyService maintains the companies stock prices are stored for, filling in the name, sector, industry
+// and exchange of placeholder companies from the providers' company profiles.
+type CompanyService struct {
+	companyRepo    repositories.CompanyStore
+	profileClients []clients.CompanyProfileClient
+}
+
+// NewCompanyService creates a new CompanyService. Profiles are fetched from profileClients, in order of preference.
+func NewCompanyService(
+	companyRepo repositories.CompanyStore,
+	profileClients []clients.CompanyProfileClient,
+) *CompanyService {
+	return &CompanyService{
+		companyRepo:    companyRepo,
+		profileClients: profileClients,
+	}
+}
+
+// GetCompany returns the company of symbol.
+func (cs *CompanyService) GetCompany(ctx context.Context, symbol string) (*models.Company, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, errors.NewModelValidationError("CompanyService", "symbol", "symbol cannot be empty")
+	}
+
+	company, err := cs.companyRepo.GetBySymbol(ctx, symbol)
+	if err != nil {
+		return nil, serviceError("Retrieving company", err)
+	}
+	return company, nil
+}
+
+// SaveCompany stores a company edited by hand, creating it if it doesn't exist yet. The profile is marked as
+// manual, so enrichment won't overwrite it. It reports whether the company was created.
+func (cs *CompanyService) SaveCompany(ctx context.Context, company *models.Company) (*models.Company, bool, error) {
+	company.Symbol = strings.ToUpper(strings.TrimSpace(company.Symbol))
+	company.Name = strings.TrimSpace(company.Name)
+	company.ProfileSource = ManualProfileSource
+	company.ProfileUpdated = time.Now().UTC()
+
+	saved, err := cs.companyRepo.Update(ctx, company)
+	var notFound *errors.NotFoundError
+	if !errors.As(err, &notFound) {
+		if err != nil {
+			return nil, false, serviceError("Updating company", err)
+		}
+		return saved, false, nil
+	}
+
+	created, err := cs.companyRepo.Create(ctx, company)
+	var conflict *errors.ConflictError
+	if errors.As(err, &conflict) {
+		// A concurrent request created the company first, update that one instead
+		return cs.SaveCompany(ctx, company)
+	}
+	if err != nil {
+		return nil, false, serviceError("Creating company", err)
+	}
+	return created, true, nil
+}
+
+// DeleteCompany removes the company of symbol. Companies with stored prices or corporate actions are kept.
+func (cs *CompanyService) DeleteCompany(ctx context.Context, symbol string) error {
+	if err := cs.companyRepo.Delete(ctx, strings.ToUpper(strings.TrimSpace(symbol))); err != nil {
+		return serviceError("Deleting company", err)
+	}
+	return nil
+}
+
+// EnrichCompany fetches the profile of symbol from the first provider that has one and stores it, replacing
+// the company's name and every sector, industry and exchange the provider reports. Manual profiles are
+// overwritten too, as the caller asked for this company explicitly.
+func (cs *CompanyService) EnrichCompany(ctx context.Context, symbol string) (*models.Company, error) {
+	company, err := cs.GetCompany(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := cs.fetchProfile(ctx, company.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	company.Name = profile.Name
+	if profile.Sector != "" {
+		company.Sector = profile.Sector
+	}
+	if profile.Industry != "" {
+		company.Industry = profile.Industry
+	}
+	if profile.Exchange != "" {
+		company.Exchange = profile.Exchange
+	}
+	company.ProfileSource = profile.ProfileSource
+	company.ProfileUpdated = time.Now().UTC()
+
+	updated, err := cs.companyRepo.Update(ctx, company)
+	if err != nil {
+		return nil, serviceError("Storing company profile", err)
+	}
+	log.Printf("Stored the profile of %s from %s", updated.Symbol, updated.ProfileSource)
+	return updated, nil
+}
+
+// EnrichPlaceholders enriches up to limit active companies that never received a profile, the ones checked
+// longest ago first. Companies no provider has a profile for stay placeholders and are reported as failed.
+// They are tried again once the other placeholders were checked, so they don't block them.
+func (cs *CompanyService) EnrichPlaceholders(ctx context.Context, limit int) (*EnrichmentResult, error) {
+	if limit <= 0 {
+		limit = DefaultEnrichLimit
+	}
+
+	placeholders, err := cs.companyRepo.ListPlaceholders(ctx, limit)
+	if err != nil {
+		return nil, errors.NewServiceError("Listing placeholder companies", err)
+	}
+
+	result := &EnrichmentResult{Enriched: []string{}, Failed: make(map[string]string)}
+	for _, company := range placeholders {
+		// No point in fetching more profiles once the caller is gone
+		if err := ctx.Err(); err != nil {
+			return nil, errors.NewServiceError("Enriching companies", err)
+		}
+
+		result.Checked++
+		if err := cs.companyRepo.MarkProfileChecked(ctx, company.Symbol); err != nil {
+			log.Printf("Failed to record the profile check of %s: %v", company.Symbol, err)
+		}
+		if _, err := cs.EnrichCompany(ctx, company.Symbol); err != nil {
+			log.Printf("Failed to enrich company %s: %v", company.Symbol, err)
+			result.Failed[company.Symbol] = err.Error()
+			continue
+		}
+		result.Enriched = append(result.Enriched, company.Symbol)
+	}
+	return result, nil
+}
+
+// fetchProfile returns the profile of symbol from the first provider that answers.
+func (cs *CompanyService) fetchProfile(ctx context.Context, symbol string) (*models.Company, error) {
+	if len(cs.profileClients) == 0 {
+		return nil, errors.NewServiceError("Fetching company profile",
+			fmt.Errorf("none of the configured providers reports company profiles"))
+	}
+
+	var errs []error
+	for _, client := range cs.profileClients {
+		profile, err := client.FetchCompanyProfile(ctx, symbol)
+		if err != nil {
+			// No point in trying other providers once the caller is gone
+			if ctx.Err() != nil {
+				return nil, errors.NewServiceError("Fetching company profile", err)
+			}
+			log.Printf("Provider %s failed to report the profile of %s, trying next provider: %v",
+				client.GetProviderName(), symbol, err)
+			errs = append(errs, fmt.Errorf("%s: %w", client.GetProviderName(), err))
+			continue
+		}
+		return profile, nil
+	}
+	return nil, errors.NewServiceError("Fetching company profile", errors.Join(errs...))
+}
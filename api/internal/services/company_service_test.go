@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/clients"
+	"slices"
+	"testing"
+)
+
+// stubProfileClient serves fixed profiles and fails for every other symbol.
+type stubProfileClient struct {
+	name     string
+	profiles map[string]*models.Company
+}
+
+func (c *stubProfileClient) FetchCompanyProfile(ctx context.Context, symbol string) (*models.Company, error) {
+	profile, ok := c.profiles[symbol]
+	if !ok {
+		return nil, fmt.Errorf("no profile for %s", symbol)
+	}
+	copied := *profile
+	copied.ProfileSource = c.name
+	return &copied, nil
+}
+
+func (c *stubProfileClient) GetProviderName() string {
+	return c.name
+}
+
+// TestCompanyService_EnrichPlaceholders verifies placeholders receive the profile of the first provider that has
+// one, while companies edited by hand and companies without a profile are left as they are.
+func TestCompanyService_EnrichPlaceholders(t *testing.T) {
+	ctx := context.Background()
+	db := repositories.NewMemoryDB()
+	dataSources := repositories.NewMemoryDataSourceRepository(db)
+	companies := repositories.NewMemoryCompanyRepository(db)
+
+	// Storing prices creates the placeholders
+	stocks := []*models.Stock{}
+	for _, symbol := range []string{"AAPL", "IBM", "NOPE"} {
+		stocks = append(stocks, &models.Stock{
+			Symbol:           symbol,
+			Date:             day(4),
+			OpenPrice:        100,
+			HighPrice:        102,
+			LowPrice:         99,
+			ClosePrice:       101,
+			AdjustedClose:    101,
+			Volume:           1000,
+			SplitCoefficient: 1,
+			DataSource:       "Stub",
+		})
+	}
+	if _, err := repositories.NewMemoryStockRepository(db, dataSources).SaveStocksToDatabase(ctx, stocks); err != nil {
+		t.Fatalf("Expected the stocks to be saved, got %v", err)
+	}
+
+	fmp := &stubProfileClient{name: "FMP", profiles: map[string]*models.Company{
+		"AAPL": {Symbol: "AAPL", Name: "Apple Inc.", Sector: "Technology", Exchange: "NASDAQ"},
+	}}
+	alphaVantage := &stubProfileClient{name: "AlphaVantage", profiles: map[string]*models.Company{
+		"AAPL": {Symbol: "AAPL", Name: "APPLE INC"},
+		"IBM":  {Symbol: "IBM", Name: "International Business Machines", Industry: "COMPUTER & OFFICE EQUIPMENT"},
+	}}
+	service := NewCompanyService(companies, []clients.CompanyProfileClient{fmp, alphaVantage})
+
+	// IBM was named by hand before the enrichment
+	if _, created, err := service.SaveCompany(ctx, &models.Company{Symbol: "ibm", Name: "IBM Corp", IsActive: true}); err != nil || created {
+		t.Fatalf("Expected the placeholder to be updated, got created %v, %v", created, err)
+	}
+
+	result, err := service.EnrichPlaceholders(ctx, 0)
+	if err != nil {
+		t.Fatalf("Expected the placeholders to be enriched, got %v", err)
+	}
+	if result.Checked != 2 || len(result.Enriched) != 1 || result.Enriched[0] != "AAPL" || result.Failed["NOPE"] == "" {
+		t.Errorf("Expected AAPL to be enriched and NOPE to fail, got %+v", result)
+	}
+
+	apple, _ := service.GetCompany(ctx, "aapl")
+	if apple.Name != "Apple Inc." || apple.Sector != "Technology" || apple.Exchange != "NASDAQ" ||
+		apple.ProfileSource != "FMP" || apple.IsPlaceholder() {
+		t.Errorf("Expected the FMP profile of AAPL, got %+v", apple)
+	}
+	ibm, _ := service.GetCompany(ctx, "IBM")
+	if ibm.Name != "IBM Corp" || ibm.ProfileSource != ManualProfileSource {
+		t.Errorf("Expected the manual profile of IBM to be kept, got %+v", ibm)
+	}
+	nope, _ := service.GetCompany(ctx, "NOPE")
+	if !nope.IsPlaceholder() || nope.Name != "NOPE" {
+		t.Errorf("Expected NOPE to stay a placeholder, got %+v", nope)
+	}
+
+	// Enriching a single company replaces its manual profile, falling over to the next provider
+	ibm, err = service.EnrichCompany(ctx, "IBM")
+	if err != nil {
+		t.Fatalf("Expected IBM to be enriched, got %v", err)
+	}
+	if ibm.Name != "International Business Machines" || ibm.Industry != "COMPUTER & OFFICE EQUIPMENT" ||
+		ibm.ProfileSource != "AlphaVantage" {
+		t.Errorf("Expected the AlphaVantage profile of IBM, got %+v", ibm)
+	}
+}
+
+// TestCompanyService_EnrichPlaceholders_Rotates verifies placeholders no provider has a profile for are tried
+// again only after the other placeholders were checked.
+func TestCompanyService_EnrichPlaceholders_Rotates(t *testing.T) {
+	ctx := context.Background()
+	companies := repositories.NewMemoryCompanyRepository(repositories.NewMemoryDB())
+	for _, symbol := range []string{"AAA", "BBB", "CCC"} {
+		if _, err := companies.Create(ctx, &models.Company{Symbol: symbol, Name: symbol, IsActive: true}); err != nil {
+			t.Fatalf("Expected the placeholder to be created, got %v", err)
+		}
+	}
+
+	client := &stubProfileClient{name: "FMP", profiles: map[string]*models.Company{
+		"CCC": {Symbol: "CCC", Name: "C Corp"},
+	}}
+	service := NewCompanyService(companies, []clients.CompanyProfileClient{client})
+
+	for _, expected := range []string{"AAA", "BBB", "CCC", "AAA"} {
+		result, err := service.EnrichPlaceholders(ctx, 1)
+		if err != nil {
+			t.Fatalf("Expected the placeholders to be enriched, got %v", err)
+		}
+		if _, failed := result.Failed[expected]; result.Checked != 1 || (!failed && !slices.Contains(result.Enriched, expected)) {
+			t.Errorf("Expected %s to be checked, got %+v", expected, result)
+		}
+	}
+}
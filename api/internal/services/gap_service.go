@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/calendar"
+	"pocketanalyst/pkg/errors"
+	"sort"
+	"time"
+)
+
+// DateGap is a run of consecutive trading days missing from the stored prices of a source.
+type DateGap struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	MissingDays int       `json:"missing_days"` // Trading days in the gap
+}
+
+// SourceGaps lists the gaps of a single symbol and data source.
+type SourceGaps struct {
+	Symbol      string    `json:"symbol"`
+	Source      string    `json:"source"`
+	Calendar    string    `json:"calendar"` // Trading calendar of the symbol's exchange
+	StoredDays  int       `json:"stored_days"`
+	MissingDays int       `json:"missing_days"`
+	Gaps        []DateGap `json:"gaps"`
+}
+
+// GapReport is the result of checking the stored prices of one or more symbols against the trading calendar.
+type GapReport struct {
+	StartDate time.Time    `json:"start_date"`
+	EndDate   time.Time    `json:"end_date"`
+	Results   []SourceGaps `json:"results"` // Only sources with gaps are listed
+}
+
+// GapService finds trading days missing from stock_prices and schedules re-fetches for them.
+type GapService struct {
+	stockRepo       repositories.StockStore
+	stockService    *StockService
+	backfillService *BackfillService
+}
+
+// NewGapService creates a new GapService. Stored dates are compared against the trading calendar of each
+// symbol's exchange.
+func NewGapService(
+	stockRepo repositories.StockStore,
+	stockService *StockService,
+	backfillService *BackfillService,
+) *GapService {
+	return &GapService{
+		stockRepo:       stockRepo,
+		stockService:    stockService,
+		backfillService: backfillService,
+	}
+}
+
+// FindGaps checks every symbol, or all active companies if symbols is empty, between startDate and endDate.
+// Each source that stored data for a symbol is checked between the first and last day it ever stored, since
+// history outside of that was never loaded rather than lost. A symbol without any stored data is reported as
+// one gap under the configured providers. If sources is not empty, only those sources are checked.
+func (gs *GapService) FindGaps(
+	ctx context.Context,
+	symbols []string,
+	sources []string,
+	startDate, endDate time.Time,
+) (*GapReport, error) {
+	if startDate.IsZero() || endDate.IsZero() || startDate.After(endDate) {
+		return nil, errors.NewModelValidationError("GapService", "date_range", "start date cannot be after end date")
+	}
+
+	if len(symbols) == 0 {
+		var err error
+		if symbols, err = gs.stockService.ActiveSymbols(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &GapReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Results:   []SourceGaps{},
+	}
+
+	for _, symbol := range normalizeSymbols(symbols) {
+		cal := gs.stockService.TradingCalendar(ctx, symbol)
+		tradingDays := calendar.TradingDays(cal, startDate, endDate)
+
+		// The bounds come from the whole history, the window may start or end in the middle of a gap
+		ranges, err := gs.stockRepo.GetStoredDateRanges(ctx, symbol)
+		if err != nil {
+			return nil, errors.NewServiceError("Retrieving stored date ranges", err)
+		}
+		stored, err := gs.stockRepo.GetStoredDates(ctx, symbol, startDate, endDate)
+		if err != nil {
+			return nil, errors.NewServiceError("Retrieving stored dates", err)
+		}
+
+		for _, source := range gapSources(ranges, sources, gs.stockService.Providers()) {
+			result := findSourceGaps(tradingDays, stored[source], ranges[source])
+			if len(result.Gaps) == 0 {
+				continue
+			}
+			result.Symbol = symbol
+			result.Source = source
+			result.Calendar = cal.Name()
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	return report, nil
+}
+
+// RepairGaps finds the gaps like FindGaps and starts a backfill run re-fetching exactly the missing ranges.
+// The run is nil if there is nothing to repair. The gaps are fetched from the configured providers, whichever
+// source they were found in.
+func (gs *GapService) RepairGaps(
+	ctx context.Context,
+	symbols []string,
+	sources []string,
+	startDate, endDate time.Time,
+) (*GapReport, *models.BackfillRun, error) {
+	report, err := gs.FindGaps(ctx, symbols, sources, startDate, endDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Several sources may miss the same days, fetch each symbol's range only once
+	seen := make(map[string]bool)
+	ranges := []*models.BackfillChunk{}
+	for _, result := range report.Results {
+		for _, gap := range result.Gaps {
+			key := result.Symbol + gap.Start.Format("2006-01-02") + gap.End.Format("2006-01-02")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			ranges = append(ranges, &models.BackfillChunk{Symbol: result.Symbol, StartDate: gap.Start, EndDate: gap.End})
+		}
+	}
+	if len(ranges) == 0 {
+		return report, nil, nil
+	}
+
+	run, err := gs.backfillService.StartTargetedBackfill(ctx, ranges)
+	if err != nil {
+		return nil, nil, err
+	}
+	return report, run, nil
+}
+
+// gapSources returns the sources to check: the requested ones, else those with stored data, else the providers.
+func gapSources(stored map[string]repositories.StoredRange, requested, providers []string) []string {
+	if len(requested) > 0 {
+		return requested
+	}
+	if len(stored) == 0 {
+		return providers[:1]
+	}
+
+	sources := make([]string, 0, len(stored))
+	for source := range stored {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// findSourceGaps merges the trading days missing from the stored dates into gaps. Trading days outside of
+// bounds, the first and last date the source ever stored, are not gaps. Without any stored history, all
+// trading days are missing.
+func findSourceGaps(tradingDays []time.Time, storedDates []time.Time, bounds repositories.StoredRange) SourceGaps {
+	result := SourceGaps{StoredDays: len(storedDates), Gaps: []DateGap{}}
+
+	stored := make(map[time.Time]bool, len(storedDates))
+	for _, date := range storedDates {
+		stored[calendar.Date(date)] = true
+	}
+
+	first, last := calendar.Date(bounds.First), calendar.Date(bounds.Last)
+
+	var current *DateGap
+	for _, day := range tradingDays {
+		if day.Before(first) || (!bounds.Last.IsZero() && day.After(last)) {
+			continue
+		}
+		if stored[day] {
+			current = nil
+			continue
+		}
+
+		result.MissingDays++
+		if current == nil {
+			result.Gaps = append(result.Gaps, DateGap{Start: day})
+			current = &result.Gaps[len(result.Gaps)-1]
+		}
+		current.End = day
+		current.MissingDays++
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/calendar"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC)
+}
+
+// TestFindSourceGaps verifies missing trading days are merged into ranges across weekends,
+// and that days before the first stored date are not reported.
+func TestFindSourceGaps(t *testing.T) {
+	// March 2024: the 4th is a Monday, the 9th and 10th are a weekend
+	tradingDays := calendar.TradingDays(calendar.WeekdayCalendar{}, day(1), day(15))
+	stored := []time.Time{day(4), day(5), day(7), day(13)}
+
+	result := findSourceGaps(tradingDays, stored, repositories.StoredRange{First: day(4), Last: day(15)})
+
+	expected := []DateGap{
+		{Start: day(6), End: day(6), MissingDays: 1},
+		{Start: day(8), End: day(12), MissingDays: 3},
+		{Start: day(14), End: day(15), MissingDays: 2},
+	}
+	if len(result.Gaps) != len(expected) {
+		t.Fatalf("Expected %d gaps, got %v", len(expected), result.Gaps)
+	}
+	for i, gap := range expected {
+		if !result.Gaps[i].Start.Equal(gap.Start) || !result.Gaps[i].End.Equal(gap.End) || result.Gaps[i].MissingDays != gap.MissingDays {
+			t.Errorf("Gap %d: expected %v, got %v", i, gap, result.Gaps[i])
+		}
+	}
+	if result.MissingDays != 6 || result.StoredDays != 4 {
+		t.Errorf("Expected 6 missing and 4 stored days, got %d and %d", result.MissingDays, result.StoredDays)
+	}
+}
+
+// TestFindSourceGaps_NothingStored verifies the whole range is one gap when nothing is stored.
+func TestFindSourceGaps_NothingStored(t *testing.T) {
+	tradingDays := calendar.TradingDays(calendar.WeekdayCalendar{}, day(4), day(8))
+
+	result := findSourceGaps(tradingDays, nil, repositories.StoredRange{})
+	if len(result.Gaps) != 1 || result.Gaps[0].MissingDays != 5 {
+		t.Errorf("Expected a single gap of 5 days, got %v", result.Gaps)
+	}
+}
+
+// TestFindSourceGaps_HistoryBounds verifies the bounds come from the whole stored history: a gap starting on the
+// first day of the window is reported, while days after the last stored date are not.
+func TestFindSourceGaps_HistoryBounds(t *testing.T) {
+	tradingDays := calendar.TradingDays(calendar.WeekdayCalendar{}, day(6), day(15))
+	stored := []time.Time{day(7), day(13)}
+
+	result := findSourceGaps(tradingDays, stored, repositories.StoredRange{First: day(1), Last: day(13)})
+
+	expected := []DateGap{
+		{Start: day(6), End: day(6), MissingDays: 1},
+		{Start: day(8), End: day(12), MissingDays: 3},
+	}
+	if len(result.Gaps) != len(expected) {
+		t.Fatalf("Expected %d gaps, got %v", len(expected), result.Gaps)
+	}
+	for i, gap := range expected {
+		if !result.Gaps[i].Start.Equal(gap.Start) || !result.Gaps[i].End.Equal(gap.End) || result.Gaps[i].MissingDays != gap.MissingDays {
+			t.Errorf("Gap %d: expected %v, got %v", i, gap, result.Gaps[i])
+		}
+	}
+}
+
+// TestGapService_FindGaps verifies a source with stored history but no rows in the window is checked, and that
+// the gap starts on the start date.
+func TestGapService_FindGaps(t *testing.T) {
+	ctx := context.Background()
+	db := repositories.NewMemoryDB()
+	dataSources := repositories.NewMemoryDataSourceRepository(db)
+	stockRepo := repositories.NewMemoryStockRepository(db, dataSources)
+	stockService := NewStockService(stockRepo, repositories.NewMemoryCompanyRepository(db),
+		repositories.NewMemoryJobExecutionLogRepository(db), nil, &stubClient{}, 0)
+
+	stocks := []*models.Stock{}
+	for _, d := range []int{1, 4, 11, 12} {
+		stocks = append(stocks, &models.Stock{
+			Symbol:           "TEST",
+			Date:             day(d),
+			OpenPrice:        100,
+			HighPrice:        102,
+			LowPrice:         99,
+			ClosePrice:       101,
+			AdjustedClose:    101,
+			Volume:           1000,
+			SplitCoefficient: 1,
+			DataSource:       "Stub",
+		})
+	}
+	if _, err := stockRepo.SaveStocksToDatabase(ctx, stocks); err != nil {
+		t.Fatalf("Expected the stocks to be saved, got %v", err)
+	}
+
+	report, err := NewGapService(stockRepo, stockService, nil).FindGaps(ctx, []string{"test"}, nil, day(5), day(8))
+	if err != nil {
+		t.Fatalf("Expected the gaps to be found, got %v", err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Source != "Stub" || len(report.Results[0].Gaps) != 1 {
+		t.Fatalf("Expected a single gap of the Stub source, got %+v", report.Results)
+	}
+	if gap := report.Results[0].Gaps[0]; !gap.Start.Equal(day(5)) || !gap.End.Equal(day(8)) || gap.MissingDays != 4 {
+		t.Errorf("Expected the 5th to the 8th to be missing, got %+v", gap)
+	}
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportFormat is the file format accepted by the ImportService.
+type ImportFormat string
+
+const (
+	ImportFormatCSV    ImportFormat = "csv"
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+// maxImportErrors caps the number of row errors kept in an import report.
+const maxImportErrors = 1000
+
+// ImportOptions fill in values that are missing from the imported rows.
+type ImportOptions struct {
+	Symbol string // Used for rows without a symbol, e.g. single-symbol CSV exports
+	Source string // Data source name for rows without a source column
+}
+
+// ImportRowError describes why a single row was not imported. Row numbers start at 1 for the first data row.
+type ImportRowError struct {
+	Row    int    `json:"row"`
+	Symbol string `json:"symbol,omitempty"`
+	Error  string `json:"error"`
+}
+
+// ImportResult reports the outcome of an import, row by row.
+type ImportResult struct {
+	RowsRead        int              `json:"rows_read"`
+	RowsImported    int              `json:"rows_imported"`
+	RowsFailed      int              `json:"rows_failed"`
+	Inserted        int              `json:"inserted"`
+	Updated         int              `json:"updated"`
+	Unchanged       int              `json:"unchanged"`
+	Errors          []ImportRowError `json:"errors"`
+	ErrorsTruncated bool             `json:"errors_truncated"`
+}
+
+// importRow is a parsed row waiting to be stored, remembering its row number for error reporting.
+type importRow struct {
+	row   int
+	stock *models.Stock
+}
+
+// ImportService loads OHLCV rows we already own into the database without going through a provider.
+type ImportService struct {
+	stockRepo repositories.StockStore
+	chunkSize int
+}
+
+// NewImportService creates a new ImportService that stores rows in chunks of chunkSize.
+func NewImportService(stockRepo repositories.StockStore, chunkSize int) *ImportService {
+	if chunkSize <= 0 {
+		chunkSize = 500
+	}
+
+	return &ImportService{
+		stockRepo: stockRepo,
+		chunkSize: chunkSize,
+	}
+}
+
+// Import streams rows from r into the database in chunks. Rows that fail to parse, fail models.Stock.Validate
+// or are rejected by the repository are listed in the result instead of failing the whole import.
+// An error is only returned if the input cannot be read at all or ctx is cancelled.
+//
+// Both formats use the columns symbol, date (YYYY-MM-DD), open, high, low, close, adj_close, volume,
+// dividend_amount, split_coefficient and source. Yahoo's "Adj Close" header is accepted for adj_close.
+func (is *ImportService) Import(ctx context.Context, r io.Reader, format ImportFormat, options ImportOptions) (*ImportResult, error) {
+	result := &ImportResult{Errors: []ImportRowError{}}
+	chunk := make([]importRow, 0, is.chunkSize)
+
+	// handleRow validates a parsed row and flushes the chunk once it is full
+	handleRow := func(row int, stock *models.Stock, parseErr error) error {
+		result.RowsRead++
+		if parseErr != nil {
+			result.addError(row, "", parseErr)
+			return nil
+		}
+
+		is.applyDefaults(stock, options)
+		if err := stock.Validate(); err != nil {
+			result.addError(row, stock.Symbol, err)
+			return nil
+		}
+
+		chunk = append(chunk, importRow{row: row, stock: stock})
+		if len(chunk) >= is.chunkSize {
+			if err := is.flush(ctx, chunk, result); err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+		return nil
+	}
+
+	var err error
+	switch format {
+	case ImportFormatCSV:
+		err = readCSVRows(r, handleRow)
+	case ImportFormatNDJSON:
+		err = readNDJSONRows(r, handleRow)
+	default:
+		return nil, errors.NewModelValidationError("ImportService", "format",
+			fmt.Sprintf("unsupported format %q, expected csv or ndjson", format))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Store whatever is left in the last partial chunk
+	if err := is.flush(ctx, chunk, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// applyDefaults fills in the symbol and source from the options and normalizes the symbol.
+func (is *ImportService) applyDefaults(stock *models.Stock, options ImportOptions) {
+	if stock.Symbol == "" {
+		stock.Symbol = options.Symbol
+	}
+	stock.Symbol = strings.ToUpper(strings.TrimSpace(stock.Symbol))
+
+	if stock.DataSource == "" {
+		stock.DataSource = options.Source
+	}
+	if stock.SplitCoefficient == 0 {
+		stock.SplitCoefficient = 1
+	}
+	if stock.AdjustedClose == 0 {
+		stock.AdjustedClose = stock.ClosePrice
+	}
+	stock.LastUpdated = time.Now()
+}
+
+// flush stores a chunk in a single transaction. If the repository rejects the chunk, its rows are stored
+// one at a time so that only the offending rows are reported.
+func (is *ImportService) flush(ctx context.Context, chunk []importRow, result *ImportResult) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+
+	stocks := make([]*models.Stock, len(chunk))
+	for i, row := range chunk {
+		stocks[i] = row.stock
+	}
+
+	saved, err := is.stockRepo.SaveStocksToDatabase(ctx, stocks)
+	if err == nil {
+		result.addSaved(saved, len(chunk))
+		return nil
+	}
+	if ctx.Err() != nil {
+		return errors.NewServiceError("Importing stock data", ctx.Err())
+	}
+
+	for _, row := range chunk {
+		saved, err := is.stockRepo.SaveStocksToDatabase(ctx, []*models.Stock{row.stock})
+		if err != nil {
+			if ctx.Err() != nil {
+				return errors.NewServiceError("Importing stock data", ctx.Err())
+			}
+			result.addError(row.row, row.stock.Symbol, err)
+			continue
+		}
+		result.addSaved(saved, 1)
+	}
+	return nil
+}
+
+// addError records a failed row, keeping at most maxImportErrors of them.
+func (r *ImportResult) addError(row int, symbol string, err error) {
+	r.RowsFailed++
+	if len(r.Errors) >= maxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, ImportRowError{Row: row, Symbol: symbol, Error: err.Error()})
+}
+
+// addSaved adds the counts of a successfully stored chunk.
+func (r *ImportResult) addSaved(saved *repositories.SaveResult, rows int) {
+	r.RowsImported += rows
+	r.Inserted += saved.Inserted
+	r.Updated += saved.Updated
+	r.Unchanged += saved.Unchanged
+}
+
+// readCSVRows parses CSV rows, matching columns by their header name.
+func readCSVRows(r io.Reader, handle func(row int, stock *models.Stock, err error) error) error {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return errors.NewModelValidationError("ImportService", "header", fmt.Sprintf("could not read CSV header: %v", err))
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[normalizeImportColumn(name)] = i
+	}
+	if _, ok := columns["date"]; !ok {
+		return errors.NewModelValidationError("ImportService", "header", "CSV header must contain a date column")
+	}
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// A malformed line only affects that row
+			if err := handle(row, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		values := make(map[string]string, len(columns))
+		for name, index := range columns {
+			if index < len(record) {
+				values[name] = strings.TrimSpace(record[index])
+			}
+		}
+
+		stock, parseErr := stockFromImportValues(values)
+		if err := handle(row, stock, parseErr); err != nil {
+			return err
+		}
+	}
+}
+
+// readNDJSONRows parses one JSON object per line. Blank lines are skipped.
+func readNDJSONRows(r io.Reader, handle func(row int, stock *models.Stock, err error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	row := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row++
+
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			if err := handle(row, nil, fmt.Errorf("invalid JSON: %w", err)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		values := make(map[string]string, len(raw))
+		for key, value := range raw {
+			switch v := value.(type) {
+			case string:
+				values[normalizeImportColumn(key)] = v
+			case float64:
+				values[normalizeImportColumn(key)] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+
+		stock, parseErr := stockFromImportValues(values)
+		if err := handle(row, stock, parseErr); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.NewServiceError("Reading NDJSON import", err)
+	}
+	return nil
+}
+
+// normalizeImportColumn maps column names onto the canonical import names, e.g. "Adj Close" -> "adj_close".
+func normalizeImportColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	name = strings.ReplaceAll(name, " ", "_")
+
+	switch name {
+	case "adjusted_close":
+		return "adj_close"
+	case "ticker":
+		return "symbol"
+	case "data_source":
+		return "source"
+	}
+	return name
+}
+
+// stockFromImportValues builds a Stock from the normalized column values of a single row.
+func stockFromImportValues(values map[string]string) (*models.Stock, error) {
+	date, err := time.Parse("2006-01-02", values["date"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", values["date"])
+	}
+
+	stock := &models.Stock{
+		Symbol:     values["symbol"],
+		Date:       date,
+		DataSource: values["source"],
+	}
+
+	fields := []struct {
+		name   string
+		target *float64
+	}{
+		{"open", &stock.OpenPrice},
+		{"high", &stock.HighPrice},
+		{"low", &stock.LowPrice},
+		{"close", &stock.ClosePrice},
+		{"adj_close", &stock.AdjustedClose},
+		{"volume", &stock.Volume},
+		{"dividend_amount", &stock.DividendAmount},
+		{"split_coefficient", &stock.SplitCoefficient},
+	}
+	for _, field := range fields {
+		value := values[field.name]
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", field.name, value)
+		}
+		*field.target = parsed
+	}
+
+	if stock.ClosePrice == 0 {
+		return nil, fmt.Errorf("close is required")
+	}
+	return stock, nil
+}
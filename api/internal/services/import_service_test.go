@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// chunkRecorder is a StockStore that remembers the size of every batch it is asked to save.
+type chunkRecorder struct {
+	repositories.StockStore
+	chunks []int
+}
+
+func (r *chunkRecorder) SaveStocksToDatabase(ctx context.Context, stocks []*models.Stock) (*repositories.SaveResult, error) {
+	r.chunks = append(r.chunks, len(stocks))
+	return r.StockStore.SaveStocksToDatabase(ctx, stocks)
+}
+
+// newImportService creates an ImportService on top of the in-memory repositories, which hold the inactive
+// data source Retired.
+func newImportService(t *testing.T, chunkSize int) (*ImportService, *chunkRecorder) {
+	db := repositories.NewMemoryDB()
+	dataSources := repositories.NewMemoryDataSourceRepository(db)
+	if _, err := dataSources.Create(context.Background(), &models.DataSource{SourceName: "Retired", SourceType: "PRICE"}); err != nil {
+		t.Fatalf("Expected the data source to be created, got %v", err)
+	}
+
+	recorder := &chunkRecorder{StockStore: repositories.NewMemoryStockRepository(db, dataSources)}
+	return NewImportService(recorder, chunkSize), recorder
+}
+
+// TestImportService_CSV verifies columns are matched by their header, Yahoo's headers included, and that
+// missing values are filled in from the options.
+func TestImportService_CSV(t *testing.T) {
+	ctx := context.Background()
+	service, recorder := newImportService(t, 0)
+
+	input := "\ufeffDate,Open,High,Low,Close,Adj Close,Volume\n" +
+		"2024-03-04,100,102,99,101,50.5,1000\n" +
+		"2024-03-05, 101, 103, 100, 102, , 1200\n"
+	result, err := service.Import(ctx, strings.NewReader(input), ImportFormatCSV, ImportOptions{Symbol: "test", Source: "Yahoo"})
+	if err != nil {
+		t.Fatalf("Expected the import to succeed, got %v", err)
+	}
+	if result.RowsRead != 2 || result.RowsImported != 2 || result.RowsFailed != 0 || result.Inserted != 2 {
+		t.Errorf("Expected 2 imported rows, got %+v", result)
+	}
+
+	stocks, err := recorder.RetrieveStocksFromDatabase(ctx, "TEST", day(1), day(31))
+	if err != nil || len(stocks) != 2 {
+		t.Fatalf("Expected 2 stored rows of TEST, got %v, %v", stocks, err)
+	}
+	for _, stock := range stocks {
+		if stock.DataSource != "Yahoo" || stock.SplitCoefficient != 1 {
+			t.Errorf("Expected a Yahoo row without a split, got %+v", stock)
+		}
+		if stock.Date.Equal(day(4)) && stock.AdjustedClose != 50.5 {
+			t.Errorf("Expected the adjusted close of the 4th to be imported, got %v", stock.AdjustedClose)
+		}
+		if stock.Date.Equal(day(5)) && stock.AdjustedClose != 102 {
+			t.Errorf("Expected the adjusted close of the 5th to default to the close, got %v", stock.AdjustedClose)
+		}
+	}
+
+	// Importing the same rows again changes nothing
+	result, err = service.Import(ctx, strings.NewReader(input), ImportFormatCSV, ImportOptions{Symbol: "TEST", Source: "Yahoo"})
+	if err != nil || result.Unchanged != 2 || result.Inserted != 0 {
+		t.Errorf("Expected 2 unchanged rows, got %+v, %v", result, err)
+	}
+
+	var validationErr *errors.ModelValidationError
+	_, err = service.Import(ctx, strings.NewReader("symbol,close\nTEST,101\n"), ImportFormatCSV, ImportOptions{})
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ModelValidationError for a header without a date, got %v", err)
+	}
+	_, err = service.Import(ctx, strings.NewReader(input), "xlsx", ImportOptions{})
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ModelValidationError for an unsupported format, got %v", err)
+	}
+}
+
+// TestImportService_NDJSON verifies numbers and numeric strings are accepted and blank lines are skipped.
+func TestImportService_NDJSON(t *testing.T) {
+	ctx := context.Background()
+	service, recorder := newImportService(t, 0)
+
+	input := `{"ticker": "msft", "date": "2024-03-04", "open": 400, "high": 410, "low": 395, "close": "405.5", "volume": 2000, "data_source": "Manual"}` +
+		"\n\n" +
+		`{"symbol": "MSFT", "date": "2024-03-05", "open": 405, "high": 412, "low": 401, "close": 410, "adjusted_close": 409, "volume": 2100}` +
+		"\n"
+	result, err := service.Import(ctx, strings.NewReader(input), ImportFormatNDJSON, ImportOptions{Source: "Fallback"})
+	if err != nil {
+		t.Fatalf("Expected the import to succeed, got %v", err)
+	}
+	if result.RowsRead != 2 || result.RowsImported != 2 || result.RowsFailed != 0 {
+		t.Errorf("Expected 2 imported rows, got %+v", result)
+	}
+
+	stocks, err := recorder.RetrieveStocksFromDatabase(ctx, "MSFT", day(1), day(31))
+	if err != nil || len(stocks) != 2 {
+		t.Fatalf("Expected 2 stored rows of MSFT, got %v, %v", stocks, err)
+	}
+	sources := map[string]*models.Stock{}
+	for _, stock := range stocks {
+		sources[stock.DataSource] = stock
+	}
+	if manual := sources["Manual"]; manual == nil || manual.ClosePrice != 405.5 || manual.Volume != 2000 {
+		t.Errorf("Expected the first row from Manual closing at 405.5, got %+v", manual)
+	}
+	if fallback := sources["Fallback"]; fallback == nil || fallback.AdjustedClose != 409 {
+		t.Errorf("Expected the second row from the default source with its adjusted close, got %+v", fallback)
+	}
+}
+
+// TestImportService_RowErrors verifies rows that fail to parse, validate or store are reported by row number
+// without failing the import.
+func TestImportService_RowErrors(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newImportService(t, 0)
+
+	input := "symbol,date,open,high,low,close,volume,source\n" +
+		"TEST,2024-03-04,100,102,99,101,1000,\n" + // 1: fine
+		"TEST,03/05/2024,100,102,99,101,1000,\n" + // 2: invalid date
+		"TEST,2024-03-06,100,abc,99,101,1000,\n" + // 3: invalid high
+		"TEST,2024-03-07,100,102,99,,1000,\n" + // 4: no close
+		"TEST,2024-03-08,100,98,99,101,1000,\n" + // 5: high below low
+		"TEST,2024-03-11,100,102,99,101,1000,Retired\n" + // 6: inactive source
+		"\"TEST,2024-03-12\n" // 7: malformed
+	result, err := service.Import(ctx, strings.NewReader(input), ImportFormatCSV, ImportOptions{Source: "Manual"})
+	if err != nil {
+		t.Fatalf("Expected the import to succeed, got %v", err)
+	}
+	if result.RowsRead != 7 || result.RowsImported != 1 || result.RowsFailed != 6 || result.Inserted != 1 {
+		t.Errorf("Expected 1 imported and 6 failed rows, got %+v", result)
+	}
+
+	rows := make([]int, len(result.Errors))
+	for i, rowErr := range result.Errors {
+		rows[i] = rowErr.Row
+		if rowErr.Error == "" {
+			t.Errorf("Expected a reason for row %d", rowErr.Row)
+		}
+	}
+	slices.Sort(rows)
+	if expected := []int{2, 3, 4, 5, 6, 7}; !slices.Equal(rows, expected) {
+		t.Errorf("Expected errors for rows %v, got %v", expected, rows)
+	}
+
+	result, err = service.Import(ctx, strings.NewReader("{\"date\": \"2024-03-04\", \"close\": 1}\nnot json\n"),
+		ImportFormatNDJSON, ImportOptions{Symbol: "TEST", Source: "Manual"})
+	if err != nil || result.RowsImported != 1 || len(result.Errors) != 1 || result.Errors[0].Row != 2 {
+		t.Errorf("Expected the invalid JSON on row 2 to be reported, got %+v, %v", result, err)
+	}
+}
+
+// TestImportService_Chunks verifies rows are stored in chunks of the configured size, and that a rejected
+// chunk is retried row by row so its valid rows are still imported.
+func TestImportService_Chunks(t *testing.T) {
+	ctx := context.Background()
+	service, recorder := newImportService(t, 2)
+
+	var input strings.Builder
+	input.WriteString("symbol,date,open,high,low,close,volume,source\n")
+	for _, line := range []string{
+		"TEST,2024-03-04,100,102,99,101,1000,Manual",
+		"TEST,2024-03-05,100,102,99,101,1000,Manual",
+		"TEST,2024-03-06,100,102,99,101,1000,Retired",
+		"TEST,2024-03-07,100,102,99,101,1000,Manual",
+		"TEST,2024-03-08,100,102,99,101,1000,Manual",
+	} {
+		input.WriteString(line + "\n")
+	}
+
+	result, err := service.Import(ctx, strings.NewReader(input.String()), ImportFormatCSV, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected the import to succeed, got %v", err)
+	}
+	if result.RowsImported != 4 || result.RowsFailed != 1 || result.Inserted != 4 {
+		t.Errorf("Expected 4 imported and 1 failed row, got %+v", result)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 3 || result.Errors[0].Symbol != "TEST" {
+		t.Errorf("Expected row 3 to be reported, got %+v", result.Errors)
+	}
+
+	// The chunk with the retired source is retried one row at a time
+	if expected := []int{2, 2, 1, 1, 1}; !slices.Equal(recorder.chunks, expected) {
+		t.Errorf("Expected chunks of %v, got %v", expected, recorder.chunks)
+	}
+}
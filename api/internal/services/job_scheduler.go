@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchedulerConfig controls how often the JobScheduler looks for due jobs and how long they may run.
+type SchedulerConfig struct {
+	PollInterval time.Duration // How often due jobs are claimed
+	BatchSize    int           // Maximum number of jobs claimed per poll
+	JobTimeout   time.Duration // Maximum run time of a single job
+	StaleAfter   time.Duration // RUNNING jobs older than this are assumed abandoned and claimed again
+}
+
+// JobScheduler runs the jobs in data_fetch_jobs when they are due. Jobs are claimed through the repository
+// with row locks, so several server instances can run a scheduler against the same database.
+type JobScheduler struct {
+	jobRepo        repositories.DataFetchJobStore
+	dataSourceRepo repositories.DataSourceStore
+	stockService   *StockService
+	gapService     *GapService
+	adjustments    *AdjustmentService
+	companies      *CompanyService
+	config         SchedulerConfig
+
+	wake   chan struct{} // Signals that jobs became due before the next poll
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// NewJobScheduler creates a new JobScheduler that dispatches price jobs to stockService, gap repair
+// jobs to gapService, corporate action jobs to adjustments and company profile jobs to companies.
+// The provider of a price job is found among the data sources in dataSourceRepo.
+func NewJobScheduler(
+	jobRepo repositories.DataFetchJobStore,
+	dataSourceRepo repositories.DataSourceStore,
+	stockService *StockService,
+	gapService *GapService,
+	adjustments *AdjustmentService,
+	companies *CompanyService,
+	config SchedulerConfig,
+) *JobScheduler {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 10
+	}
+	if config.JobTimeout <= 0 {
+		config.JobTimeout = 10 * time.Minute
+	}
+	if config.StaleAfter < config.JobTimeout {
+		config.StaleAfter = 2 * config.JobTimeout
+	}
+
+	return &JobScheduler{
+		jobRepo:        jobRepo,
+		dataSourceRepo: dataSourceRepo,
+		stockService:   stockService,
+		gapService:     gapService,
+		adjustments:    adjustments,
+		companies:      companies,
+		config:         config,
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// Start begins polling for due jobs in the background. Calling Start on a running scheduler does nothing.
+func (js *JobScheduler) Start() {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	js.cancel = cancel
+	js.done = make(chan struct{})
+
+	go js.run(ctx, js.done)
+	log.Printf("Job scheduler started, polling every %v", js.config.PollInterval)
+}
+
+// Stop cancels the running jobs and waits for the scheduler to exit.
+func (js *JobScheduler) Stop() {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.cancel == nil {
+		return
+	}
+
+	js.cancel()
+	<-js.done
+	js.cancel = nil
+	log.Println("Job scheduler stopped")
+}
+
+// Wake makes a running scheduler poll for due jobs right away instead of waiting for the next tick.
+// It never blocks, and does nothing if the scheduler is not running in this instance.
+func (js *JobScheduler) Wake() {
+	select {
+	case js.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Running reports whether the scheduler was started in this instance.
+func (js *JobScheduler) Running() bool {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	return js.cancel != nil
+}
+
+// run polls for due jobs until ctx is cancelled.
+func (js *JobScheduler) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(js.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep claiming while full batches come back, so a backlog is worked off without waiting for the ticker
+		for js.poll(ctx) == js.config.BatchSize && ctx.Err() == nil {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-js.wake:
+		}
+	}
+}
+
+// poll claims a batch of due jobs and runs them one after another. It returns how many jobs were claimed.
+func (js *JobScheduler) poll(ctx context.Context) int {
+	jobs, err := js.jobRepo.ClaimDueJobs(ctx, js.config.BatchSize, js.config.StaleAfter)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Job scheduler failed to claim jobs: %v", err)
+		}
+		return 0
+	}
+
+	for _, job := range jobs {
+		js.runJob(ctx, job)
+	}
+	return len(jobs)
+}
+
+// runJob executes a claimed job and records its outcome and next run.
+func (js *JobScheduler) runJob(ctx context.Context, job *models.DataFetchJob) {
+	jobCtx, cancel := context.WithTimeout(ctx, js.config.JobTimeout)
+	defer cancel()
+
+	status := models.JobStatusSuccess
+	if err := js.dispatch(jobCtx, job); err != nil {
+		status = models.JobStatusFailed
+		log.Printf("Job %d (%s %s %s) failed: %v", job.JobID, job.DataType, job.EntityType, job.EntityValue, err)
+	}
+
+	// A job interrupted by shutdown stays RUNNING with its schedule untouched, so it is picked up again
+	// once it goes stale
+	if ctx.Err() != nil {
+		return
+	}
+
+	next, err := nextScheduledRun(job, time.Now().UTC())
+	if err != nil {
+		// Unknown frequency, don't run the job again until it is fixed
+		log.Printf("Job %d cannot be rescheduled: %v", job.JobID, err)
+		status = models.JobStatusFailed
+		next = time.Now().UTC().AddDate(100, 0, 0)
+	}
+
+	if err := js.jobRepo.FinishJob(ctx, job.JobID, status, next); err != nil {
+		log.Printf("Job scheduler failed to finish job %d: %v", job.JobID, err)
+	}
+}
+
+// dispatch hands the job to the service responsible for its data type.
+//
+// PRICE jobs synchronize the SYMBOL in entity_value, fetching from the provider of the job's data source first.
+// The other job types use every provider that supports them, their data source is ignored. GAP_REPAIR jobs look
+// for gaps in the last lookback_days (a job parameter, 30 by default) of the SYMBOL in entity_value, or of all
+// active companies for a MARKET entity, and schedule a backfill of the missing ranges. CORPORATE_ACTIONS jobs
+// fetch the splits and dividends of the SYMBOL in entity_value and re-adjust its prices. COMPANY_PROFILE jobs
+// fetch the profile of the SYMBOL in entity_value, or of up to limit (a job parameter, 50 by default) placeholder
+// companies for a MARKET entity.
+func (js *JobScheduler) dispatch(ctx context.Context, job *models.DataFetchJob) error {
+	switch {
+	case strings.EqualFold(job.DataType, models.JobDataTypeGapRepair):
+		return js.repairGaps(ctx, job)
+	case strings.EqualFold(job.DataType, models.JobDataTypeCorporateActions) &&
+		strings.EqualFold(job.EntityType, models.JobEntitySymbol):
+		result, err := js.adjustments.SyncCorporateActions(ctx, job.EntityValue, time.Time{}, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		log.Printf("Job %d fetched %d corporate actions of %s from %s, %d prices re-adjusted",
+			job.JobID, result.Actions, strings.ToUpper(job.EntityValue), result.Provider, result.PricesUpdated)
+		return nil
+	case strings.EqualFold(job.DataType, models.JobDataTypeCompanyProfile):
+		return js.enrichCompanies(ctx, job)
+	case strings.EqualFold(job.DataType, models.JobDataTypePrice) && strings.EqualFold(job.EntityType, models.JobEntitySymbol):
+		provider, err := js.jobProvider(ctx, job)
+		if err != nil {
+			return err
+		}
+		result, err := js.stockService.SynchronizeStockDataForJob(ctx, job.JobID, strings.ToUpper(job.EntityValue), provider)
+		if err != nil {
+			return err
+		}
+		log.Printf("Job %d synchronized %s: %d fetched, %d inserted, %d updated",
+			job.JobID, result.Symbol, result.Fetched, result.Inserted, result.Updated)
+		return nil
+	default:
+		return fmt.Errorf("unsupported job: data type %s for entity type %s", job.DataType, job.EntityType)
+	}
+}
+
+// jobProvider returns the name of the configured provider behind the job's data source. Jobs of a data source
+// that is not a configured provider fail, as nothing could fetch from it.
+func (js *JobScheduler) jobProvider(ctx context.Context, job *models.DataFetchJob) (string, error) {
+	for _, provider := range js.stockService.Providers() {
+		ds, err := js.dataSourceRepo.GetByName(ctx, provider)
+		var notFound *errors.NotFoundError
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve data source of provider %s: %w", provider, err)
+		}
+		if ds.SourceID == job.SourceID {
+			return provider, nil
+		}
+	}
+	return "", fmt.Errorf("data source %d is not one of the configured providers %s",
+		job.SourceID, strings.Join(js.stockService.Providers(), ", "))
+}
+
+// repairGaps runs a GAP_REPAIR job.
+func (js *JobScheduler) repairGaps(ctx context.Context, job *models.DataFetchJob) error {
+	lookbackDays := 30
+	if days, ok := job.Parameters["lookback_days"].(float64); ok && days > 0 {
+		lookbackDays = int(days)
+	}
+
+	var symbols []string
+	switch {
+	case strings.EqualFold(job.EntityType, models.JobEntitySymbol):
+		symbols = []string{job.EntityValue}
+	case strings.EqualFold(job.EntityType, models.JobEntityMarket):
+		// Empty means all active companies
+	default:
+		return fmt.Errorf("unsupported job: data type %s for entity type %s", job.DataType, job.EntityType)
+	}
+
+	// Today's session may not be over yet
+	endDate := time.Now().UTC().AddDate(0, 0, -1)
+	report, run, err := js.gapService.RepairGaps(ctx, symbols, nil, endDate.AddDate(0, 0, -lookbackDays), endDate)
+	if err != nil {
+		return err
+	}
+
+	if run != nil {
+		log.Printf("Job %d found gaps in %d symbol sources, backfill run %d repairs them", job.JobID, len(report.Results), run.RunID)
+	}
+	return nil
+}
+
+// enrichCompanies runs a COMPANY_PROFILE job.
+func (js *JobScheduler) enrichCompanies(ctx context.Context, job *models.DataFetchJob) error {
+	switch {
+	case strings.EqualFold(job.EntityType, models.JobEntitySymbol):
+		company, err := js.companies.EnrichCompany(ctx, job.EntityValue)
+		if err != nil {
+			return err
+		}
+		log.Printf("Job %d stored the profile of %s from %s", job.JobID, company.Symbol, company.ProfileSource)
+		return nil
+	case strings.EqualFold(job.EntityType, models.JobEntityMarket):
+		limit := 0
+		if value, ok := job.Parameters["limit"].(float64); ok && value > 0 {
+			limit = int(value)
+		}
+
+		result, err := js.companies.EnrichPlaceholders(ctx, limit)
+		if err != nil {
+			return err
+		}
+		log.Printf("Job %d enriched %d of %d placeholder companies", job.JobID, len(result.Enriched), result.Checked)
+		return nil
+	default:
+		return fmt.Errorf("unsupported job: data type %s for entity type %s", job.DataType, job.EntityType)
+	}
+}
+
+// nextScheduledRun steps the job's schedule forward by its frequency until it lies after now, so a job that
+// was missed several times (e.g. while the server was down) runs once instead of catching up on every run.
+// A job run on request before it was due keeps its next run. The zero time is returned for jobs that only
+// run once, even if they ran on request.
+func nextScheduledRun(job *models.DataFetchJob, now time.Time) (time.Time, error) {
+	if job.Frequency == models.FrequencyOnce {
+		return time.Time{}, nil
+	}
+
+	next := job.NextScheduled
+	if next.IsZero() {
+		next = now
+	}
+
+	for !next.After(now) {
+		var err error
+		if next, err = job.NextRunAfter(next); err != nil || next.IsZero() {
+			return time.Time{}, err
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/clients"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestNextScheduledRun verifies missed runs are skipped rather than caught up on one by one.
+func TestNextScheduledRun(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		frequency string
+		scheduled time.Time
+		expected  time.Time
+	}{
+		{models.FrequencyDaily, time.Date(2024, 3, 15, 6, 0, 0, 0, time.UTC), time.Date(2024, 3, 16, 6, 0, 0, 0, time.UTC)},
+		{models.FrequencyDaily, time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC), time.Date(2024, 3, 16, 6, 0, 0, 0, time.UTC)},
+		{models.FrequencyHourly, time.Date(2024, 3, 15, 11, 30, 0, 0, time.UTC), time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)},
+		{models.FrequencyWeekly, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 22, 0, 0, 0, 0, time.UTC)},
+		{models.FrequencyMonthly, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		job := &models.DataFetchJob{Frequency: test.frequency, NextScheduled: test.scheduled}
+		next, err := nextScheduledRun(job, now)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", test.frequency, err)
+		}
+		if !next.Equal(test.expected) {
+			t.Errorf("Expected %s job scheduled for %v to run next at %v, got %v",
+				test.frequency, test.scheduled, test.expected, next)
+		}
+	}
+
+	if _, err := nextScheduledRun(&models.DataFetchJob{Frequency: "yearly", NextScheduled: now}, now); err == nil {
+		t.Error("Expected an error for an unknown frequency")
+	}
+}
+
+// TestNextScheduledRun_Once verifies jobs that only run once are not rescheduled.
+func TestNextScheduledRun_Once(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	next, err := nextScheduledRun(&models.DataFetchJob{Frequency: models.FrequencyOnce, NextScheduled: now}, now)
+	if err != nil || !next.IsZero() {
+		t.Errorf("Expected the zero time without an error, got %v, %v", next, err)
+	}
+}
+
+// namedClient is a stubClient of a named provider.
+type namedClient struct {
+	*stubClient
+	name string
+}
+
+func (c namedClient) GetProviderName() string {
+	return c.name
+}
+
+// TestJobScheduler_DispatchPriceJob verifies price jobs fetch from the provider of their data source first,
+// and that jobs of a data source no provider serves fail.
+func TestJobScheduler_DispatchPriceJob(t *testing.T) {
+	ctx := context.Background()
+	db := repositories.NewMemoryDB()
+	dataSources := repositories.NewMemoryDataSourceRepository(db)
+	sources := map[string]int{}
+	for _, name := range []string{"FMP", "CSV", "Manual"} {
+		ds, err := dataSources.Create(ctx, &models.DataSource{SourceName: name, SourceType: "PRICE", IsActive: true})
+		if err != nil {
+			t.Fatalf("Expected the data source to be created, got %v", err)
+		}
+		sources[name] = ds.SourceID
+	}
+
+	fmp := namedClient{newBatchClient("IBM"), "FMP"}
+	csv := namedClient{newBatchClient("IBM"), "CSV"}
+	stockService := NewStockService(
+		repositories.NewMemoryStockRepository(db, dataSources),
+		repositories.NewMemoryCompanyRepository(db),
+		repositories.NewMemoryJobExecutionLogRepository(db),
+		nil,
+		clients.NewFailoverClient(fmp, csv),
+		0,
+	)
+	scheduler := NewJobScheduler(repositories.NewMemoryDataFetchJobRepository(db), dataSources, stockService,
+		nil, nil, nil, SchedulerConfig{})
+
+	job := &models.DataFetchJob{SourceID: sources["CSV"], EntityType: "SYMBOL", EntityValue: "ibm", DataType: "PRICE"}
+	if err := scheduler.dispatch(ctx, job); err != nil {
+		t.Fatalf("Expected the job to succeed, got %v", err)
+	}
+	if !slices.Equal(csv.requested, []string{"IBM"}) || len(fmp.requested) != 0 {
+		t.Errorf("Expected only CSV to be asked, got CSV %v and FMP %v", csv.requested, fmp.requested)
+	}
+
+	job.SourceID = sources["Manual"]
+	if err := scheduler.dispatch(ctx, job); err == nil {
+		t.Error("Expected an error for a data source without a provider")
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"strings"
+	"time"
+)
+
+// maxJobLogs caps the number of execution logs returned for a job.
+const maxJobLogs = 500
+
+// JobService handles business logic related to data fetch jobs and their execution history
+type JobService struct {
+	jobRepo        repositories.DataFetchJobStore
+	logRepo        repositories.JobExecutionLogStore
+	dataSourceRepo repositories.DataSourceStore
+	stockService   *StockService
+	scheduler      *JobScheduler
+}
+
+// NewJobService creates a new JobService. Work enqueued through it is run by the scheduler, which is
+// woken up so it doesn't wait for its next poll.
+func NewJobService(
+	jobRepo repositories.DataFetchJobStore,
+	logRepo repositories.JobExecutionLogStore,
+	dataSourceRepo repositories.DataSourceStore,
+	stockService *StockService,
+	scheduler *JobScheduler,
+) *JobService {
+	return &JobService{
+		jobRepo:        jobRepo,
+		logRepo:        logRepo,
+		dataSourceRepo: dataSourceRepo,
+		stockService:   stockService,
+		scheduler:      scheduler,
+	}
+}
+
+// JobStatus is a job together with its most recent execution, which reports the progress, record
+// counts and error of the current or last run.
+type JobStatus struct {
+	*models.DataFetchJob
+	LatestExecution *models.JobExecutionLog `json:"latest_execution"` // nil if the job never ran
+}
+
+// GetJobStatus returns a job and its most recent execution.
+func (js *JobService) GetJobStatus(ctx context.Context, jobID int) (*JobStatus, error) {
+	job, err := js.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := js.logRepo.ListByJob(ctx, jobID, 1)
+	if err != nil {
+		return nil, errors.NewServiceError("Retrieving job execution logs", err)
+	}
+
+	status := &JobStatus{DataFetchJob: job}
+	if len(logs) > 0 {
+		status.LatestExecution = logs[0]
+	}
+	return status, nil
+}
+
+// EnqueueStockFetch schedules a synchronization of symbol to run in the background and returns the job
+// running it. The job belongs to the preferred provider's data source. An existing job for the symbol is
+// run now, unless it is already running, otherwise a job that only runs once is created. An UnavailableError
+// is returned if the scheduler doesn't run in this instance, as nothing would pick the job up.
+func (js *JobService) EnqueueStockFetch(ctx context.Context, symbol string) (*models.DataFetchJob, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, errors.NewModelValidationError("JobService", "symbol", "symbol cannot be empty")
+	}
+	if !js.scheduler.Running() {
+		return nil, errors.NewUnavailableError("Background fetching", "the job scheduler is disabled, fetch synchronously instead")
+	}
+
+	ds, err := js.dataSourceRepo.GetByName(ctx, js.stockService.Providers()[0])
+	if err != nil {
+		return nil, serviceError("Resolving data source", err)
+	}
+
+	job, err := js.enqueueJob(ctx, &models.DataFetchJob{
+		SourceID:      ds.SourceID,
+		EntityType:    models.JobEntitySymbol,
+		EntityValue:   symbol,
+		DataType:      models.JobDataTypePrice,
+		Frequency:     models.FrequencyOnce,
+		NextScheduled: time.Now().UTC(),
+		Status:        models.JobStatusPending,
+		IsActive:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	js.scheduler.Wake()
+	return job, nil
+}
+
+// enqueueJob makes the job matching template due now, creating it from template if it doesn't exist yet.
+func (js *JobService) enqueueJob(ctx context.Context, template *models.DataFetchJob) (*models.DataFetchJob, error) {
+	existing, err := js.jobRepo.FindByEntity(ctx, template.SourceID, template.EntityType, template.EntityValue, template.DataType)
+	if err != nil {
+		return nil, errors.NewServiceError("Looking up job", err)
+	}
+
+	if existing == nil {
+		created, err := js.jobRepo.Create(ctx, template)
+		var conflict *errors.ConflictError
+		if errors.As(err, &conflict) {
+			// A concurrent request created the job first, enqueue that one instead
+			return js.enqueueJob(ctx, template)
+		}
+		if err != nil {
+			return nil, serviceError("Creating job", err)
+		}
+		return created, nil
+	}
+
+	switch {
+	case existing.IsActive && existing.Status == models.JobStatusRunning:
+		// Already being worked on, report that run
+		return existing, nil
+	case existing.Frequency == models.FrequencyOnce:
+		// One-off jobs are deactivated after they ran, so they need to be reactivated. The run is requested
+		// first, which resets the outcome of the last run, so the scheduler can't claim the job in between.
+		scheduled, err := js.jobRepo.ScheduleNow(ctx, existing.JobID)
+		if err != nil {
+			return nil, serviceError("Scheduling job", err)
+		}
+		scheduled.IsActive = true
+		scheduled.NextScheduled = template.NextScheduled
+		job, err := js.jobRepo.Update(ctx, scheduled)
+		if err != nil {
+			return nil, serviceError("Scheduling job", err)
+		}
+		return job, nil
+	default:
+		return js.RunJobNow(ctx, existing.JobID)
+	}
+}
+
+// ListJobs returns all jobs. If active is not nil, only active or only paused jobs are returned.
+func (js *JobService) ListJobs(ctx context.Context, active *bool) ([]*models.DataFetchJob, error) {
+	jobs, err := js.jobRepo.List(ctx, active)
+	if err != nil {
+		return nil, errors.NewServiceError("Listing jobs", err)
+	}
+	return jobs, nil
+}
+
+// GetJob returns a single job.
+func (js *JobService) GetJob(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	job, err := js.jobRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, serviceError("Retrieving job", err)
+	}
+	return job, nil
+}
+
+// CreateJob creates a new job. Jobs without a next_scheduled time are due immediately.
+func (js *JobService) CreateJob(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	normalizeJob(job)
+	if job.NextScheduled.IsZero() {
+		job.NextScheduled = time.Now().UTC()
+	}
+	job.Status = models.JobStatusPending
+
+	created, err := js.jobRepo.Create(ctx, job)
+	if err != nil {
+		return nil, serviceError("Creating job", err)
+	}
+	return created, nil
+}
+
+// UpdateJob stores the changed definition of an existing job.
+func (js *JobService) UpdateJob(ctx context.Context, job *models.DataFetchJob) (*models.DataFetchJob, error) {
+	normalizeJob(job)
+
+	updated, err := js.jobRepo.Update(ctx, job)
+	if err != nil {
+		return nil, serviceError("Updating job", err)
+	}
+	return updated, nil
+}
+
+// SetJobActive pauses (false) or resumes (true) a job.
+func (js *JobService) SetJobActive(ctx context.Context, jobID int, active bool) (*models.DataFetchJob, error) {
+	job, err := js.jobRepo.SetActive(ctx, jobID, active)
+	if err != nil {
+		return nil, serviceError("Changing job state", err)
+	}
+	return job, nil
+}
+
+// RunJobNow requests a run of a job, so the scheduler runs it on its next poll. The job keeps its schedule
+// and is PENDING again, unless it is running right now. Paused jobs are never claimed by the scheduler, so they
+// are rejected.
+func (js *JobService) RunJobNow(ctx context.Context, jobID int) (*models.DataFetchJob, error) {
+	job, err := js.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !job.IsActive {
+		return nil, errors.NewModelValidationError("DataFetchJob", "is_active", "job is paused, resume it before running it")
+	}
+
+	job, err = js.jobRepo.ScheduleNow(ctx, jobID)
+	if err != nil {
+		return nil, serviceError("Scheduling job", err)
+	}
+	return job, nil
+}
+
+// DeleteJob deletes a job together with its execution history.
+func (js *JobService) DeleteJob(ctx context.Context, jobID int) error {
+	if err := js.jobRepo.Delete(ctx, jobID); err != nil {
+		return serviceError("Deleting job", err)
+	}
+	return nil
+}
+
+// GetJobLogs returns up to limit execution logs of a job, newest first.
+func (js *JobService) GetJobLogs(ctx context.Context, jobID, limit int) ([]*models.JobExecutionLog, error) {
+	if limit <= 0 || limit > maxJobLogs {
+		limit = maxJobLogs
+	}
+
+	// Make sure the job exists, so an unknown ID is a 404 rather than an empty history
+	if _, err := js.GetJob(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	logs, err := js.logRepo.ListByJob(ctx, jobID, limit)
+	if err != nil {
+		return nil, errors.NewServiceError("Retrieving job execution logs", err)
+	}
+	return logs, nil
+}
+
+// normalizeJob brings the enumerated fields of a job into the case the scheduler expects.
+func normalizeJob(job *models.DataFetchJob) {
+	job.EntityType = strings.ToUpper(strings.TrimSpace(job.EntityType))
+	job.DataType = strings.ToUpper(strings.TrimSpace(job.DataType))
+	job.Frequency = strings.ToLower(strings.TrimSpace(job.Frequency))
+	job.EntityValue = strings.TrimSpace(job.EntityValue)
+	if job.EntityType == models.JobEntitySymbol {
+		job.EntityValue = strings.ToUpper(job.EntityValue)
+	}
+}
+
+// serviceError passes validation, not found, conflict and unavailable errors through to the caller unchanged,
+// and wraps everything else in a ServiceError.
+func serviceError(operation string, err error) error {
+	switch err.(type) {
+	case *errors.ModelValidationError, *errors.NotFoundError, *errors.ConflictError, *errors.UnavailableError:
+		return err
+	}
+	return errors.NewServiceError(operation, err)
+}
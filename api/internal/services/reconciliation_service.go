@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"math"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"sort"
+	"time"
+)
+
+// ReconciliationTolerances are the relative differences allowed between sources before a day is flagged.
+// E.g. a PriceTolerance of 0.005 allows prices from different sources to differ by 0.5%.
+type ReconciliationTolerances struct {
+	PriceTolerance  float64 `json:"price_tolerance"`
+	VolumeTolerance float64 `json:"volume_tolerance"`
+}
+
+// FieldDivergence describes a single field whose values differ between sources beyond the tolerance.
+type FieldDivergence struct {
+	Field     string             `json:"field"`
+	Values    map[string]float64 `json:"values"`    // Source name -> value
+	Deviation float64            `json:"deviation"` // (max - min) / max across sources
+}
+
+// DayDiscrepancy lists everything that did not line up between sources on a single trading day.
+type DayDiscrepancy struct {
+	Date           time.Time         `json:"date"`
+	MissingSources []string          `json:"missing_sources,omitempty"`
+	Divergences    []FieldDivergence `json:"divergences,omitempty"`
+}
+
+// ReconciliationReport is the result of comparing the bars of every source for a symbol.
+type ReconciliationReport struct {
+	Symbol        string                   `json:"symbol"`
+	StartDate     time.Time                `json:"start_date"`
+	EndDate       time.Time                `json:"end_date"`
+	Sources       []string                 `json:"sources"`
+	Tolerances    ReconciliationTolerances `json:"tolerances"`
+	DaysCompared  int                      `json:"days_compared"`
+	Discrepancies []DayDiscrepancy         `json:"discrepancies"`
+}
+
+// ReconciliationService compares the stock prices stored from different data sources.
+type ReconciliationService struct {
+	stockRepo         repositories.StockStore
+	defaultTolerances ReconciliationTolerances
+}
+
+// NewReconciliationService creates a new ReconciliationService. The default tolerances are used for
+// requests that don't specify their own.
+func NewReconciliationService(
+	stockRepo repositories.StockStore,
+	defaultTolerances ReconciliationTolerances,
+) *ReconciliationService {
+	return &ReconciliationService{
+		stockRepo:         stockRepo,
+		defaultTolerances: defaultTolerances,
+	}
+}
+
+// DefaultTolerances returns the tolerances used when a request doesn't specify any.
+func (rs *ReconciliationService) DefaultTolerances() ReconciliationTolerances {
+	return rs.defaultTolerances
+}
+
+// Reconcile lines up the bars of every source for symbol between startDate and endDate. A day is reported
+// when a source that has data in the range is missing that day, or when OHLC or volume diverge beyond the tolerances.
+func (rs *ReconciliationService) Reconcile(
+	ctx context.Context,
+	symbol string,
+	startDate, endDate time.Time,
+	tolerances ReconciliationTolerances,
+) (*ReconciliationReport, error) {
+	if err := validateInput("ReconciliationService", symbol, startDate, endDate); err != nil {
+		return nil, err
+	}
+	if tolerances.PriceTolerance < 0 || tolerances.VolumeTolerance < 0 {
+		return nil, errors.NewModelValidationError("ReconciliationService", "tolerance", "tolerances cannot be negative")
+	}
+
+	stocks, err := rs.stockRepo.RetrieveStocksFromDatabase(ctx, symbol, startDate, endDate)
+	if err != nil {
+		return nil, errors.NewServiceError("Retrieving stock prices for reconciliation", err)
+	}
+	if len(stocks) == 0 {
+		return nil, errors.NewNotFoundError("Symbol", symbol)
+	}
+
+	// Group the bars by day, then by source
+	barsByDate := make(map[time.Time]map[string]*models.Stock)
+	sourceSet := make(map[string]bool)
+	for _, stock := range stocks {
+		if barsByDate[stock.Date] == nil {
+			barsByDate[stock.Date] = make(map[string]*models.Stock)
+		}
+		barsByDate[stock.Date][stock.DataSource] = stock
+		sourceSet[stock.DataSource] = true
+	}
+
+	sources := make([]string, 0, len(sourceSet))
+	for source := range sourceSet {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	dates := make([]time.Time, 0, len(barsByDate))
+	for date := range barsByDate {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	report := &ReconciliationReport{
+		Symbol:        symbol,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Sources:       sources,
+		Tolerances:    tolerances,
+		DaysCompared:  len(dates),
+		Discrepancies: []DayDiscrepancy{},
+	}
+
+	for _, date := range dates {
+		if discrepancy, found := compareBars(date, barsByDate[date], sources, tolerances); found {
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+		}
+	}
+
+	return report, nil
+}
+
+// compareBars checks the bars of a single day. found is false when all sources agree.
+func compareBars(
+	date time.Time,
+	bars map[string]*models.Stock,
+	sources []string,
+	tolerances ReconciliationTolerances,
+) (discrepancy DayDiscrepancy, found bool) {
+	discrepancy.Date = date
+
+	for _, source := range sources {
+		if _, ok := bars[source]; !ok {
+			discrepancy.MissingSources = append(discrepancy.MissingSources, source)
+		}
+	}
+
+	// Values can only diverge if at least two sources have the day
+	if len(bars) > 1 {
+		fields := []struct {
+			name      string
+			value     func(*models.Stock) float64
+			tolerance float64
+		}{
+			{"open_price", func(s *models.Stock) float64 { return s.OpenPrice }, tolerances.PriceTolerance},
+			{"high_price", func(s *models.Stock) float64 { return s.HighPrice }, tolerances.PriceTolerance},
+			{"low_price", func(s *models.Stock) float64 { return s.LowPrice }, tolerances.PriceTolerance},
+			{"close_price", func(s *models.Stock) float64 { return s.ClosePrice }, tolerances.PriceTolerance},
+			{"volume", func(s *models.Stock) float64 { return s.Volume }, tolerances.VolumeTolerance},
+		}
+
+		for _, field := range fields {
+			values := make(map[string]float64, len(bars))
+			for source, bar := range bars {
+				values[source] = field.value(bar)
+			}
+
+			if deviation := relativeSpread(values); deviation > field.tolerance {
+				discrepancy.Divergences = append(discrepancy.Divergences, FieldDivergence{
+					Field:     field.name,
+					Values:    values,
+					Deviation: deviation,
+				})
+			}
+		}
+	}
+
+	return discrepancy, len(discrepancy.MissingSources) > 0 || len(discrepancy.Divergences) > 0
+}
+
+// relativeSpread returns (max - min) / max of the values. Dividing by the larger value keeps the result
+// between 0 and 1 for non-negative values, even when one source reports 0.
+func relativeSpread(values map[string]float64) float64 {
+	minValue, maxValue := math.Inf(1), math.Inf(-1)
+	for _, value := range values {
+		minValue = math.Min(minValue, value)
+		maxValue = math.Max(maxValue, value)
+	}
+
+	if maxValue <= 0 || maxValue == minValue {
+		return 0
+	}
+	return (maxValue - minValue) / maxValue
+}
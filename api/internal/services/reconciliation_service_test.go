@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"slices"
+	"testing"
+)
+
+// reconciliationBar returns a bar of TEST on the given day of March 2024, closing at close.
+func reconciliationBar(d int, source string, close, volume float64) *models.Stock {
+	return &models.Stock{
+		Symbol:           "TEST",
+		Date:             day(d),
+		OpenPrice:        100,
+		HighPrice:        110,
+		LowPrice:         90,
+		ClosePrice:       close,
+		AdjustedClose:    close,
+		Volume:           volume,
+		SplitCoefficient: 1,
+		DataSource:       source,
+	}
+}
+
+// TestRelativeSpread verifies the spread is relative to the largest value and that equal or non-positive
+// values don't diverge.
+func TestRelativeSpread(t *testing.T) {
+	tests := []struct {
+		values   map[string]float64
+		expected float64
+	}{
+		{map[string]float64{"A": 100, "B": 99}, 0.01},
+		{map[string]float64{"A": 99, "B": 100, "C": 99.5}, 0.01},
+		{map[string]float64{"A": 0, "B": 50}, 1},
+		{map[string]float64{"A": 100, "B": 100}, 0},
+		{map[string]float64{"A": 0, "B": 0}, 0},
+		{map[string]float64{"A": 100}, 0},
+	}
+	for _, test := range tests {
+		if spread := relativeSpread(test.values); spread < test.expected-1e-12 || spread > test.expected+1e-12 {
+			t.Errorf("Expected a spread of %v for %v, got %v", test.expected, test.values, spread)
+		}
+	}
+}
+
+// TestReconciliationService_Reconcile verifies days with missing sources and fields diverging beyond their
+// tolerance are reported, while differences within the tolerance are not.
+func TestReconciliationService_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	db := repositories.NewMemoryDB()
+	stocks := repositories.NewMemoryStockRepository(db, repositories.NewMemoryDataSourceRepository(db))
+	_, err := stocks.SaveStocksToDatabase(ctx, []*models.Stock{
+		// Within the tolerances
+		reconciliationBar(4, "FMP", 100, 1000),
+		reconciliationBar(4, "AlphaVantage", 100.4, 1040),
+		// Close and volume beyond the tolerances
+		reconciliationBar(5, "FMP", 100, 1000),
+		reconciliationBar(5, "AlphaVantage", 101, 1100),
+		// Missing from AlphaVantage
+		reconciliationBar(6, "FMP", 100, 1000),
+	})
+	if err != nil {
+		t.Fatalf("Expected the stocks to be saved, got %v", err)
+	}
+	service := NewReconciliationService(stocks, ReconciliationTolerances{PriceTolerance: 0.005, VolumeTolerance: 0.05})
+
+	report, err := service.Reconcile(ctx, "TEST", day(1), day(31), service.DefaultTolerances())
+	if err != nil {
+		t.Fatalf("Expected the report, got %v", err)
+	}
+	if report.DaysCompared != 3 || !slices.Equal(report.Sources, []string{"AlphaVantage", "FMP"}) {
+		t.Errorf("Expected 3 days from AlphaVantage and FMP, got %+v", report)
+	}
+	if len(report.Discrepancies) != 2 {
+		t.Fatalf("Expected 2 discrepancies, got %+v", report.Discrepancies)
+	}
+
+	diverged := report.Discrepancies[0]
+	if !diverged.Date.Equal(day(5)) || len(diverged.MissingSources) != 0 || len(diverged.Divergences) != 2 {
+		t.Fatalf("Expected 2 diverging fields on the 5th, got %+v", diverged)
+	}
+	if closing := diverged.Divergences[0]; closing.Field != "close_price" || closing.Values["FMP"] != 100 ||
+		closing.Values["AlphaVantage"] != 101 || closing.Deviation < 0.0099 || closing.Deviation > 0.0100 {
+		t.Errorf("Expected the close to diverge by 1/101, got %+v", closing)
+	}
+	if volume := diverged.Divergences[1]; volume.Field != "volume" || volume.Values["AlphaVantage"] != 1100 {
+		t.Errorf("Expected the volume to diverge, got %+v", volume)
+	}
+
+	missing := report.Discrepancies[1]
+	if !missing.Date.Equal(day(6)) || !slices.Equal(missing.MissingSources, []string{"AlphaVantage"}) ||
+		len(missing.Divergences) != 0 {
+		t.Errorf("Expected AlphaVantage to be missing on the 6th, got %+v", missing)
+	}
+
+	// Looser tolerances only leave the missing day
+	report, err = service.Reconcile(ctx, "TEST", day(1), day(31), ReconciliationTolerances{PriceTolerance: 0.01, VolumeTolerance: 0.1})
+	if err != nil || len(report.Discrepancies) != 1 || !report.Discrepancies[0].Date.Equal(day(6)) {
+		t.Errorf("Expected only the missing day to be reported, got %+v, %v", report, err)
+	}
+}
+
+// TestReconciliationService_Reconcile_Invalid verifies negative tolerances are rejected and symbols without
+// prices are not found.
+func TestReconciliationService_Reconcile_Invalid(t *testing.T) {
+	ctx := context.Background()
+	db := repositories.NewMemoryDB()
+	service := NewReconciliationService(
+		repositories.NewMemoryStockRepository(db, repositories.NewMemoryDataSourceRepository(db)),
+		ReconciliationTolerances{},
+	)
+
+	var validationErr *errors.ModelValidationError
+	_, err := service.Reconcile(ctx, "TEST", day(1), day(31), ReconciliationTolerances{PriceTolerance: -0.1})
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ModelValidationError for a negative tolerance, got %v", err)
+	}
+
+	var notFound *errors.NotFoundError
+	if _, err := service.Reconcile(ctx, "TEST", day(1), day(31), ReconciliationTolerances{}); !errors.As(err, &notFound) {
+		t.Errorf("Expected a NotFoundError for a symbol without prices, got %v", err)
+	}
+}
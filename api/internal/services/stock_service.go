@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"pocketanalyst/internal/models"
 	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/calendar"
 	"pocketanalyst/pkg/clients"
 	"pocketanalyst/pkg/errors"
 	"strings"
@@ -13,55 +15,301 @@ import (
 
 // StockService handles business logic related to stock operations
 type StockService struct {
-	stockRepo *repositories.StockRepository
-	client    clients.StockDataClient
+	stockRepo       repositories.StockStore
+	companyRepo     repositories.CompanyStore
+	logRepo         repositories.JobExecutionLogStore
+	adjustments     *AdjustmentService
+	client          clients.StockDataClient
+	syncOverlapDays int
 }
 
+// NewStockService creates a new StockService. syncOverlapDays is how many days before the latest stored
+// date an incremental sync re-fetches, so that late corrections from the provider are picked up.
+// Every synchronization is recorded in the job execution logs through logRepo, and the adjusted closes of
+// newly stored prices are recomputed from the corporate actions through adjustments.
 func NewStockService(
-	stockRepo *repositories.StockRepository,
+	stockRepo repositories.StockStore,
+	companyRepo repositories.CompanyStore,
+	logRepo repositories.JobExecutionLogStore,
+	adjustments *AdjustmentService,
 	client clients.StockDataClient,
+	syncOverlapDays int,
 ) *StockService {
+	if syncOverlapDays < 0 {
+		syncOverlapDays = 0
+	}
+
 	return &StockService{
-		stockRepo: stockRepo,
-		client:    client,
+		stockRepo:       stockRepo,
+		companyRepo:     companyRepo,
+		logRepo:         logRepo,
+		adjustments:     adjustments,
+		client:          client,
+		syncOverlapDays: syncOverlapDays,
+	}
+}
+
+// SyncResult summarizes a single synchronization run.
+type SyncResult struct {
+	LogID       int       `json:"log_id,omitempty"` // Job execution log recording this run
+	Symbol      string    `json:"symbol"`
+	Provider    string    `json:"provider"`
+	Incremental bool      `json:"incremental"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Fetched     int       `json:"fetched"`
+	Inserted    int       `json:"inserted"`
+	Updated     int       `json:"updated"`
+	Unchanged   int       `json:"unchanged"`
+	Retries     int       `json:"retries"` // Provider requests that had to be retried
+}
+
+// SynchronizeStockData fetches the daily bars for symbol that are missing from the database and stores them.
+// When the symbol already has data from the configured providers, only the days after the latest stored date
+// (minus the overlap window) are requested. Otherwise the complete history is fetched.
+// The context is handed to the client, so cancelling the originating request stops the upstream call.
+func (s *StockService) SynchronizeStockData(ctx context.Context, symbol string) (*SyncResult, error) {
+	return s.synchronizeWithLog(ctx, s.client, 0, symbol, time.Time{}, time.Time{})
+}
+
+// SynchronizeStockDataForJob runs SynchronizeStockData on behalf of a data fetch job, so that the
+// execution log is attached to the job. The job's provider is tried first, the others remain the fallback.
+func (s *StockService) SynchronizeStockDataForJob(ctx context.Context, jobID int, symbol, provider string) (*SyncResult, error) {
+	return s.synchronizeWithLog(ctx, clients.PreferProvider(s.client, provider), jobID, symbol, time.Time{}, time.Time{})
+}
+
+// SynchronizeStockRange fetches and stores the daily bars of symbol between from and to, regardless of
+// what is already stored. A range without any data, e.g. before the symbol was listed, is not an error.
+func (s *StockService) SynchronizeStockRange(ctx context.Context, symbol string, from, to time.Time) (*SyncResult, error) {
+	if err := validateInput("StockService", symbol, from, to); err != nil {
+		return nil, err
+	}
+	return s.synchronizeWithLog(ctx, s.client, 0, symbol, from, to)
+}
+
+// synchronizeWithLog synchronizes symbol through client between a RUNNING job execution log and its final
+// status. A zero from and to synchronize incrementally, otherwise exactly that range is fetched.
+// Failing to write the log is logged but does not fail the synchronization itself.
+func (s *StockService) synchronizeWithLog(
+	ctx context.Context,
+	client clients.StockDataClient,
+	jobID int,
+	symbol string,
+	from, to time.Time,
+) (*SyncResult, error) {
+	fixedRange := !to.IsZero()
+	if !fixedRange {
+		to = time.Now().UTC()
+	}
+
+	result := &SyncResult{
+		Symbol:   symbol,
+		Provider: client.GetProviderName(),
+		From:     from,
+		To:       to,
+	}
+
+	// Count the provider requests made by this sync, including retries
+	ctx, stats := clients.WithRequestStats(ctx)
+
+	entry := &models.JobExecutionLog{
+		JobID:     jobID,
+		JobType:   models.JobTypeDataFetch,
+		StartTime: time.Now().UTC(),
+		Status:    models.JobStatusRunning,
+	}
+	if s.logRepo != nil {
+		entry.Details = syncDetails(result, stats, "fetching")
+		if err := s.logRepo.Start(ctx, entry); err != nil {
+			log.Printf("Failed to log the start of the %s sync: %v", symbol, err)
+		}
+	}
+
+	// Report the fetched record count while the records are being stored
+	progress := func() {
+		if s.logRepo == nil || entry.LogID == 0 {
+			return
+		}
+		entry.RecordsProcessed = result.Fetched
+		entry.Details = syncDetails(result, stats, "storing")
+		if err := s.logRepo.Update(ctx, entry); err != nil {
+			log.Printf("Failed to log the progress of the %s sync: %v", symbol, err)
+		}
+	}
+
+	err := s.synchronize(ctx, client, result, fixedRange, progress)
+	result.Retries = stats.Retries()
+
+	if s.logRepo != nil && entry.LogID != 0 {
+		result.LogID = entry.LogID
+		entry.EndTime = time.Now().UTC()
+		entry.Status = models.JobStatusSuccess
+		entry.RecordsProcessed = result.Fetched
+		entry.Details = syncDetails(result, stats, "done")
+		if err != nil {
+			entry.Status = models.JobStatusFailed
+			entry.ErrorMessage = err.Error()
+		}
+
+		// Record the outcome even if the sync was cancelled by its caller
+		if logErr := s.logRepo.Update(context.WithoutCancel(ctx), entry); logErr != nil {
+			log.Printf("Failed to log the end of the %s sync: %v", symbol, logErr)
+		}
 	}
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func (s *StockService) SynchronizeStockData(ctx context.Context, symbol string) (int, error) {
+// syncDetails builds the details stored in the execution log of a sync at the given stage.
+func syncDetails(result *SyncResult, stats *clients.RequestStats, stage string) map[string]any {
+	return map[string]any{
+		"stage":       stage,
+		"symbol":      result.Symbol,
+		"provider":    result.Provider,
+		"incremental": result.Incremental,
+		"from":        result.From,
+		"to":          result.To,
+		"fetched":     result.Fetched,
+		"inserted":    result.Inserted,
+		"updated":     result.Updated,
+		"unchanged":   result.Unchanged,
+		"requests":    stats.Requests(),
+		"retry_count": stats.Retries(),
+	}
+}
+
+// synchronize performs the synchronization through client, filling in result as it goes. Unless fixedRange is
+// set, the range starts at the latest stored date. progress is called once the data has been fetched, before it
+// is stored.
+func (s *StockService) synchronize(
+	ctx context.Context,
+	client clients.StockDataClient,
+	result *SyncResult,
+	fixedRange bool,
+	progress func(),
+) error {
+	if !fixedRange {
+		// Find where the stored history from these providers ends
+		latest, err := s.stockRepo.GetLatestStockDate(ctx, result.Symbol, clients.ProviderNames(client))
+		if err != nil {
+			return errors.NewServiceError("Looking up latest stock date", err)
+		}
+
+		if !latest.IsZero() {
+			result.Incremental = true
+			result.From = latest.AddDate(0, 0, -s.syncOverlapDays)
+		}
+	}
+
 	// Fetch stock data from chosen API
-	stocks, err := s.client.FetchDaily(symbol)
+	stocks, err := client.FetchDailyRange(ctx, result.Symbol, result.From, result.To)
 	if err != nil {
-		return 0, errors.NewServiceError("Fetching stock data", err)
+		return errors.NewServiceError("Fetching stock data", err)
+	}
+	result.Fetched = len(stocks)
+	if len(stocks) > 0 {
+		// Report the provider that actually served the data
+		result.Provider = stocks[0].DataSource
 	}
 
-	// If no data was returned, return early
+	// If no data was returned, return early. An incremental sync simply has nothing new yet.
 	if len(stocks) == 0 {
-		return 0, fmt.Errorf("No stock data found for symbol %s", symbol)
+		if result.Incremental || fixedRange {
+			return nil
+		}
+		return fmt.Errorf("No stock data found for symbol %s", result.Symbol)
+	}
+	progress()
+
+	// Derive the values our corporate actions determine before storing, so unchanged days compare equal.
+	// Should that fail, the values are recomputed once the prices are stored.
+	if s.adjustments != nil {
+		if err := s.adjustments.ApplyStoredActions(ctx, result.Symbol, stocks); err != nil {
+			log.Printf("Failed to apply the corporate actions of %s: %v", result.Symbol, err)
+		}
 	}
 
 	// Store the fetched data in the database
-	storedCount, err := s.stockRepo.SaveStocksToDatabase(ctx, stocks)
+	saved, err := s.stockRepo.SaveStocksToDatabase(ctx, stocks)
 	if err != nil {
-		return 0, errors.NewServiceError("Storing stock data", err)
+		return errors.NewServiceError("Storing stock data", err)
 	}
 
-	return storedCount, nil
+	result.Inserted = saved.Inserted
+	result.Updated = saved.Updated
+	result.Unchanged = saved.Unchanged
+
+	// New or corrected closes change the dividend factors of earlier days, so derive the adjusted closes again.
+	// The prices are stored either way, so a failure here doesn't fail the sync.
+	if s.adjustments != nil && saved.Inserted+saved.Updated > 0 {
+		if err := s.adjustments.RecomputeIfAdjusted(ctx, result.Symbol); err != nil {
+			log.Printf("Failed to recompute the adjusted closes of %s: %v", result.Symbol, err)
+		}
+	}
+	return nil
+}
+
+// Providers returns the names of the providers stock data is fetched from, in order of preference.
+func (s *StockService) Providers() []string {
+	return clients.ProviderNames(s.client)
+}
+
+// SupportsDateRange reports whether the preferred provider fetches a short date range more cheaply than the
+// full history.
+func (s *StockService) SupportsDateRange() bool {
+	return clients.SupportsDateRange(s.client)
 }
 
+// ProviderHealth reports the health of the data providers behind the configured client. Clients that
+// do not track their health are not reported.
+func (s *StockService) ProviderHealth() []clients.ProviderHealth {
+	reporter, ok := s.client.(clients.HealthReporter)
+	if !ok {
+		return []clients.ProviderHealth{}
+	}
+	return reporter.ProviderHealth()
+}
+
+// TradingCalendar returns the trading calendar of the exchange symbol is listed on. If the exchange cannot be
+// looked up, the default calendar is used, so callers always get a calendar.
+func (s *StockService) TradingCalendar(ctx context.Context, symbol string) calendar.Calendar {
+	exchange, err := s.companyRepo.GetExchange(ctx, strings.ToUpper(strings.TrimSpace(symbol)))
+	if err != nil {
+		log.Printf("Looking up the exchange of %s failed, using the default calendar: %v", symbol, err)
+	}
+	return calendar.ForExchange(exchange)
+}
+
+// GetStockHistory returns the stored prices of symbol between startDate and endDate, newest first.
+// With adjusted set, open, high, low, close and volume are back-adjusted for splits and dividends,
+// otherwise they are returned as reported by the providers.
 func (s *StockService) GetStockHistory(
 	ctx context.Context,
 	symbol string,
 	startDate, endDate time.Time,
+	adjusted bool,
 ) ([]*models.Stock, error) {
 	// Validate date function parameters before anything
-	if err := s.validateInput(symbol, startDate, endDate); err != nil {
+	if err := validateInput("StockService", symbol, startDate, endDate); err != nil {
 		return nil, err
 	}
 
-	// Get stock prices from the database using the repository
-	stocks, err := s.stockRepo.RetrieveStocksFromDatabase(ctx, symbol, startDate, endDate)
-	if err != nil {
-		return nil, errors.NewServiceError("Retrieving stock history", err)
+	var stocks []*models.Stock
+	var err error
+	if adjusted && s.adjustments != nil {
+		stocks, err = s.adjustments.GetAdjustedHistory(ctx, symbol, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Get stock prices from the database using the repository
+		stocks, err = s.stockRepo.RetrieveStocksFromDatabase(ctx, symbol, startDate, endDate)
+		if err != nil {
+			return nil, errors.NewServiceError("Retrieving stock history", err)
+		}
 	}
 
 	// If no data is returned, treat as "symbol not found."
@@ -71,10 +319,11 @@ func (s *StockService) GetStockHistory(
 	return stocks, nil
 }
 
-func (s *StockService) validateInput(symbol string, startDate, endDate time.Time) error {
+// validateInput checks the symbol and date range parameters shared by the services.
+func validateInput(service, symbol string, startDate, endDate time.Time) error {
 	if strings.TrimSpace(symbol) == "" {
 		return errors.NewModelValidationError(
-			"StockService",
+			service,
 			"symbol",
 			"symbol cannot be empty",
 		)
@@ -82,7 +331,7 @@ func (s *StockService) validateInput(symbol string, startDate, endDate time.Time
 
 	if startDate.After(endDate) {
 		return errors.NewModelValidationError(
-			"StockService",
+			service,
 			"date_range",
 			"start date cannot be after end date",
 		)
@@ -90,7 +339,7 @@ func (s *StockService) validateInput(symbol string, startDate, endDate time.Time
 
 	if startDate.IsZero() || endDate.IsZero() {
 		return errors.NewModelValidationError(
-			"StockService",
+			service,
 			"date_range",
 			"dates cannot be empty",
 		)
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/internal/repositories"
+	"pocketanalyst/pkg/errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubClient serves a fixed history and remembers the range of the last request and the requested symbols.
+// Symbols in failures fail with their error.
+type stubClient struct {
+	stocks    []*models.Stock
+	failures  map[string]error
+	from, to  time.Time
+	requested []string
+	mu        sync.Mutex
+}
+
+func (c *stubClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.from, c.to = from, to
+	c.requested = append(c.requested, symbol)
+	if err := c.failures[symbol]; err != nil {
+		return nil, err
+	}
+
+	stocks := []*models.Stock{}
+	for _, stock := range c.stocks {
+		if stock.Symbol == symbol && (from.IsZero() || !stock.Date.Before(from)) && (to.IsZero() || !stock.Date.After(to)) {
+			copied := *stock
+			stocks = append(stocks, &copied)
+		}
+	}
+	return stocks, nil
+}
+
+func (c *stubClient) GetProviderName() string {
+	return "Stub"
+}
+
+// newMemoryStockService creates a StockService on top of the in-memory repositories.
+func newMemoryStockService(client *stubClient, syncOverlapDays int) *StockService {
+	db := repositories.NewMemoryDB()
+	dataSources := repositories.NewMemoryDataSourceRepository(db)
+	return NewStockService(
+		repositories.NewMemoryStockRepository(db, dataSources),
+		repositories.NewMemoryCompanyRepository(db),
+		repositories.NewMemoryJobExecutionLogRepository(db),
+		nil,
+		client,
+		syncOverlapDays,
+	)
+}
+
+// TestStockService_SynchronizeStockData verifies the first sync fetches the complete history and later syncs
+// only re-fetch the overlap window after the latest stored date.
+func TestStockService_SynchronizeStockData(t *testing.T) {
+	ctx := context.Background()
+	client := &stubClient{}
+	for d := 8; d >= 4; d-- {
+		client.stocks = append(client.stocks, &models.Stock{
+			Symbol:           "TEST",
+			Date:             day(d),
+			OpenPrice:        100,
+			HighPrice:        102,
+			LowPrice:         99,
+			ClosePrice:       101,
+			AdjustedClose:    101,
+			Volume:           1000,
+			SplitCoefficient: 1,
+			DataSource:       "Stub",
+		})
+	}
+	service := newMemoryStockService(client, 2)
+
+	result, err := service.SynchronizeStockData(ctx, "TEST")
+	if err != nil {
+		t.Fatalf("Expected the first sync to succeed, got %v", err)
+	}
+	if result.Incremental || !client.from.IsZero() || result.Inserted != 5 {
+		t.Errorf("Expected a full sync inserting 5 rows, got %+v from %v", result, client.from)
+	}
+	if result.LogID == 0 {
+		t.Error("Expected the sync to be logged")
+	}
+
+	result, err = service.SynchronizeStockData(ctx, "TEST")
+	if err != nil {
+		t.Fatalf("Expected the second sync to succeed, got %v", err)
+	}
+	if !result.Incremental || !client.from.Equal(day(6)) || result.Unchanged != 3 || result.Inserted != 0 {
+		t.Errorf("Expected an incremental sync from the 6th with 3 unchanged rows, got %+v from %v", result, client.from)
+	}
+
+	history, err := service.GetStockHistory(ctx, "TEST", day(1), day(31), false)
+	if err != nil || len(history) != 5 || !history[0].Date.Equal(day(8)) {
+		t.Errorf("Expected 5 stored days, newest first, got %v, %v", history, err)
+	}
+
+	var notFound *errors.NotFoundError
+	if _, err := service.GetStockHistory(ctx, "NONE", day(1), day(31), false); !errors.As(err, &notFound) {
+		t.Errorf("Expected a NotFoundError for a symbol without data, got %v", err)
+	}
+}
+
+// newHistoryClient serves the prices of TEST from the first to the last day of March 2024.
+func newHistoryClient(first, last int) *stubClient {
+	client := newBatchClient()
+	for d := first; d <= last; d++ {
+		client.stocks = append(client.stocks, &models.Stock{
+			Symbol:           "TEST",
+			Date:             day(d),
+			OpenPrice:        100,
+			HighPrice:        102,
+			LowPrice:         99,
+			ClosePrice:       101,
+			AdjustedClose:    101,
+			Volume:           1000,
+			SplitCoefficient: 1,
+			DataSource:       "Stub",
+		})
+	}
+	return client
+}
+
+// TestStockService_SynchronizeStockData_OverlapWindow verifies an incremental sync starts the configured
+// number of days before the latest stored date, so corrections within the window are picked up.
+func TestStockService_SynchronizeStockData_OverlapWindow(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		overlap   int
+		from      time.Time
+		unchanged int
+	}{
+		{0, day(8), 1},
+		{3, day(5), 4},
+		{10, time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC), 5},
+	}
+	for _, test := range tests {
+		client := newHistoryClient(4, 8)
+		service := newMemoryStockService(client, test.overlap)
+		if _, err := service.SynchronizeStockData(ctx, "TEST"); err != nil {
+			t.Fatalf("Expected the first sync to succeed, got %v", err)
+		}
+
+		result, err := service.SynchronizeStockData(ctx, "TEST")
+		if err != nil {
+			t.Fatalf("Expected the incremental sync to succeed, got %v", err)
+		}
+		if !result.Incremental || !result.From.Equal(test.from) || !client.from.Equal(test.from) {
+			t.Errorf("Expected an overlap of %d days to sync from %v, got %v", test.overlap, test.from, client.from)
+		}
+		if result.Unchanged != test.unchanged || result.Inserted != 0 || result.Updated != 0 {
+			t.Errorf("Expected %d unchanged rows with an overlap of %d days, got %+v", test.unchanged, test.overlap, result)
+		}
+	}
+
+	// A corrected day within the window is updated, new days are inserted
+	client := newHistoryClient(4, 8)
+	service := newMemoryStockService(client, 2)
+	if _, err := service.SynchronizeStockData(ctx, "TEST"); err != nil {
+		t.Fatalf("Expected the first sync to succeed, got %v", err)
+	}
+	client.stocks[3].ClosePrice = 100.5
+	client.stocks = append(newHistoryClient(11, 11).stocks, client.stocks...)
+
+	result, err := service.SynchronizeStockData(ctx, "TEST")
+	if err != nil {
+		t.Fatalf("Expected the incremental sync to succeed, got %v", err)
+	}
+	if result.Fetched != 4 || result.Inserted != 1 || result.Updated != 1 || result.Unchanged != 2 {
+		t.Errorf("Expected 1 inserted, 1 updated and 2 unchanged rows, got %+v", result)
+	}
+}
+
+// TestStockService_SynchronizeStockData_EmptyIncremental verifies an incremental sync without new data
+// succeeds, while a full sync without data fails.
+func TestStockService_SynchronizeStockData_EmptyIncremental(t *testing.T) {
+	ctx := context.Background()
+	client := newHistoryClient(4, 8)
+	service := newMemoryStockService(client, 0)
+	if _, err := service.SynchronizeStockData(ctx, "TEST"); err != nil {
+		t.Fatalf("Expected the first sync to succeed, got %v", err)
+	}
+
+	client.stocks = nil
+	result, err := service.SynchronizeStockData(ctx, "TEST")
+	if err != nil {
+		t.Fatalf("Expected an incremental sync without new data to succeed, got %v", err)
+	}
+	if !result.Incremental || result.Fetched != 0 || result.Inserted+result.Updated+result.Unchanged != 0 {
+		t.Errorf("Expected an empty incremental sync, got %+v", result)
+	}
+	if result.Provider != "Stub" || result.LogID == 0 {
+		t.Errorf("Expected a logged sync reporting the configured provider, got %+v", result)
+	}
+
+	if _, err := service.SynchronizeStockData(ctx, "NONE"); err == nil {
+		t.Error("Expected a full sync without data to fail")
+	}
+}
+
+// TestStockService_SynchronizeStockData_OtherProvider verifies only the history of the configured providers
+// makes a sync incremental, as the days another provider stored may not be served by them.
+func TestStockService_SynchronizeStockData_OtherProvider(t *testing.T) {
+	ctx := context.Background()
+	client := newHistoryClient(4, 8)
+	service := newMemoryStockService(client, 0)
+
+	other := newHistoryClient(4, 8).stocks
+	for _, stock := range other {
+		stock.DataSource = "Other"
+	}
+	if _, err := service.stockRepo.SaveStocksToDatabase(ctx, other); err != nil {
+		t.Fatalf("Expected the stocks of the other provider to be saved, got %v", err)
+	}
+
+	result, err := service.SynchronizeStockData(ctx, "TEST")
+	if err != nil {
+		t.Fatalf("Expected the sync to succeed, got %v", err)
+	}
+	if result.Incremental || !client.from.IsZero() || result.Inserted != 5 {
+		t.Errorf("Expected a full sync inserting 5 rows, got %+v from %v", result, client.from)
+	}
+}
@@ -0,0 +1,122 @@
+package calendar
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Calendar decides which days an exchange holds a trading session. Dates are compared by their
+// year, month and day, the time of day and location are ignored.
+type Calendar interface {
+	// Name returns the name of the calendar, e.g. "NYSE".
+	Name() string
+	// IsTradingDay reports whether the exchange holds a session on date.
+	IsTradingDay(date time.Time) bool
+	// IsEarlyClose reports whether the session on date closes early, e.g. the day after Thanksgiving.
+	IsEarlyClose(date time.Time) bool
+}
+
+// WeekdayCalendar treats every Monday to Friday as a full trading day. It is used for exchanges
+// without a registered calendar.
+type WeekdayCalendar struct{}
+
+// Name returns "weekdays".
+func (WeekdayCalendar) Name() string {
+	return "weekdays"
+}
+
+// IsTradingDay reports whether date falls on a weekday.
+func (WeekdayCalendar) IsTradingDay(date time.Time) bool {
+	return isWeekday(date)
+}
+
+// IsEarlyClose always returns false.
+func (WeekdayCalendar) IsEarlyClose(date time.Time) bool {
+	return false
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Calendar{
+		"NYSE":          NYSE,
+		"NASDAQ":        NASDAQ,
+		"NYSE ARCA":     NYSE,
+		"NYSEARCA":      NYSE,
+		"NYSE AMERICAN": NYSE,
+		"AMEX":          NYSE,
+		"BATS":          NYSE,
+		"CBOE":          NYSE,
+	}
+)
+
+// Register makes cal the calendar of exchange, replacing any calendar registered before.
+// Exchange names are matched case-insensitively, like the values of companies.exchange.
+func Register(exchange string, cal Calendar) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[normalizeExchange(exchange)] = cal
+}
+
+// ForExchange returns the calendar registered for exchange. Companies without an exchange are assumed to
+// trade on the NYSE schedule, unknown exchanges fall back to the WeekdayCalendar.
+func ForExchange(exchange string) Calendar {
+	exchange = normalizeExchange(exchange)
+	if exchange == "" {
+		return NYSE
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if cal, ok := registry[exchange]; ok {
+		return cal
+	}
+	return WeekdayCalendar{}
+}
+
+// TradingDays returns the trading days between from and to (both inclusive) in ascending order,
+// as midnight UTC.
+func TradingDays(cal Calendar, from, to time.Time) []time.Time {
+	days := []time.Time{}
+	for day := Date(from); !day.After(Date(to)); day = day.AddDate(0, 0, 1) {
+		if cal.IsTradingDay(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// LastTradingDays returns the first day of the last n trading days up to and including end, as midnight UTC.
+// E.g. with n = 5 and end on a Friday without holidays, the Monday of that week is returned.
+func LastTradingDays(cal Calendar, end time.Time, n int) time.Time {
+	day := Date(end)
+	for found := 0; ; day = day.AddDate(0, 0, -1) {
+		if cal.IsTradingDay(day) {
+			found++
+			if found >= n {
+				return day
+			}
+		}
+	}
+}
+
+// PreviousTradingDay returns the last trading day before date, as midnight UTC.
+func PreviousTradingDay(cal Calendar, date time.Time) time.Time {
+	return LastTradingDays(cal, Date(date).AddDate(0, 0, -1), 1)
+}
+
+// Date returns midnight UTC of t's year, month and day.
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// isWeekday reports whether date falls on Monday to Friday.
+func isWeekday(date time.Time) bool {
+	weekday := date.Weekday()
+	return weekday != time.Saturday && weekday != time.Sunday
+}
+
+// normalizeExchange upper-cases and trims an exchange name.
+func normalizeExchange(exchange string) string {
+	return strings.ToUpper(strings.TrimSpace(exchange))
+}
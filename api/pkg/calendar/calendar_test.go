@@ -0,0 +1,110 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// TestNYSE_Holidays verifies the generated holidays against the published NYSE schedules.
+func TestNYSE_Holidays(t *testing.T) {
+	tests := []struct {
+		year     int
+		holidays []time.Time
+	}{
+		{2024, []time.Time{
+			date(2024, 1, 1), date(2024, 1, 15), date(2024, 2, 19), date(2024, 3, 29), date(2024, 5, 27),
+			date(2024, 6, 19), date(2024, 7, 4), date(2024, 9, 2), date(2024, 11, 28), date(2024, 12, 25),
+		}},
+		// New Year's Day on a Saturday is not observed, Juneteenth and Christmas move to Monday
+		{2022, []time.Time{
+			date(2022, 1, 17), date(2022, 2, 21), date(2022, 4, 15), date(2022, 5, 30), date(2022, 6, 20),
+			date(2022, 7, 4), date(2022, 9, 5), date(2022, 11, 24), date(2022, 12, 26),
+		}},
+		// Independence Day on a Saturday is observed the Friday before
+		{2026, []time.Time{
+			date(2026, 1, 1), date(2026, 1, 19), date(2026, 2, 16), date(2026, 4, 3), date(2026, 5, 25),
+			date(2026, 6, 19), date(2026, 7, 3), date(2026, 9, 7), date(2026, 11, 26), date(2026, 12, 25),
+		}},
+	}
+
+	for _, tt := range tests {
+		holidays := NYSE.Holidays(tt.year)
+		if len(holidays) != len(tt.holidays) {
+			t.Errorf("%d: expected %d holidays, got %v", tt.year, len(tt.holidays), holidays)
+			continue
+		}
+		for i, holiday := range tt.holidays {
+			if !holidays[i].Equal(holiday) {
+				t.Errorf("%d: expected holiday %s, got %s", tt.year, holiday.Format("2006-01-02"), holidays[i].Format("2006-01-02"))
+			}
+		}
+	}
+}
+
+// TestNYSE_TradingDays verifies weekends, holidays and special closures are not trading days,
+// and which sessions close early.
+func TestNYSE_TradingDays(t *testing.T) {
+	tests := []struct {
+		date       time.Time
+		trading    bool
+		earlyClose bool
+	}{
+		{date(2024, 7, 3), true, true},
+		{date(2024, 7, 4), false, false},
+		{date(2024, 11, 29), true, true},
+		{date(2024, 12, 24), true, true},
+		{date(2024, 12, 23), true, false},
+		{date(2024, 12, 28), false, false}, // Saturday
+		{date(2021, 12, 31), true, false},  // New Year's Day 2022 fell on a Saturday
+		{date(2026, 7, 3), false, false},   // Observed Independence Day, so no early close
+		{date(2025, 1, 9), false, false},   // National day of mourning
+	}
+
+	for _, tt := range tests {
+		if got := NYSE.IsTradingDay(tt.date); got != tt.trading {
+			t.Errorf("%s: expected trading day %v, got %v", tt.date.Format("2006-01-02"), tt.trading, got)
+		}
+		if got := NYSE.IsEarlyClose(tt.date); got != tt.earlyClose {
+			t.Errorf("%s: expected early close %v, got %v", tt.date.Format("2006-01-02"), tt.earlyClose, got)
+		}
+	}
+}
+
+// TestLastTradingDays verifies the start of the last N trading days skips weekends and holidays.
+func TestLastTradingDays(t *testing.T) {
+	// Friday 2024-03-29 is Good Friday, so the last 5 sessions up to Tuesday 2024-04-02 start on Tuesday 2024-03-26
+	start := LastTradingDays(NYSE, time.Date(2024, 4, 2, 15, 30, 0, 0, time.UTC), 5)
+	if !start.Equal(date(2024, 3, 26)) {
+		t.Errorf("Expected 2024-03-26, got %s", start.Format("2006-01-02"))
+	}
+
+	if days := TradingDays(NYSE, start, date(2024, 4, 2)); len(days) != 5 {
+		t.Errorf("Expected 5 trading days, got %v", days)
+	}
+
+	if previous := PreviousTradingDay(NYSE, date(2024, 4, 1)); !previous.Equal(date(2024, 3, 28)) {
+		t.Errorf("Expected 2024-03-28, got %s", previous.Format("2006-01-02"))
+	}
+}
+
+// TestForExchange verifies exchanges are matched case-insensitively and unknown exchanges fall back to weekdays.
+func TestForExchange(t *testing.T) {
+	if cal := ForExchange(" nasdaq "); cal.Name() != "NASDAQ" {
+		t.Errorf("Expected the NASDAQ calendar, got %s", cal.Name())
+	}
+	if cal := ForExchange(""); cal.Name() != "NYSE" {
+		t.Errorf("Expected the NYSE calendar for a missing exchange, got %s", cal.Name())
+	}
+	if cal := ForExchange("XETRA"); cal.Name() != "weekdays" {
+		t.Errorf("Expected the weekday calendar for an unknown exchange, got %s", cal.Name())
+	}
+
+	Register("xetra", NewExchangeCalendar("XETRA", []Rule{Fixed(time.December, 31)}, nil, nil))
+	if cal := ForExchange("XETRA"); cal.IsTradingDay(date(2024, 12, 31)) {
+		t.Errorf("Expected the registered XETRA calendar to be closed on 2024-12-31")
+	}
+}
@@ -0,0 +1,2 @@
+// Package calendar provides exchange trading calendars, telling which days a market is open.
+package calendar
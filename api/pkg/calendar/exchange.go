@@ -0,0 +1,141 @@
+package calendar
+
+import (
+	"sync"
+	"time"
+)
+
+// ExchangeCalendar is a Calendar built from holiday and early close rules, so it covers any year.
+// Closures that follow no rule, e.g. national days of mourning, are listed as special closures.
+type ExchangeCalendar struct {
+	name            string
+	holidays        []Rule
+	earlyCloses     []Rule
+	specialClosures map[time.Time]bool
+
+	// The holidays and early closes are computed once per year
+	mu    sync.Mutex
+	years map[int]*calendarYear
+}
+
+// calendarYear holds the computed holidays and early closes of a single year.
+type calendarYear struct {
+	holidays    map[time.Time]bool
+	earlyCloses map[time.Time]bool
+}
+
+// NewExchangeCalendar creates a calendar closed on weekends, on the dates of the holiday rules and on the
+// special closures. The sessions on the dates of the early close rules close early, unless they are closed.
+func NewExchangeCalendar(name string, holidays, earlyCloses []Rule, specialClosures []time.Time) *ExchangeCalendar {
+	closures := make(map[time.Time]bool, len(specialClosures))
+	for _, date := range specialClosures {
+		closures[Date(date)] = true
+	}
+
+	return &ExchangeCalendar{
+		name:            name,
+		holidays:        holidays,
+		earlyCloses:     earlyCloses,
+		specialClosures: closures,
+		years:           make(map[int]*calendarYear),
+	}
+}
+
+// Name returns the name of the exchange.
+func (ec *ExchangeCalendar) Name() string {
+	return ec.name
+}
+
+// IsTradingDay reports whether date is a weekday that is neither a holiday nor a special closure.
+func (ec *ExchangeCalendar) IsTradingDay(date time.Time) bool {
+	date = Date(date)
+	return isWeekday(date) && !ec.year(date.Year()).holidays[date] && !ec.specialClosures[date]
+}
+
+// IsEarlyClose reports whether date is a trading day on which the session closes early.
+func (ec *ExchangeCalendar) IsEarlyClose(date time.Time) bool {
+	date = Date(date)
+	return ec.IsTradingDay(date) && ec.year(date.Year()).earlyCloses[date]
+}
+
+// Holidays returns the holidays of year in ascending order, not including special closures.
+func (ec *ExchangeCalendar) Holidays(year int) []time.Time {
+	holidays := []time.Time{}
+	for day := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC); day.Year() == year; day = day.AddDate(0, 0, 1) {
+		if ec.year(year).holidays[day] {
+			holidays = append(holidays, day)
+		}
+	}
+	return holidays
+}
+
+// year returns the computed holidays and early closes of year.
+func (ec *ExchangeCalendar) year(year int) *calendarYear {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	if computed, ok := ec.years[year]; ok {
+		return computed
+	}
+
+	computed := &calendarYear{
+		holidays:    applyRules(ec.holidays, year),
+		earlyCloses: applyRules(ec.earlyCloses, year),
+	}
+	ec.years[year] = computed
+	return computed
+}
+
+// applyRules collects the dates the rules produce for year.
+func applyRules(rules []Rule, year int) map[time.Time]bool {
+	dates := make(map[time.Time]bool, len(rules))
+	for _, rule := range rules {
+		if date, ok := rule(year); ok {
+			dates[date] = true
+		}
+	}
+	return dates
+}
+
+// nyseHolidays are the full-day holidays observed by the NYSE and NASDAQ.
+var nyseHolidays = []Rule{
+	SundayObserved(Fixed(time.January, 1)),                // New Year's Day
+	Since(1998, NthWeekday(time.January, time.Monday, 3)), // Martin Luther King Jr. Day
+	NthWeekday(time.February, time.Monday, 3),             // Washington's Birthday
+	EasterOffset(-2),                            // Good Friday
+	LastWeekday(time.May, time.Monday),          // Memorial Day
+	Since(2022, Observed(Fixed(time.June, 19))), // Juneteenth
+	Observed(Fixed(time.July, 4)),               // Independence Day
+	NthWeekday(time.September, time.Monday, 1),  // Labor Day
+	NthWeekday(time.November, time.Thursday, 4), // Thanksgiving Day
+	Observed(Fixed(time.December, 25)),          // Christmas Day
+}
+
+// nyseEarlyCloses are the sessions closing at 1:00 p.m. Eastern. They only apply when the day is a trading day.
+var nyseEarlyCloses = []Rule{
+	Fixed(time.July, 3), // Day before Independence Day
+	DaysAfter(NthWeekday(time.November, time.Thursday, 4), 1), // Day after Thanksgiving
+	Fixed(time.December, 24),                                  // Christmas Eve
+}
+
+// nyseSpecialClosures are the unscheduled closures since 2000.
+var nyseSpecialClosures = []time.Time{
+	time.Date(2001, time.September, 11, 0, 0, 0, 0, time.UTC), // September 11 attacks
+	time.Date(2001, time.September, 12, 0, 0, 0, 0, time.UTC),
+	time.Date(2001, time.September, 13, 0, 0, 0, 0, time.UTC),
+	time.Date(2001, time.September, 14, 0, 0, 0, 0, time.UTC),
+	time.Date(2004, time.June, 11, 0, 0, 0, 0, time.UTC),    // Mourning for President Reagan
+	time.Date(2007, time.January, 2, 0, 0, 0, 0, time.UTC),  // Mourning for President Ford
+	time.Date(2012, time.October, 29, 0, 0, 0, 0, time.UTC), // Hurricane Sandy
+	time.Date(2012, time.October, 30, 0, 0, 0, 0, time.UTC),
+	time.Date(2018, time.December, 5, 0, 0, 0, 0, time.UTC), // Mourning for President George H. W. Bush
+	time.Date(2025, time.January, 9, 0, 0, 0, 0, time.UTC),  // Mourning for President Carter
+}
+
+var (
+	// NYSE is the trading calendar of the New York Stock Exchange.
+	NYSE = NewExchangeCalendar("NYSE", nyseHolidays, nyseEarlyCloses, nyseSpecialClosures)
+
+	// NASDAQ follows the same holidays and early closes as the NYSE.
+	NASDAQ = NewExchangeCalendar("NASDAQ", nyseHolidays, nyseEarlyCloses, nyseSpecialClosures)
+)
@@ -0,0 +1,108 @@
+package calendar
+
+import (
+	"time"
+)
+
+// Rule computes the date of a recurring holiday or early close in a given year. ok is false for years
+// in which the rule does not apply, e.g. before the holiday was introduced.
+type Rule func(year int) (date time.Time, ok bool)
+
+// Fixed returns a rule for the same month and day every year.
+func Fixed(month time.Month, day int) Rule {
+	return func(year int) (time.Time, bool) {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), true
+	}
+}
+
+// Observed moves a holiday falling on a Saturday to the Friday before and one falling on a Sunday
+// to the Monday after.
+func Observed(rule Rule) Rule {
+	return func(year int) (time.Time, bool) {
+		date, ok := rule(year)
+		switch date.Weekday() {
+		case time.Saturday:
+			date = date.AddDate(0, 0, -1)
+		case time.Sunday:
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, ok
+	}
+}
+
+// SundayObserved moves a holiday falling on a Sunday to the Monday after. A holiday falling on a Saturday
+// is not observed at all, like New Year's Day on the NYSE, which never closes on the last day of a year.
+func SundayObserved(rule Rule) Rule {
+	return func(year int) (time.Time, bool) {
+		date, ok := rule(year)
+		switch date.Weekday() {
+		case time.Saturday:
+			return date, false
+		case time.Sunday:
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, ok
+	}
+}
+
+// NthWeekday returns a rule for the nth weekday of month, e.g. the third Monday of January.
+func NthWeekday(month time.Month, weekday time.Weekday, n int) Rule {
+	return func(year int) (time.Time, bool) {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+		return first.AddDate(0, 0, offset+7*(n-1)), true
+	}
+}
+
+// LastWeekday returns a rule for the last weekday of month, e.g. the last Monday of May.
+func LastWeekday(month time.Month, weekday time.Weekday) Rule {
+	return func(year int) (time.Time, bool) {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+		return last.AddDate(0, 0, -offset), true
+	}
+}
+
+// EasterOffset returns a rule for the day days after Western Easter Sunday, e.g. -2 for Good Friday.
+func EasterOffset(days int) Rule {
+	return func(year int) (time.Time, bool) {
+		return easterSunday(year).AddDate(0, 0, days), true
+	}
+}
+
+// DaysAfter returns a rule for the day days after the date of rule, e.g. the day after Thanksgiving.
+func DaysAfter(rule Rule, days int) Rule {
+	return func(year int) (time.Time, bool) {
+		date, ok := rule(year)
+		return date.AddDate(0, 0, days), ok
+	}
+}
+
+// Since limits rule to the years from firstYear on.
+func Since(firstYear int, rule Rule) Rule {
+	return func(year int) (time.Time, bool) {
+		if year < firstYear {
+			return time.Time{}, false
+		}
+		return rule(year)
+	}
+}
+
+// easterSunday computes Western Easter Sunday with the anonymous Gregorian algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -1,8 +1,10 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors/client_errors"
 	"sort"
 	"strconv"
 	"time"
@@ -25,18 +27,112 @@ func (avc *AlphaVantageClient) GetProviderName() string {
 	return "AlphaVantage"
 }
 
+// compactOutputDays is how far back Alpha Vantage's compact output (the latest 100 data points) is
+// guaranteed to reach. 100 trading days always span more than 130 calendar days.
+const compactOutputDays = 130
+
 // Fetch daily stock prices from Alpha Vantage
 // Alpha Vantage API documentation https://www.alphavantage.co/documentation/
 func (avc *AlphaVantageClient) FetchDaily(symbol string) ([]*models.Stock, error) {
-	// Construct URL with required parameters
-	// TIME_SERIES_DAILY_ADJUSTED returns daily adjusted time series
+	return avc.FetchDailyRange(context.Background(), symbol, time.Time{}, time.Time{})
+}
+
+// FetchDailyRange fetches daily bars between from and to. Alpha Vantage has no date range parameters,
+// so the smaller compact output is requested when it covers the range and the result is filtered locally.
+func (avc *AlphaVantageClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
+	// TIME_SERIES_DAILY returns the daily time series as traded
 	// outputsize=compact returns the latest 100 data points
 	// outputsize=full returns all the data in its full length
-	url := fmt.Sprintf("%s?function=TIME_SERIES_DAILY&symbol=%s&outputsize=full&apikey=%s",
-		avc.BaseURL, symbol, avc.APIKey)
+	stocks, err := avc.fetchTimeSeries(ctx, "TIME_SERIES_DAILY", symbol, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterByDateRange(stocks, from, to), nil
+}
+
+// FetchCorporateActions reads the dividends and splits of symbol between from and to from the dividend amount
+// and split coefficient columns of TIME_SERIES_DAILY_ADJUSTED.
+func (avc *AlphaVantageClient) FetchCorporateActions(
+	ctx context.Context,
+	symbol string,
+	from, to time.Time,
+) ([]*models.CorporateAction, error) {
+	stocks, err := avc.fetchTimeSeries(ctx, "TIME_SERIES_DAILY_ADJUSTED", symbol, from)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []*models.CorporateAction{}
+	for _, stock := range filterByDateRange(stocks, from, to) {
+		if stock.DividendAmount > 0 {
+			actions = append(actions, &models.CorporateAction{
+				Symbol:      symbol,
+				ActionType:  models.CorporateActionDividend,
+				ExDate:      stock.Date,
+				Amount:      stock.DividendAmount,
+				Source:      avc.GetProviderName(),
+				LastUpdated: time.Now(),
+			})
+		}
+		if stock.SplitCoefficient > 0 && stock.SplitCoefficient != 1 {
+			actions = append(actions, &models.CorporateAction{
+				Symbol:      symbol,
+				ActionType:  models.CorporateActionSplit,
+				ExDate:      stock.Date,
+				Ratio:       stock.SplitCoefficient,
+				Source:      avc.GetProviderName(),
+				LastUpdated: time.Now(),
+			})
+		}
+	}
+	return actions, nil
+}
+
+// FetchCompanyProfile reads the name, sector, industry and exchange of symbol from the OVERVIEW function.
+// Alpha Vantage reports sectors and industries in upper case.
+func (avc *AlphaVantageClient) FetchCompanyProfile(ctx context.Context, symbol string) (*models.Company, error) {
+	url := fmt.Sprintf("%s?function=OVERVIEW&symbol=%s&apikey=%s", avc.BaseURL, symbol, avc.APIKey)
+
+	response, err := avc.MakeRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := avc.CheckAPIError(response, "Note", "Information"); err != nil {
+		return nil, err
+	}
+
+	// Unknown symbols return an empty object
+	name, _ := response["Name"].(string)
+	if name == "" {
+		return nil, client_errors.NewDataNotFoundError("Name")
+	}
+	sector, _ := response["Sector"].(string)
+	industry, _ := response["Industry"].(string)
+	exchange, _ := response["Exchange"].(string)
+
+	return &models.Company{
+		Symbol:        symbol,
+		Name:          name,
+		Sector:        sector,
+		Industry:      industry,
+		Exchange:      exchange,
+		ProfileSource: avc.GetProviderName(),
+	}, nil
+}
+
+// fetchTimeSeries requests one of the daily time series functions. The compact output is requested
+// when it reaches back to from.
+func (avc *AlphaVantageClient) fetchTimeSeries(ctx context.Context, function, symbol string, from time.Time) ([]*models.Stock, error) {
+	outputSize := "full"
+	if !from.IsZero() && time.Since(from) < compactOutputDays*24*time.Hour {
+		outputSize = "compact"
+	}
+	url := fmt.Sprintf("%s?function=%s&symbol=%s&outputsize=%s&apikey=%s",
+		avc.BaseURL, function, symbol, outputSize, avc.APIKey)
 
 	// Use the shared HTTP Request logic from BaseClient
-	response, err := avc.MakeRequest(url)
+	response, err := avc.MakeRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +192,13 @@ func (avc *AlphaVantageClient) parseAlphaVantageResponse(response map[string]any
 			LastUpdated:      time.Now(),
 		}
 
+		// TIME_SERIES_DAILY has no adjusted columns and reports the volume as "5. volume"
+		if _, unadjusted := dailyData["5. volume"]; unadjusted {
+			stock.AdjustedClose = stock.ClosePrice
+			stock.Volume = parseFloat(dailyData, "5. volume")
+			stock.SplitCoefficient = 1
+		}
+
 		stocks = append(stocks, stock)
 	}
 	return stocks, nil
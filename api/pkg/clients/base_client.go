@@ -1,8 +1,11 @@
 package clients
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"log"
 	"net/http"
 	"pocketanalyst/pkg/errors/client_errors"
 	"time"
@@ -10,9 +13,11 @@ import (
 
 // Struct for common HTTP Client functionality. Promotes code reuse.
 type BaseClient struct {
-	BaseURL string
-	APIKey  string
-	Client  *http.Client
+	BaseURL     string
+	APIKey      string
+	Client      *http.Client
+	RateLimiter *RateLimiter // Optional, throttles every outgoing request when set
+	RetryPolicy RetryPolicy  // Controls how transient failures are retried
 }
 
 func NewBaseClient(baseURL, apiKey string) *BaseClient {
@@ -22,28 +27,17 @@ func NewBaseClient(baseURL, apiKey string) *BaseClient {
 		Client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		RetryPolicy: DefaultRetryPolicy(),
 	}
 }
 
 // Common logic for making HTTP requests to an API, with all the error handling.
-func (bc *BaseClient) MakeRequest(url string) (map[string]any, error) {
-	// Make HTTP request. Return a HTTPRequestError if it fails.
-	resp, err := bc.Client.Get(url)
+// The request is bound to ctx, so it is aborted as soon as the caller gives up.
+func (bc *BaseClient) MakeRequest(ctx context.Context, url string) (map[string]any, error) {
+	// Make HTTP request, retrying transient failures
+	body, err := bc.fetchBody(ctx, url)
 	if err != nil {
-		return nil, client_errors.NewHTTPRequestError(url, err)
-	}
-	defer resp.Body.Close() // Ensure the response body is closed to prevent resource leaks.
-
-	// Check for successful HTTP response. Return a HTTPStatusError if it is not a successful response.
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, client_errors.NewHTTPStatusError(url, resp.StatusCode, string(body))
-	}
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, client_errors.NewResponseReadError(err)
+		return nil, err
 	}
 
 	// Parse the JSON response
@@ -63,33 +57,116 @@ func (bc *BaseClient) MakeRequest(url string) (map[string]any, error) {
 
 // MakeArrayRequest handles HTTP requests that return JSON arrays instead of objects.
 // Some APIs (like FMP) return arrays directly rather than wrapping them in objects.
-func (bc *BaseClient) MakeArrayRequest(url string) ([]map[string]any, error) {
-	// Make HTTP request with proper error wrapping
-	resp, err := bc.Client.Get(url)
+func (bc *BaseClient) MakeArrayRequest(ctx context.Context, url string) ([]map[string]any, error) {
+	// Make HTTP request, retrying transient failures
+	body, err := bc.fetchBody(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse JSON response as an array
+	var response []map[string]any
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, client_errors.NewResponseParseError(err)
+	}
+
+	return response, nil
+}
+
+// SetRateLimiter throttles all further requests made by this client through limiter.
+func (bc *BaseClient) SetRateLimiter(limiter *RateLimiter) {
+	bc.RateLimiter = limiter
+}
+
+// SetRetryPolicy replaces the policy used to retry transient failures.
+func (bc *BaseClient) SetRetryPolicy(policy RetryPolicy) {
+	bc.RetryPolicy = policy
+}
+
+// fetchBody downloads the body of url, retrying retryable failures according to the retry policy.
+// A Retry-After header on the failed response takes precedence over the computed backoff.
+func (bc *BaseClient) fetchBody(ctx context.Context, url string) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		recordRequest(ctx, attempt > 1)
+		body, err := bc.fetchOnce(ctx, url)
+		if err == nil {
+			return body, nil
+		}
+
+		// Give up on permanent errors, after the last attempt, or once the caller is gone
+		if !client_errors.IsRetryable(err) || attempt >= bc.RetryPolicy.MaxAttempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := bc.RetryPolicy.backoff(attempt)
+		var statusErr *client_errors.HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			// Waiting longer than we are willing to would only hold up the caller
+			if bc.RetryPolicy.MaxBackoff > 0 && statusErr.RetryAfter > bc.RetryPolicy.MaxBackoff {
+				return nil, err
+			}
+			delay = statusErr.RetryAfter
+		}
+
+		log.Printf("Retrying request to %s in %s (attempt %d of %d): %v",
+			bc.BaseURL, delay.Round(time.Millisecond), attempt+1, bc.RetryPolicy.MaxAttempts, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// fetchOnce makes a single rate limited request and returns the body of a successful response.
+func (bc *BaseClient) fetchOnce(ctx context.Context, url string) ([]byte, error) {
+	// Respect the provider's rate limits before making the request
+	if err := bc.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	// Make HTTP request. Return a HTTPRequestError if it fails.
+	resp, err := bc.get(ctx, url)
 	if err != nil {
 		return nil, client_errors.NewHTTPRequestError(url, err)
 	}
-	defer resp.Body.Close() // Ensure response body is closed to prevent resource leaks
+	defer resp.Body.Close() // Ensure the response body is closed to prevent resource leaks.
 
-	// Check for successful HTTP response status
+	// Check for successful HTTP response. Return a HTTPStatusError if it is not a successful response.
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, client_errors.NewHTTPStatusError(url, resp.StatusCode, string(body))
+		statusErr := client_errors.NewHTTPStatusError(url, resp.StatusCode, string(body))
+		statusErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, statusErr
 	}
 
-	// Read the entire response body
+	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, client_errors.NewResponseReadError(err)
 	}
 
-	// Parse JSON response as an array
-	var response []map[string]any
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, client_errors.NewResponseParseError(err)
+	return body, nil
+}
+
+// waitForRateLimit blocks until the rate limiter allows another request, if one is configured.
+func (bc *BaseClient) waitForRateLimit(ctx context.Context) error {
+	if bc.RateLimiter == nil {
+		return nil
 	}
+	return bc.RateLimiter.Wait(ctx)
+}
 
-	return response, nil
+// get issues a GET request for url that is cancelled together with ctx.
+func (bc *BaseClient) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bc.Client.Do(req)
 }
 
 // NOTE: The 'errorsKeys ...string' means accept zero or more string elements. Variadic parameter.
@@ -0,0 +1,84 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pocketanalyst/pkg/errors/client_errors"
+	"testing"
+	"time"
+)
+
+// newTestBaseClient creates a BaseClient pointing at server with backoffs short enough for tests.
+func newTestBaseClient(server *httptest.Server) *BaseClient {
+	client := NewBaseClient(server.URL, "test")
+	client.SetRetryPolicy(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	})
+	return client
+}
+
+// TestBaseClient_RetriesTransientFailures verifies 5xx and 429 responses are retried until one succeeds.
+func TestBaseClient_RetriesTransientFailures(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`[{"date": "2025-01-02", "close": 10}]`))
+		}
+	}))
+	defer server.Close()
+
+	ctx, stats := WithRequestStats(context.Background())
+	response, err := newTestBaseClient(server).MakeArrayRequest(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+	if attempts != 3 || len(response) != 1 {
+		t.Errorf("Expected 3 attempts and 1 element, got %d attempts and %d elements", attempts, len(response))
+	}
+	if stats.Requests() != 3 || stats.Retries() != 2 {
+		t.Errorf("Expected 3 requests and 2 retries to be recorded, got %d and %d", stats.Requests(), stats.Retries())
+	}
+}
+
+// TestBaseClient_DoesNotRetryPermanentFailures verifies client errors and explicit API errors fail immediately.
+func TestBaseClient_DoesNotRetryPermanentFailures(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Query().Get("symbol") == "INVALID" {
+			w.Write([]byte(`{"Error Message": "Invalid API call"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newTestBaseClient(server)
+
+	_, err := client.MakeRequest(context.Background(), server.URL)
+	var statusErr *client_errors.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.Retryable() {
+		t.Fatalf("Expected a non-retryable HTTPStatusError, got %v", err)
+	}
+
+	_, err = client.MakeRequest(context.Background(), server.URL+"?symbol=INVALID")
+	var apiErr *client_errors.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %v", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("Expected exactly one attempt per request, got %d attempts", attempts)
+	}
+}
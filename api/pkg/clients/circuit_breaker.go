@@ -0,0 +1,181 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"log"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors/client_errors"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Requests flow normally
+	CircuitOpen     CircuitState = "open"      // Requests are rejected until the cooldown passes
+	CircuitHalfOpen CircuitState = "half_open" // A single trial request decides whether to close again
+)
+
+// CircuitBreakerClient wraps a StockDataClient and stops calling it after too many consecutive failures.
+// While open, requests fail immediately with a CircuitOpenError instead of waiting on a provider that is down.
+// After the cooldown, one trial request is let through. Its outcome either closes the breaker or opens it again.
+//
+// Only transient failures (see client_errors.ClientError.Retryable) count against the provider. Errors such as
+// an invalid symbol prove the provider is reachable and reset the failure count. Rate limit and circuit open
+// errors say nothing about the provider's health and leave the breaker as it is.
+type CircuitBreakerClient struct {
+	client           StockDataClient
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time // Replaceable clock for tests
+
+	mu                  sync.Mutex
+	state               CircuitState
+	consecutiveFailures int
+	openedAt            time.Time
+	trialInFlight       bool
+	lastError           string
+}
+
+// NewCircuitBreakerClient wraps client with a circuit breaker that opens after failureThreshold consecutive
+// failures and half-opens after cooldown.
+func NewCircuitBreakerClient(client StockDataClient, failureThreshold int, cooldown time.Duration) *CircuitBreakerClient {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+
+	return &CircuitBreakerClient{
+		client:           client,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		now:              time.Now,
+		state:            CircuitClosed,
+	}
+}
+
+// GetProviderName returns the name of the wrapped provider.
+func (cbc *CircuitBreakerClient) GetProviderName() string {
+	return cbc.client.GetProviderName()
+}
+
+// SupportsDateRange reports whether the wrapped client supports date ranges.
+func (cbc *CircuitBreakerClient) SupportsDateRange() bool {
+	return SupportsDateRange(cbc.client)
+}
+
+// FetchDailyRange calls the wrapped client unless the breaker is open.
+func (cbc *CircuitBreakerClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
+	if err := cbc.allow(); err != nil {
+		return nil, err
+	}
+
+	stocks, err := cbc.client.FetchDailyRange(ctx, symbol, from, to)
+	cbc.record(err)
+	return stocks, err
+}
+
+// State returns the current state of the breaker.
+func (cbc *CircuitBreakerClient) State() CircuitState {
+	cbc.mu.Lock()
+	defer cbc.mu.Unlock()
+
+	return cbc.state
+}
+
+// ProviderHealth reports the state of the breaker for the wrapped provider.
+func (cbc *CircuitBreakerClient) ProviderHealth() []ProviderHealth {
+	cbc.mu.Lock()
+	defer cbc.mu.Unlock()
+
+	health := ProviderHealth{
+		Provider:            cbc.client.GetProviderName(),
+		State:               cbc.state,
+		ConsecutiveFailures: cbc.consecutiveFailures,
+		LastError:           cbc.lastError,
+	}
+	if cbc.state == CircuitOpen {
+		health.RetryAfterSeconds = int(cbc.remainingCooldown().Round(time.Second).Seconds())
+	}
+	return []ProviderHealth{health}
+}
+
+// allow decides whether a request may go through, moving an open breaker to half-open once the cooldown passed.
+func (cbc *CircuitBreakerClient) allow() error {
+	cbc.mu.Lock()
+	defer cbc.mu.Unlock()
+
+	switch cbc.state {
+	case CircuitOpen:
+		if remaining := cbc.remainingCooldown(); remaining > 0 {
+			return client_errors.NewCircuitOpenError(cbc.client.GetProviderName(), remaining)
+		}
+		cbc.state = CircuitHalfOpen
+		cbc.trialInFlight = true
+		log.Printf("Circuit breaker for %s is half-open, sending a trial request", cbc.client.GetProviderName())
+	case CircuitHalfOpen:
+		// Only one trial request at a time, everyone else keeps failing fast until it completes
+		if cbc.trialInFlight {
+			return client_errors.NewCircuitOpenError(cbc.client.GetProviderName(), 0)
+		}
+		cbc.trialInFlight = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a request.
+func (cbc *CircuitBreakerClient) record(err error) {
+	cbc.mu.Lock()
+	defer cbc.mu.Unlock()
+
+	cbc.trialInFlight = false
+
+	// A caller giving up says nothing about the provider
+	if errors.Is(err, context.Canceled) {
+		if cbc.state == CircuitHalfOpen {
+			cbc.state = CircuitOpen
+		}
+		return
+	}
+
+	// Neither does a request held back by a rate limit or another breaker, the next request is the trial
+	var rateLimitErr *client_errors.RateLimitExceededError
+	var circuitErr *client_errors.CircuitOpenError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &circuitErr) {
+		return
+	}
+
+	if err == nil || !client_errors.IsRetryable(err) {
+		if cbc.state != CircuitClosed {
+			log.Printf("Circuit breaker for %s closed", cbc.client.GetProviderName())
+		}
+		cbc.state = CircuitClosed
+		cbc.consecutiveFailures = 0
+		cbc.lastError = ""
+		return
+	}
+
+	cbc.consecutiveFailures++
+	cbc.lastError = err.Error()
+
+	// A failed trial re-opens the breaker immediately
+	if cbc.state == CircuitHalfOpen || cbc.consecutiveFailures >= cbc.failureThreshold {
+		if cbc.state != CircuitOpen {
+			log.Printf("Circuit breaker for %s opened after %d consecutive failures: %v",
+				cbc.client.GetProviderName(), cbc.consecutiveFailures, err)
+		}
+		cbc.state = CircuitOpen
+		cbc.openedAt = cbc.now()
+	}
+}
+
+// remainingCooldown returns how long an open breaker stays open. Callers must hold mu.
+func (cbc *CircuitBreakerClient) remainingCooldown() time.Duration {
+	remaining := cbc.cooldown - cbc.now().Sub(cbc.openedAt)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
@@ -0,0 +1,116 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors/client_errors"
+	"testing"
+	"time"
+)
+
+// stubClient is a StockDataClient returning a fixed error, counting how often it was called.
+type stubClient struct {
+	name   string
+	stocks []*models.Stock
+	err    error
+	calls  int
+}
+
+func (sc *stubClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
+	sc.calls++
+	return sc.stocks, sc.err
+}
+
+func (sc *stubClient) GetProviderName() string {
+	return sc.name
+}
+
+// TestCircuitBreakerClient_OpensAndRecovers walks the breaker through closed, open, half-open and back to closed.
+func TestCircuitBreakerClient_OpensAndRecovers(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	stub := &stubClient{name: "FMP", err: client_errors.NewHTTPStatusError("url", 503, "")}
+	breaker := NewCircuitBreakerClient(stub, 2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+	}
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected breaker to open after 2 failures, got %s", breaker.State())
+	}
+
+	// While open, the provider must not be called
+	_, err := breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+	var circuitErr *client_errors.CircuitOpenError
+	if !errors.As(err, &circuitErr) || stub.calls != 2 {
+		t.Fatalf("Expected CircuitOpenError without calling the provider, got %v after %d calls", err, stub.calls)
+	}
+
+	// After the cooldown a successful trial closes the breaker again
+	now = now.Add(time.Minute)
+	stub.err = nil
+	if _, err := breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("Expected trial request to succeed, got %v", err)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected breaker to close after a successful trial, got %s", breaker.State())
+	}
+}
+
+// TestCircuitBreakerClient_IgnoresPermanentErrors verifies errors like an invalid symbol don't open the breaker.
+func TestCircuitBreakerClient_IgnoresPermanentErrors(t *testing.T) {
+	stub := &stubClient{name: "FMP", err: client_errors.NewAPIError("Invalid symbol")}
+	breaker := NewCircuitBreakerClient(stub, 1, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		breaker.FetchDailyRange(context.Background(), "INVALID", time.Time{}, time.Time{})
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected breaker to stay closed on API errors, got %s", breaker.State())
+	}
+}
+
+// TestCircuitBreakerClient_IgnoresNeutralErrors verifies rate limit and circuit open errors neither close nor
+// open the breaker, and release a half-open breaker's trial for the next request.
+func TestCircuitBreakerClient_IgnoresNeutralErrors(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	stub := &stubClient{name: "FMP", err: client_errors.NewHTTPStatusError("url", 503, "")}
+	breaker := NewCircuitBreakerClient(stub, 2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	neutral := []error{
+		client_errors.NewRateLimitExceededError("FMP", "minute", 5, time.Minute),
+		client_errors.NewCircuitOpenError("FMP", time.Minute),
+	}
+
+	// Neutral errors between two failures don't reset the failure count
+	breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+	for _, err := range neutral {
+		stub.err = err
+		breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+	}
+	stub.err = client_errors.NewHTTPStatusError("url", 503, "")
+	breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected breaker to open after 2 failures, got %s", breaker.State())
+	}
+
+	// A neutral trial keeps the breaker half-open and lets the next request be the trial
+	now = now.Add(time.Minute)
+	for _, err := range neutral {
+		stub.err = err
+		breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+		if breaker.State() != CircuitHalfOpen {
+			t.Errorf("Expected breaker to stay half-open after %T, got %s", err, breaker.State())
+		}
+	}
+	calls := stub.calls
+	stub.err = nil
+	if _, err := breaker.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{}); err != nil || stub.calls != calls+1 {
+		t.Fatalf("Expected the next request to be sent as trial, got %v", err)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected the successful trial to close the breaker, got %s", breaker.State())
+	}
+}
@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"pocketanalyst/pkg/errors"
+	"pocketanalyst/pkg/errors/client_errors"
+	"testing"
+)
+
+// TestFMPClient_FetchCompanyProfile verifies the name, sector, industry and exchange are read from the profile endpoint.
+func TestFMPClient_FetchCompanyProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stable/profile" || r.URL.Query().Get("symbol") != "AAPL" {
+			t.Errorf("Expected the AAPL profile to be requested, got %s", r.URL)
+		}
+		w.Write([]byte(`[{"symbol": "AAPL", "companyName": "Apple Inc.", "sector": "Technology",
+			"industry": "Consumer Electronics", "exchange": "NASDAQ", "exchangeFullName": "NASDAQ Global Select"}]`))
+	}))
+	defer server.Close()
+
+	client := NewFMPClient(server.URL, "test")
+	profile, err := client.FetchCompanyProfile(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected the profile to be fetched, got %v", err)
+	}
+
+	if profile.Name != "Apple Inc." || profile.Sector != "Technology" || profile.Industry != "Consumer Electronics" ||
+		profile.Exchange != "NASDAQ" || profile.ProfileSource != "FMP" {
+		t.Errorf("Expected Apple's profile from FMP, got %+v", profile)
+	}
+}
+
+// TestAlphaVantageClient_FetchCompanyProfile verifies the profile is read from OVERVIEW, and that the empty
+// object returned for unknown symbols is reported as missing data.
+func TestAlphaVantageClient_FetchCompanyProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("function") != "OVERVIEW" {
+			t.Errorf("Expected OVERVIEW, got %s", r.URL.Query().Get("function"))
+		}
+		if r.URL.Query().Get("symbol") != "IBM" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"Symbol": "IBM", "Name": "International Business Machines", "Exchange": "NYSE",
+			"Sector": "TECHNOLOGY", "Industry": "COMPUTER & OFFICE EQUIPMENT"}`))
+	}))
+	defer server.Close()
+
+	client := NewAlphaVantageClient(server.URL, "test")
+	profile, err := client.FetchCompanyProfile(context.Background(), "IBM")
+	if err != nil {
+		t.Fatalf("Expected the profile to be fetched, got %v", err)
+	}
+	if profile.Name != "International Business Machines" || profile.Sector != "TECHNOLOGY" ||
+		profile.Exchange != "NYSE" || profile.ProfileSource != "AlphaVantage" {
+		t.Errorf("Expected IBM's profile from AlphaVantage, got %+v", profile)
+	}
+
+	var notFound *client_errors.DataNotFoundError
+	if _, err := client.FetchCompanyProfile(context.Background(), "NOPE"); !errors.As(err, &notFound) {
+		t.Errorf("Expected a DataNotFoundError for an unknown symbol, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors"
+	"pocketanalyst/pkg/errors/client_errors"
+	"testing"
+	"time"
+)
+
+// TestFMPClient_FetchCorporateActions verifies dividends and splits are read from their endpoints,
+// with split ratios computed from numerator and denominator.
+func TestFMPClient_FetchCorporateActions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable/dividends":
+			w.Write([]byte(`[{"date": "2024-08-12", "dividend": 0.25, "adjDividend": 0.25},
+				{"date": "2019-05-10", "dividend": 0.77, "adjDividend": 0.1925}]`))
+		case "/stable/splits":
+			w.Write([]byte(`[{"date": "2020-08-31", "numerator": 4, "denominator": 1}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewFMPClient(server.URL, "test")
+	actions, err := client.FetchCorporateActions(context.Background(), "AAPL",
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil {
+		t.Fatalf("Expected the actions to be fetched, got %v", err)
+	}
+
+	if len(actions) != 2 {
+		t.Fatalf("Expected the 2024 dividend and the 2020 split, got %d actions", len(actions))
+	}
+	if actions[0].ActionType != models.CorporateActionDividend || actions[0].Amount != 0.25 || actions[0].Source != "FMP" {
+		t.Errorf("Expected a 0.25 dividend from FMP, got %+v", actions[0])
+	}
+	if actions[1].ActionType != models.CorporateActionSplit || actions[1].Ratio != 4 {
+		t.Errorf("Expected a 4-for-1 split, got %+v", actions[1])
+	}
+}
+
+// TestFMPClient_FetchCorporateActions_NoSplits verifies the empty array FMP returns for a symbol that never
+// split is read as no splits, while an error payload still fails the fetch.
+func TestFMPClient_FetchCorporateActions_NoSplits(t *testing.T) {
+	splits := `[]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable/dividends":
+			w.Write([]byte(`[{"date": "2024-08-12", "dividend": 0.25, "adjDividend": 0.25}]`))
+		case "/stable/splits":
+			w.Write([]byte(splits))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewFMPClient(server.URL, "test")
+	actions, err := client.FetchCorporateActions(context.Background(), "MSFT", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected the actions to be fetched, got %v", err)
+	}
+	if len(actions) != 1 || actions[0].ActionType != models.CorporateActionDividend {
+		t.Errorf("Expected only the dividend, got %+v", actions)
+	}
+
+	splits = `[{"Error Message": "Invalid API KEY."}]`
+	var apiErr *client_errors.APIError
+	if _, err := client.FetchCorporateActions(context.Background(), "MSFT", time.Time{}, time.Time{}); !errors.As(err, &apiErr) {
+		t.Errorf("Expected an APIError for the error payload, got %v", err)
+	}
+}
+
+// TestAlphaVantageClient_FetchCorporateActions verifies actions are taken from the dividend amount and
+// split coefficient columns of the adjusted time series.
+func TestAlphaVantageClient_FetchCorporateActions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("function") != "TIME_SERIES_DAILY_ADJUSTED" {
+			t.Errorf("Expected TIME_SERIES_DAILY_ADJUSTED, got %s", r.URL.Query().Get("function"))
+		}
+		w.Write([]byte(`{"Time Series (Daily)": {
+			"2020-08-31": {"4. close": "129.04", "7. dividend amount": "0.0000", "8. split coefficient": "4.0"},
+			"2020-08-07": {"4. close": "444.45", "7. dividend amount": "0.8200", "8. split coefficient": "1.0"},
+			"2020-08-06": {"4. close": "455.61", "7. dividend amount": "0.0000", "8. split coefficient": "1.0"}
+		}}`))
+	}))
+	defer server.Close()
+
+	client := NewAlphaVantageClient(server.URL, "test")
+	actions, err := client.FetchCorporateActions(context.Background(), "AAPL", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected the actions to be fetched, got %v", err)
+	}
+
+	if len(actions) != 2 {
+		t.Fatalf("Expected a split and a dividend, got %d actions", len(actions))
+	}
+	if actions[0].ActionType != models.CorporateActionSplit || actions[0].Ratio != 4 {
+		t.Errorf("Expected a 4-for-1 split, got %+v", actions[0])
+	}
+	if actions[1].ActionType != models.CorporateActionDividend || actions[1].Amount != 0.82 {
+		t.Errorf("Expected a 0.82 dividend, got %+v", actions[1])
+	}
+}
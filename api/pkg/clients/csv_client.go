@@ -0,0 +1,215 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors/client_errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVClient implements the StockDataClient interface for Yahoo-style CSV exports with the layout
+// Date,Open,High,Low,Close,Adj Close,Volume.
+//
+// BaseURL is either an http(s) URL or a local directory (optionally prefixed with file://), so the client
+// also works offline. Each symbol is read from <BaseURL>/<SYMBOL>.csv, unless BaseURL contains a {symbol}
+// placeholder, in which case the placeholder is replaced with the symbol instead.
+type CSVClient struct {
+	*BaseClient // Embedded struct provides all Base HTTP Functionality.
+}
+
+// csvSymbolPattern matches the ticker symbols a CSV may be read for, including index (^GSPC), currency (EURUSD=X)
+// and share class (BRK-B, BRK.B) symbols. Anything else, like path separators, never reaches a path or URL.
+var csvSymbolPattern = regexp.MustCompile(`^[A-Za-z0-9.\-^=]{1,20}$`)
+
+func NewCSVClient(baseURL string) *CSVClient {
+	return &CSVClient{
+		BaseClient: NewBaseClient(baseURL, ""),
+	}
+}
+
+func (cc *CSVClient) GetProviderName() string {
+	return "CSV"
+}
+
+// FetchDaily reads the full daily history for symbol.
+func (cc *CSVClient) FetchDaily(symbol string) ([]*models.Stock, error) {
+	return cc.FetchDailyRange(context.Background(), symbol, time.Time{}, time.Time{})
+}
+
+// FetchDailyRange reads the CSV for symbol and returns the bars between from and to.
+func (cc *CSVClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
+	location, err := cc.location(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if isHTTPURL(location) {
+		// Use the shared HTTP Request logic from BaseClient
+		data, err = cc.fetchBody(ctx, location)
+	} else {
+		data, err = os.ReadFile(location)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, client_errors.NewDataNotFoundError(location)
+		}
+		if err != nil {
+			err = client_errors.NewResponseReadError(err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stocks, err := cc.parseCSV(bytes.NewReader(data), symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterByDateRange(stocks, from, to), nil
+}
+
+// location returns the URL or file path holding the CSV for symbol. Symbols are escaped in URLs, and file
+// paths must stay inside the configured directory.
+func (cc *CSVClient) location(symbol string) (string, error) {
+	if !csvSymbolPattern.MatchString(symbol) || strings.Trim(symbol, ".") == "" {
+		return "", client_errors.NewAPIError(fmt.Sprintf("invalid symbol %q", symbol))
+	}
+
+	if isHTTPURL(cc.BaseURL) {
+		if strings.Contains(cc.BaseURL, "{symbol}") {
+			return strings.ReplaceAll(cc.BaseURL, "{symbol}", url.PathEscape(symbol)), nil
+		}
+		return strings.TrimSuffix(cc.BaseURL, "/") + "/" + url.PathEscape(symbol) + ".csv", nil
+	}
+
+	base := strings.TrimPrefix(cc.BaseURL, "file://")
+	var dir, path string
+	if prefix, _, found := strings.Cut(base, "{symbol}"); found {
+		// The directory is the part of the template before the symbol
+		dir = filepath.Dir(prefix)
+		path = filepath.Clean(strings.ReplaceAll(base, "{symbol}", symbol))
+	} else {
+		dir = filepath.Clean(base)
+		path = filepath.Join(dir, symbol+".csv")
+	}
+
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", client_errors.NewAPIError(fmt.Sprintf("invalid symbol %q", symbol))
+	}
+	return path, nil
+}
+
+// isHTTPURL reports whether location is downloaded rather than read from disk.
+func isHTTPURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// parseCSV converts the CSV export into Stock models, newest first like the other providers.
+// Columns are matched by their header name, so their order does not matter. Rows with null or
+// missing prices (Yahoo emits those for non-trading days) are skipped.
+func (cc *CSVClient) parseCSV(r io.Reader, symbol string) ([]*models.Stock, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // Tolerate trailing columns and short rows, they are checked below
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, client_errors.NewResponseParseError(fmt.Errorf("reading CSV header: %w", err))
+	}
+
+	// Index the columns by lowercase name, dropping the byte order mark some exports start with
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"date", "open", "high", "low", "close", "volume"} {
+		if _, ok := columns[required]; !ok {
+			return nil, client_errors.NewResponseParseError(fmt.Errorf("CSV is missing the %q column", required))
+		}
+	}
+
+	stocks := []*models.Stock{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, client_errors.NewResponseParseError(err)
+		}
+
+		date, err := time.Parse("2006-01-02", csvField(record, columns, "date"))
+		if err != nil {
+			continue // Skip dates we can't parse
+		}
+
+		open, okOpen := csvFloat(record, columns, "open")
+		high, okHigh := csvFloat(record, columns, "high")
+		low, okLow := csvFloat(record, columns, "low")
+		closePrice, okClose := csvFloat(record, columns, "close")
+		volume, okVolume := csvFloat(record, columns, "volume")
+		if !okOpen || !okHigh || !okLow || !okClose || !okVolume {
+			continue // Skip null rows
+		}
+
+		// Use the real adjusted close when the export has one
+		adjustedClose, ok := csvFloat(record, columns, "adj close")
+		if !ok {
+			adjustedClose = closePrice
+		}
+
+		stocks = append(stocks, &models.Stock{
+			Symbol:           symbol,
+			Date:             date,
+			OpenPrice:        open,
+			HighPrice:        high,
+			LowPrice:         low,
+			ClosePrice:       closePrice,
+			AdjustedClose:    adjustedClose,
+			Volume:           volume,
+			DividendAmount:   0, // Not available in this layout
+			SplitCoefficient: 1, // Not available in this layout
+			DataSource:       cc.GetProviderName(),
+			LastUpdated:      time.Now(),
+		})
+	}
+
+	// Sort in descending order (newest first)
+	sort.Slice(stocks, func(i, j int) bool { return stocks[i].Date.After(stocks[j].Date) })
+	return stocks, nil
+}
+
+// csvField returns the trimmed value of the named column, or "" if the row is too short.
+func csvField(record []string, columns map[string]int, name string) string {
+	index, ok := columns[name]
+	if !ok || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+// csvFloat parses the named column. ok is false for missing, "null" or otherwise invalid values.
+func csvFloat(record []string, columns map[string]int, name string) (float64, bool) {
+	value := csvField(record, columns, name)
+	if value == "" || strings.EqualFold(value, "null") {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pocketanalyst/pkg/errors"
+	"pocketanalyst/pkg/errors/client_errors"
+	"testing"
+	"time"
+)
+
+const testCSV = `Date,Open,High,Low,Close,Adj Close,Volume
+2024-06-07,194.65,196.94,194.14,196.89,196.40,53103900
+2024-06-10,null,null,null,null,null,null
+2024-06-11,193.65,207.16,193.63,207.15,206.64,172373300
+`
+
+// TestCSVClient_LocalDirectory verifies the Yahoo layout is parsed from a local directory,
+// keeping the real adjusted close and skipping null rows.
+func TestCSVClient_LocalDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "AAPL.csv"), []byte(testCSV), 0o644); err != nil {
+		t.Fatalf("Failed to write test CSV: %v", err)
+	}
+
+	stocks, err := NewCSVClient(dir).FetchDaily("AAPL")
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+
+	if len(stocks) != 2 {
+		t.Fatalf("Expected 2 stocks after skipping the null row, got %d", len(stocks))
+	}
+
+	newest := stocks[0]
+	if newest.Date.Format("2006-01-02") != "2024-06-11" {
+		t.Errorf("Expected newest stock first, got %s", newest.Date.Format("2006-01-02"))
+	}
+	if newest.ClosePrice != 207.15 || newest.AdjustedClose != 206.64 || newest.Volume != 172373300 {
+		t.Errorf("Unexpected values parsed: %s adjusted %.2f", newest, newest.AdjustedClose)
+	}
+	if newest.DataSource != "CSV" {
+		t.Errorf("Expected data source 'CSV', got '%s'", newest.DataSource)
+	}
+}
+
+// TestCSVClient_HTTP verifies the CSV is downloaded through the {symbol} placeholder and filtered by date.
+func TestCSVClient_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/exports/AAPL" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(testCSV))
+	}))
+	defer server.Close()
+
+	from := time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC)
+	client := NewCSVClient(server.URL + "/exports/{symbol}")
+	stocks, err := client.FetchDailyRange(context.Background(), "AAPL", from, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to download CSV: %v", err)
+	}
+
+	if len(stocks) != 1 || stocks[0].Date.Before(from) {
+		t.Errorf("Expected only the bar after %s, got %v", from.Format("2006-01-02"), stocks)
+	}
+}
+
+// TestCSVClient_PathTraversal verifies symbols can't read files outside the configured directory,
+// and that symbols are escaped in URLs.
+func TestCSVClient_PathTraversal(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "exports")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret.csv"), []byte(testCSV), 0o644); err != nil {
+		t.Fatalf("Failed to write test CSV: %v", err)
+	}
+
+	for _, baseURL := range []string{dir, "file://" + dir, filepath.Join(dir, "{symbol}.csv"), filepath.Join(dir, "{symbol}")} {
+		client := NewCSVClient(baseURL)
+		for _, symbol := range []string{"../secret", "..", "../../secret", "/secret", "AAPL/../../secret", ""} {
+			var apiErr *client_errors.APIError
+			if _, err := client.FetchDaily(symbol); !errors.As(err, &apiErr) {
+				t.Errorf("Expected %q to be rejected for %s, got %v", symbol, baseURL, err)
+			}
+		}
+	}
+
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.EscapedPath()
+		w.Write([]byte(testCSV))
+	}))
+	defer server.Close()
+
+	if _, err := NewCSVClient(server.URL + "/exports/{symbol}").FetchDaily("^GSPC"); err != nil {
+		t.Fatalf("Failed to download CSV: %v", err)
+	}
+	if requested != "/exports/%5EGSPC" {
+		t.Errorf("Expected the symbol to be escaped, got %s", requested)
+	}
+}
@@ -25,6 +25,18 @@ func (cf *ClientFactory) RegisterProvider(name, baseURL, apiKey string) {
 	}
 }
 
+// SetRetryPolicy configures how clients for a registered provider retry transient failures.
+func (cf *ClientFactory) SetRetryPolicy(name string, policy RetryPolicy) error {
+	config, exists := cf.config[strings.ToLower(name)]
+	if !exists {
+		return fmt.Errorf("Unknown provider: %s", name)
+	}
+
+	config.RetryPolicy = &policy
+	cf.config[strings.ToLower(name)] = config
+	return nil
+}
+
 // CreateClient creates a client for the specified provider. Return an error if the provider is not registered or implemented.
 func (cf *ClientFactory) CreateClient(providerName string) (StockDataClient, error) {
 	config, exists := cf.config[strings.ToLower(providerName)]
@@ -33,14 +45,24 @@ func (cf *ClientFactory) CreateClient(providerName string) (StockDataClient, err
 	}
 
 	// Create the appropriate client implementation
+	var client StockDataClient
 	switch strings.ToLower(providerName) {
 	case "alphavantage":
-		return NewAlphaVantageClient(config.BaseURL, config.APIKey), nil
+		client = NewAlphaVantageClient(config.BaseURL, config.APIKey)
 	case "fmp":
-		return NewFMPClient(config.BaseURL, config.APIKey), nil
+		client = NewFMPClient(config.BaseURL, config.APIKey)
+	case "csv":
+		client = NewCSVClient(config.BaseURL)
 	default:
 		return nil, fmt.Errorf("Provider %s not implemented", providerName)
 	}
+
+	// Apply the provider specific retry policy, if one was configured
+	if retrying, ok := client.(RetryingClient); ok && config.RetryPolicy != nil {
+		retrying.SetRetryPolicy(*config.RetryPolicy)
+	}
+
+	return client, nil
 }
 
 // GetRegisteredProviders returns a list of all registered provider names.
@@ -0,0 +1,114 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"pocketanalyst/internal/models"
+	"strings"
+	"time"
+)
+
+// FailoverClient is a StockDataClient composed of several providers in order of preference.
+// Each request goes to the first provider, falling back to the next one whenever a provider errors
+// or returns no data for a full history request. The stocks are tagged with the provider that actually
+// served them, so they are stored under that provider's source_id.
+type FailoverClient struct {
+	clients []StockDataClient
+}
+
+// NewFailoverClient creates a FailoverClient trying clients in the given order.
+func NewFailoverClient(clients ...StockDataClient) *FailoverClient {
+	return &FailoverClient{clients: clients}
+}
+
+// GetProviderName returns the names of all providers in order of preference, e.g. "FMP,AlphaVantage".
+func (fc *FailoverClient) GetProviderName() string {
+	return strings.Join(fc.Providers(), ",")
+}
+
+// Providers returns the name of every provider behind this client in order of preference.
+func (fc *FailoverClient) Providers() []string {
+	names := make([]string, 0, len(fc.clients))
+	for _, client := range fc.clients {
+		names = append(names, client.GetProviderName())
+	}
+	return names
+}
+
+// Preferring returns a FailoverClient over the same providers that tries the named provider first. The order of
+// the others is kept. An unknown provider leaves the order as it is.
+func (fc *FailoverClient) Preferring(provider string) *FailoverClient {
+	clients := make([]StockDataClient, 0, len(fc.clients))
+	for _, client := range fc.clients {
+		if client.GetProviderName() == provider {
+			clients = append(clients, client)
+		}
+	}
+	for _, client := range fc.clients {
+		if client.GetProviderName() != provider {
+			clients = append(clients, client)
+		}
+	}
+	return &FailoverClient{clients: clients}
+}
+
+// SupportsDateRange reports whether the preferred provider supports date ranges, as it serves the requests
+// unless it fails.
+func (fc *FailoverClient) SupportsDateRange() bool {
+	return len(fc.clients) > 0 && SupportsDateRange(fc.clients[0])
+}
+
+// FetchDailyRange returns the bars of the first provider that doesn't fail. Without a from date, providers
+// returning no data are skipped too. If every provider fails, the errors of all providers are returned
+// joined together.
+func (fc *FailoverClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
+	var errs []error
+	for _, client := range fc.clients {
+		provider := client.GetProviderName()
+
+		stocks, err := client.FetchDailyRange(ctx, symbol, from, to)
+		if err != nil {
+			// No point in trying other providers once the caller is gone
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.Printf("Provider %s failed for %s, trying next provider: %v", provider, symbol, err)
+			errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+			continue
+		}
+
+		// An empty range starting at from usually means there is nothing new yet, which the other providers
+		// can't change. Only an empty full history means the provider doesn't know the symbol.
+		if len(stocks) == 0 {
+			if !from.IsZero() {
+				return stocks, nil
+			}
+			log.Printf("Provider %s returned no data for %s, trying next provider", provider, symbol)
+			continue
+		}
+
+		// Record which provider served the data so it lands under the correct source_id
+		for _, stock := range stocks {
+			stock.DataSource = provider
+		}
+		return stocks, nil
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return []*models.Stock{}, nil
+}
+
+// ProviderHealth reports the health of every provider that tracks it.
+func (fc *FailoverClient) ProviderHealth() []ProviderHealth {
+	health := []ProviderHealth{}
+	for _, client := range fc.clients {
+		if reporter, ok := client.(HealthReporter); ok {
+			health = append(health, reporter.ProviderHealth()...)
+		}
+	}
+	return health
+}
@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors/client_errors"
+	"testing"
+	"time"
+)
+
+// TestFailoverClient_FallsBack verifies errors and empty responses fall through to the next provider,
+// and that the served data is tagged with the provider that returned it.
+func TestFailoverClient_FallsBack(t *testing.T) {
+	failing := &stubClient{name: "FMP", err: client_errors.NewHTTPStatusError("url", 500, "")}
+	empty := &stubClient{name: "CSV", stocks: []*models.Stock{}}
+	serving := &stubClient{name: "AlphaVantage", stocks: []*models.Stock{{Symbol: "AAPL", DataSource: "unset"}}}
+
+	client := NewFailoverClient(failing, empty, serving)
+	stocks, err := client.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected the third provider to serve the request, got %v", err)
+	}
+	if len(stocks) != 1 || stocks[0].DataSource != "AlphaVantage" {
+		t.Errorf("Expected 1 stock served by AlphaVantage, got %v", stocks)
+	}
+}
+
+// TestFailoverClient_AllFail verifies the errors of every provider are returned when none succeeds.
+func TestFailoverClient_AllFail(t *testing.T) {
+	client := NewFailoverClient(
+		&stubClient{name: "FMP", err: client_errors.NewCircuitOpenError("FMP", time.Minute)},
+		&stubClient{name: "AlphaVantage", err: client_errors.NewAPIError("Invalid symbol")},
+	)
+
+	_, err := client.FetchDailyRange(context.Background(), "INVALID", time.Time{}, time.Time{})
+	var circuitErr *client_errors.CircuitOpenError
+	var apiErr *client_errors.APIError
+	if !errors.As(err, &circuitErr) || !errors.As(err, &apiErr) {
+		t.Errorf("Expected both provider errors to be returned, got %v", err)
+	}
+}
+
+// TestFailoverClient_SupportsDateRange verifies range support is reported for the preferred provider, through
+// its circuit breaker.
+func TestFailoverClient_SupportsDateRange(t *testing.T) {
+	fmp := NewCircuitBreakerClient(NewFMPClient("url", "key"), 3, time.Minute)
+	alphaVantage := NewCircuitBreakerClient(NewAlphaVantageClient("url", "key"), 3, time.Minute)
+
+	if !SupportsDateRange(NewFailoverClient(fmp, alphaVantage)) {
+		t.Error("Expected date range support with FMP preferred")
+	}
+	if SupportsDateRange(NewFailoverClient(alphaVantage, fmp)) {
+		t.Error("Expected no date range support with Alpha Vantage preferred")
+	}
+	if SupportsDateRange(NewFailoverClient()) || SupportsDateRange(&stubClient{name: "CSV"}) {
+		t.Error("Expected no date range support without a provider supporting it")
+	}
+}
+
+// TestFailoverClient_EmptyIncrementalRange verifies an empty range with a from date is returned as-is instead
+// of being requested from the other providers.
+func TestFailoverClient_EmptyIncrementalRange(t *testing.T) {
+	empty := &stubClient{name: "FMP", stocks: []*models.Stock{}}
+	serving := &stubClient{name: "AlphaVantage", stocks: []*models.Stock{{Symbol: "AAPL"}}}
+	client := NewFailoverClient(empty, serving)
+
+	from := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	stocks, err := client.FetchDailyRange(context.Background(), "AAPL", from, time.Time{})
+	if err != nil || len(stocks) != 0 || serving.calls != 0 {
+		t.Errorf("Expected no stocks without asking AlphaVantage, got %v, %v after %d calls", stocks, err, serving.calls)
+	}
+
+	// Errors still fall through to the next provider
+	empty.err = client_errors.NewHTTPStatusError("url", 500, "")
+	stocks, err = client.FetchDailyRange(context.Background(), "AAPL", from, time.Time{})
+	if err != nil || len(stocks) != 1 || serving.calls != 1 {
+		t.Errorf("Expected AlphaVantage to serve the request, got %v, %v", stocks, err)
+	}
+}
+
+// TestPreferProvider verifies the preferred provider is tried first while the others remain the fallback.
+func TestPreferProvider(t *testing.T) {
+	fmp := &stubClient{name: "FMP", stocks: []*models.Stock{{Symbol: "AAPL"}}}
+	csv := &stubClient{name: "CSV", err: client_errors.NewAPIError("file missing")}
+	alphaVantage := &stubClient{name: "AlphaVantage", stocks: []*models.Stock{{Symbol: "AAPL"}}}
+	client := NewFailoverClient(fmp, csv, alphaVantage)
+
+	preferred := PreferProvider(client, "CSV")
+	if name := preferred.GetProviderName(); name != "CSV,FMP,AlphaVantage" {
+		t.Errorf("Expected CSV to be tried first, got %s", name)
+	}
+	if name := client.GetProviderName(); name != "FMP,CSV,AlphaVantage" {
+		t.Errorf("Expected the original order to be kept, got %s", name)
+	}
+
+	stocks, err := preferred.FetchDailyRange(context.Background(), "AAPL", time.Time{}, time.Time{})
+	if err != nil || len(stocks) != 1 || stocks[0].DataSource != "FMP" || csv.calls != 1 {
+		t.Errorf("Expected FMP to serve the request after CSV failed, got %v, %v", stocks, err)
+	}
+
+	if name := PreferProvider(client, "Unknown").GetProviderName(); name != "FMP,CSV,AlphaVantage" {
+		t.Errorf("Expected an unknown provider to keep the order, got %s", name)
+	}
+	if PreferProvider(fmp, "CSV") != StockDataClient(fmp) {
+		t.Error("Expected a single provider to be returned as it is")
+	}
+}
@@ -1,8 +1,10 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors/client_errors"
 	"strconv"
 	"time"
 )
@@ -21,12 +23,30 @@ func (fmpc *FMPClient) GetProviderName() string {
 	return "FMP"
 }
 
+// SupportsDateRange reports that FMP filters date ranges server-side.
+func (fmpc *FMPClient) SupportsDateRange() bool {
+	return true
+}
+
+// FetchDaily fetches the full daily history for symbol.
 func (fmpc *FMPClient) FetchDaily(symbol string) ([]*models.Stock, error) {
+	return fmpc.FetchDailyRange(context.Background(), symbol, time.Time{}, time.Time{})
+}
+
+// FetchDailyRange fetches daily bars between from and to. FMP filters the range server-side through
+// the optional from/to query parameters.
+func (fmpc *FMPClient) FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error) {
 	url := fmt.Sprintf("%s/stable/historical-price-eod/full?symbol=%s&apikey=%s",
 		fmpc.BaseURL, symbol, fmpc.APIKey)
+	if !from.IsZero() {
+		url += "&from=" + from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		url += "&to=" + to.Format("2006-01-02")
+	}
 
 	// Use the shared HTTP Request logic from BaseClient
-	dailyData, err := fmpc.MakeArrayRequest(url)
+	dailyData, err := fmpc.MakeArrayRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +89,115 @@ func (fmpc *FMPClient) FetchDaily(symbol string) ([]*models.Stock, error) {
 		stocks = append(stocks, stock)
 	}
 
-	return stocks, nil
+	return filterByDateRange(stocks, from, to), nil
+}
+
+// FetchCorporateActions fetches the dividends and splits of symbol between from and to. Dividends are reported
+// as paid, not adjusted for later splits, since the adjustment applies the splits itself.
+func (fmpc *FMPClient) FetchCorporateActions(
+	ctx context.Context,
+	symbol string,
+	from, to time.Time,
+) ([]*models.CorporateAction, error) {
+	dividends, err := fmpc.fetchStableData(ctx, "dividends", symbol)
+	if err != nil {
+		return nil, err
+	}
+	splits, err := fmpc.fetchStableData(ctx, "splits", symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]*models.CorporateAction, 0, len(dividends)+len(splits))
+	for _, data := range dividends {
+		amount := getFloat(data, "dividend")
+		if amount == 0 {
+			amount = getFloat(data, "adjDividend")
+		}
+		if action := fmpc.newAction(data, symbol, models.CorporateActionDividend); action != nil && amount > 0 {
+			action.Amount = amount
+			actions = append(actions, action)
+		}
+	}
+	for _, data := range splits {
+		numerator, denominator := getFloat(data, "numerator"), getFloat(data, "denominator")
+		if numerator <= 0 || denominator <= 0 || numerator == denominator {
+			continue // Skip malformed splits
+		}
+		if action := fmpc.newAction(data, symbol, models.CorporateActionSplit); action != nil {
+			action.Ratio = numerator / denominator
+			actions = append(actions, action)
+		}
+	}
+
+	return filterActionsByDateRange(actions, from, to), nil
+}
+
+// FetchCompanyProfile fetches the name, sector, industry and exchange of symbol from the profile endpoint.
+func (fmpc *FMPClient) FetchCompanyProfile(ctx context.Context, symbol string) (*models.Company, error) {
+	data, err := fmpc.fetchStableData(ctx, "profile", symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, client_errors.NewDataNotFoundError("companyName")
+	}
+	profile := data[0]
+	name, _ := profile["companyName"].(string)
+	if name == "" {
+		return nil, client_errors.NewDataNotFoundError("companyName")
+	}
+	sector, _ := profile["sector"].(string)
+	industry, _ := profile["industry"].(string)
+	exchange, _ := profile["exchange"].(string)
+
+	return &models.Company{
+		Symbol:        symbol,
+		Name:          name,
+		Sector:        sector,
+		Industry:      industry,
+		Exchange:      exchange,
+		ProfileSource: fmpc.GetProviderName(),
+	}, nil
+}
+
+// fetchStableData requests one of FMP's stable endpoints that take a symbol, e.g. "dividends". Unlike prices,
+// an empty array is a valid answer: FMP returns [] for symbols that never split or paid a dividend.
+func (fmpc *FMPClient) fetchStableData(ctx context.Context, endpoint, symbol string) ([]map[string]any, error) {
+	url := fmt.Sprintf("%s/stable/%s?symbol=%s&apikey=%s", fmpc.BaseURL, endpoint, symbol, fmpc.APIKey)
+
+	data, err := fmpc.MakeArrayRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return data, nil
+	}
+	if err := fmpc.CheckArrayAPIError(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// newAction creates an action dated at the ex-date in the date field, or nil if there is no valid date.
+func (fmpc *FMPClient) newAction(data map[string]any, symbol, actionType string) *models.CorporateAction {
+	dateStr, ok := data["date"].(string)
+	if !ok {
+		return nil
+	}
+	exDate, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return nil
+	}
+
+	return &models.CorporateAction{
+		Symbol:      symbol,
+		ActionType:  actionType,
+		ExDate:      exDate,
+		Source:      fmpc.GetProviderName(),
+		LastUpdated: time.Now(),
+	}
 }
 
 // getFloat handles handles FMP's numeric format
@@ -1,17 +1,158 @@
 package clients
 
 import (
+	"context"
 	"pocketanalyst/internal/models"
+	"time"
 )
 
 // This interface will allow us to swap between data sources as needed.
+//
+// FetchDailyRange returns the daily bars for symbol between from and to (inclusive). A zero from or to
+// leaves that side of the range open, so passing two zero times fetches the full history. The context
+// is attached to every upstream HTTP request, so cancelling it aborts the fetch.
 type StockDataClient interface {
-	FetchDaily(symbol string) ([]*models.Stock, error)
+	FetchDailyRange(ctx context.Context, symbol string, from, to time.Time) ([]*models.Stock, error)
 	GetProviderName() string
 }
 
+// CorporateActionsClient is implemented by providers that report splits and dividends.
+//
+// FetchCorporateActions returns the actions of symbol with an ex-date between from and to (inclusive), with
+// the same open range semantics as FetchDailyRange. Each action's Source is the provider's name.
+type CorporateActionsClient interface {
+	FetchCorporateActions(ctx context.Context, symbol string, from, to time.Time) ([]*models.CorporateAction, error)
+	GetProviderName() string
+}
+
+// CompanyProfileClient is implemented by providers that describe the companies behind their symbols.
+//
+// FetchCompanyProfile returns the name, sector, industry and exchange of symbol, with ProfileSource set to the
+// provider's name. Fields the provider doesn't report are left empty.
+type CompanyProfileClient interface {
+	FetchCompanyProfile(ctx context.Context, symbol string) (*models.Company, error)
+	GetProviderName() string
+}
+
+// MultiProviderClient is implemented by clients that are backed by several providers, such as FailoverClient.
+// Their GetProviderName does not match a single data source, so Providers lists the individual names.
+type MultiProviderClient interface {
+	Providers() []string
+}
+
+// ProviderNames returns the data source names that client may serve data from.
+func ProviderNames(client StockDataClient) []string {
+	if multi, ok := client.(MultiProviderClient); ok {
+		return multi.Providers()
+	}
+	return []string{client.GetProviderName()}
+}
+
+// DateRangeClient is implemented by clients whose provider filters date ranges server-side, so fetching a
+// short range costs less than fetching the full history. Providers without range support download the full
+// history for every range and filter it locally.
+type DateRangeClient interface {
+	SupportsDateRange() bool
+}
+
+// SupportsDateRange reports whether client fetches a short range more cheaply than the full history.
+func SupportsDateRange(client StockDataClient) bool {
+	ranged, ok := client.(DateRangeClient)
+	return ok && ranged.SupportsDateRange()
+}
+
+// PreferProvider returns client with the named provider tried first. Clients that don't fail over between
+// several providers are returned as they are.
+func PreferProvider(client StockDataClient, provider string) StockDataClient {
+	if failover, ok := client.(*FailoverClient); ok {
+		return failover.Preferring(provider)
+	}
+	return client
+}
+
+// RateLimitedClient is implemented by clients whose outgoing requests can be throttled by a RateLimiter.
+// Clients embedding BaseClient implement it automatically.
+type RateLimitedClient interface {
+	SetRateLimiter(limiter *RateLimiter)
+}
+
+// RetryingClient is implemented by clients that retry transient failures according to a RetryPolicy.
+// Clients embedding BaseClient implement it automatically.
+type RetryingClient interface {
+	SetRetryPolicy(policy RetryPolicy)
+}
+
+// ProviderHealth describes the current health of a single data provider.
+type ProviderHealth struct {
+	Provider            string       `json:"provider"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	RetryAfterSeconds   int          `json:"retry_after_seconds,omitempty"` // Remaining cooldown while open
+}
+
+// Healthy reports whether requests to the provider are currently let through normally.
+func (ph ProviderHealth) Healthy() bool {
+	return ph.State == CircuitClosed
+}
+
+// HealthReporter is implemented by clients that track the health of the providers behind them.
+type HealthReporter interface {
+	ProviderHealth() []ProviderHealth
+}
+
 // Holds configuration parameters for creating clients.
 type ClientConfig struct {
-	BaseURL string `json:"base_url"`
-	APIKey  string `json:"api_key"`
+	BaseURL     string       `json:"base_url"`
+	APIKey      string       `json:"api_key"`
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"` // nil uses DefaultRetryPolicy
+}
+
+// filterByDateRange drops every stock outside of [from, to]. A zero bound is treated as open.
+// Providers that cannot filter server-side use this to honour the requested range.
+func filterByDateRange(stocks []*models.Stock, from, to time.Time) []*models.Stock {
+	if from.IsZero() && to.IsZero() {
+		return stocks
+	}
+
+	// Bars are dated at midnight UTC, so compare against the calendar day of each bound.
+	from, to = startOfDay(from), startOfDay(to)
+
+	filtered := stocks[:0]
+	for _, stock := range stocks {
+		if !from.IsZero() && stock.Date.Before(from) {
+			continue
+		}
+		if !to.IsZero() && stock.Date.After(to) {
+			continue
+		}
+		filtered = append(filtered, stock)
+	}
+	return filtered
+}
+
+// filterActionsByDateRange drops every corporate action with an ex-date outside of [from, to].
+// A zero bound is treated as open.
+func filterActionsByDateRange(actions []*models.CorporateAction, from, to time.Time) []*models.CorporateAction {
+	from, to = startOfDay(from), startOfDay(to)
+
+	filtered := actions[:0]
+	for _, action := range actions {
+		if !from.IsZero() && action.ExDate.Before(from) {
+			continue
+		}
+		if !to.IsZero() && action.ExDate.After(to) {
+			continue
+		}
+		filtered = append(filtered, action)
+	}
+	return filtered
+}
+
+// startOfDay returns midnight UTC of t's calendar day, keeping the zero time as-is.
+func startOfDay(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
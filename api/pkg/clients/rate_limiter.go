@@ -0,0 +1,176 @@
+package clients
+
+import (
+	"context"
+	"log"
+	"pocketanalyst/internal/models"
+	"pocketanalyst/pkg/errors/client_errors"
+	"sync"
+	"time"
+)
+
+// QuotaStore persists the number of requests made to a provider per UTC day, so the daily quota
+// survives restarts and is shared between instances.
+type QuotaStore interface {
+	// GetUsage returns how many requests were made to provider on day.
+	GetUsage(ctx context.Context, provider string, day time.Time) (int, error)
+	// IncrementUsage records one more request to provider on day and returns the new total.
+	IncrementUsage(ctx context.Context, provider string, day time.Time) (int, error)
+}
+
+// RateLimiter is a token bucket limiter for a single provider. The bucket holds up to the per-minute
+// limit and refills continuously, while a separate counter enforces the per-day quota.
+// A limit of 0 disables that check.
+type RateLimiter struct {
+	provider  string
+	perMinute int
+	perDay    int
+	store     QuotaStore
+	failFast  bool
+	now       func() time.Time // Replaceable clock for tests
+
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+	day        time.Time // UTC day dailyUsed belongs to
+	dailyUsed  int
+}
+
+// NewRateLimiter creates a rate limiter for provider. When failFast is true, requests that would exceed
+// the per-minute limit fail with a RateLimitExceededError instead of waiting for a token. Exhausting the
+// daily quota always fails, since waiting for the next day is never useful to a caller. The store may be nil,
+// in which case daily usage is only tracked in memory.
+func NewRateLimiter(provider string, perMinute, perDay int, store QuotaStore, failFast bool) *RateLimiter {
+	return &RateLimiter{
+		provider:  provider,
+		perMinute: perMinute,
+		perDay:    perDay,
+		store:     store,
+		failFast:  failFast,
+		now:       time.Now,
+		tokens:    float64(perMinute),
+	}
+}
+
+// NewRateLimiterFromDataSource creates a rate limiter using the limits configured in the data_sources table.
+func NewRateLimiterFromDataSource(ds *models.DataSource, store QuotaStore, failFast bool) *RateLimiter {
+	return NewRateLimiter(ds.SourceName, ds.RateLimitPerMinute, ds.RateLimitPerDay, store, failFast)
+}
+
+// Provider returns the name of the provider this limiter throttles.
+func (rl *RateLimiter) Provider() string {
+	return rl.provider
+}
+
+// Wait blocks until a request may be made, or returns an error if the quota is exhausted, the limiter
+// is in fail-fast mode and no token is available, or ctx is done first.
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay, err := rl.reserve(ctx)
+		if err != nil {
+			return err
+		}
+
+		// A token was taken, count it against the daily quota
+		if delay == 0 {
+			rl.recordUsage(ctx)
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available. Otherwise it returns how long to wait for the next one.
+func (rl *RateLimiter) reserve(ctx context.Context) (time.Duration, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+
+	// Check the daily quota first, loading the persisted usage whenever a new day starts
+	if rl.perDay > 0 {
+		today := startOfDay(now.UTC())
+		if !rl.day.Equal(today) {
+			rl.day = today
+			rl.dailyUsed = rl.loadUsage(ctx, today)
+		}
+
+		if rl.dailyUsed >= rl.perDay {
+			return 0, client_errors.NewRateLimitExceededError(
+				rl.provider, "day", rl.perDay, today.AddDate(0, 0, 1).Sub(now))
+		}
+	}
+
+	if rl.perMinute <= 0 {
+		rl.dailyUsed++
+		return 0, nil
+	}
+
+	// Refill the bucket for the time elapsed since the last request
+	ratePerSecond := float64(rl.perMinute) / 60
+	if !rl.lastRefill.IsZero() {
+		rl.tokens += now.Sub(rl.lastRefill).Seconds() * ratePerSecond
+		if rl.tokens > float64(rl.perMinute) {
+			rl.tokens = float64(rl.perMinute)
+		}
+	}
+	rl.lastRefill = now
+
+	if rl.tokens >= 1 {
+		rl.tokens--
+		rl.dailyUsed++
+		return 0, nil
+	}
+
+	delay := time.Duration((1 - rl.tokens) / ratePerSecond * float64(time.Second))
+	if rl.failFast {
+		return 0, client_errors.NewRateLimitExceededError(rl.provider, "minute", rl.perMinute, delay)
+	}
+	return delay, nil
+}
+
+// loadUsage reads the persisted usage for day. Failures are logged and treated as no usage, so an
+// unavailable store never blocks requests.
+func (rl *RateLimiter) loadUsage(ctx context.Context, day time.Time) int {
+	if rl.store == nil {
+		return 0
+	}
+
+	used, err := rl.store.GetUsage(ctx, rl.provider, day)
+	if err != nil {
+		log.Printf("Warning: could not load %s quota usage: %v", rl.provider, err)
+		return 0
+	}
+	return used
+}
+
+// recordUsage persists one request and syncs the in-memory counter with the stored total, which also
+// accounts for requests made by other instances.
+func (rl *RateLimiter) recordUsage(ctx context.Context) {
+	if rl.store == nil || rl.perDay <= 0 {
+		return
+	}
+
+	rl.mu.Lock()
+	day := rl.day
+	rl.mu.Unlock()
+
+	used, err := rl.store.IncrementUsage(ctx, rl.provider, day)
+	if err != nil {
+		log.Printf("Warning: could not persist %s quota usage: %v", rl.provider, err)
+		return
+	}
+
+	rl.mu.Lock()
+	if rl.day.Equal(day) && used > rl.dailyUsed {
+		rl.dailyUsed = used
+	}
+	rl.mu.Unlock()
+}
@@ -0,0 +1,87 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"pocketanalyst/pkg/errors/client_errors"
+	"testing"
+	"time"
+)
+
+// memoryQuotaStore is an in-memory QuotaStore used to simulate persisted usage.
+type memoryQuotaStore struct {
+	usage map[string]int
+}
+
+func (m *memoryQuotaStore) key(provider string, day time.Time) string {
+	return provider + day.Format("2006-01-02")
+}
+
+func (m *memoryQuotaStore) GetUsage(ctx context.Context, provider string, day time.Time) (int, error) {
+	return m.usage[m.key(provider, day)], nil
+}
+
+func (m *memoryQuotaStore) IncrementUsage(ctx context.Context, provider string, day time.Time) (int, error) {
+	m.usage[m.key(provider, day)]++
+	return m.usage[m.key(provider, day)], nil
+}
+
+// TestRateLimiter_PerMinute verifies the token bucket empties and refills at the configured rate.
+func TestRateLimiter_PerMinute(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter("AlphaVantage", 5, 0, nil, true)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Request %d should be allowed, got %v", i+1, err)
+		}
+	}
+
+	err := limiter.Wait(context.Background())
+	var rateLimitErr *client_errors.RateLimitExceededError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected RateLimitExceededError once the bucket is empty, got %v", err)
+	}
+	if rateLimitErr.Window != "minute" || rateLimitErr.RetryAfter != 12*time.Second {
+		t.Errorf("Expected to retry a minute window after 12s, got %s after %s", rateLimitErr.Window, rateLimitErr.RetryAfter)
+	}
+
+	// One token refills every 12 seconds at 5 requests per minute
+	now = now.Add(12 * time.Second)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("Request should be allowed after a token refilled, got %v", err)
+	}
+}
+
+// TestRateLimiter_PerDayPersisted verifies the daily quota includes usage loaded from the store.
+func TestRateLimiter_PerDayPersisted(t *testing.T) {
+	now := time.Date(2025, 1, 2, 23, 0, 0, 0, time.UTC)
+	store := &memoryQuotaStore{usage: map[string]int{}}
+	store.usage[store.key("FMP", startOfDay(now))] = 2
+
+	limiter := NewRateLimiter("FMP", 0, 3, store, false)
+	limiter.now = func() time.Time { return now }
+
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Third request of the day should be allowed, got %v", err)
+	}
+	if used := store.usage[store.key("FMP", startOfDay(now))]; used != 3 {
+		t.Errorf("Expected persisted usage of 3, got %d", used)
+	}
+
+	err := limiter.Wait(context.Background())
+	var rateLimitErr *client_errors.RateLimitExceededError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.Window != "day" {
+		t.Fatalf("Expected daily RateLimitExceededError, got %v", err)
+	}
+	if rateLimitErr.RetryAfter != time.Hour {
+		t.Errorf("Expected to retry at midnight UTC in 1h, got %s", rateLimitErr.RetryAfter)
+	}
+
+	// The quota resets on the next UTC day
+	now = now.Add(2 * time.Hour)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("Request should be allowed on a new day, got %v", err)
+	}
+}
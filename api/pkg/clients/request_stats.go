@@ -0,0 +1,45 @@
+package clients
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// RequestStats counts the HTTP requests made on behalf of a single operation, e.g. one synchronization.
+// It is carried in the context, so it sees every request no matter how the clients are wrapped.
+type RequestStats struct {
+	requests atomic.Int64
+	retries  atomic.Int64
+}
+
+// requestStatsKey is the context key under which the RequestStats are stored.
+type requestStatsKey struct{}
+
+// WithRequestStats returns a context that records the requests made with it into the returned RequestStats.
+func WithRequestStats(ctx context.Context) (context.Context, *RequestStats) {
+	stats := &RequestStats{}
+	return context.WithValue(ctx, requestStatsKey{}, stats), stats
+}
+
+// Requests returns how many requests were attempted, including retries.
+func (rs *RequestStats) Requests() int {
+	return int(rs.requests.Load())
+}
+
+// Retries returns how many of the requests were retries of a failed request.
+func (rs *RequestStats) Retries() int {
+	return int(rs.retries.Load())
+}
+
+// recordRequest counts a request attempt against the stats in ctx, if there are any.
+func recordRequest(ctx context.Context, retry bool) {
+	stats, ok := ctx.Value(requestStatsKey{}).(*RequestStats)
+	if !ok {
+		return
+	}
+
+	stats.requests.Add(1)
+	if retry {
+		stats.retries.Add(1)
+	}
+}
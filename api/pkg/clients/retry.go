@@ -0,0 +1,61 @@
+package clients
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how BaseClient retries requests that failed with a retryable error.
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"max_attempts"`    // Total attempts including the first, 1 disables retries
+	InitialBackoff time.Duration `json:"initial_backoff"` // Backoff before the first retry
+	MaxBackoff     time.Duration `json:"max_backoff"`     // Upper bound for any single wait, including Retry-After
+}
+
+// DefaultRetryPolicy returns the policy used by clients that were not configured otherwise.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+}
+
+// backoff returns how long to wait before the given retry (1 for the first retry). The delay doubles
+// with every retry up to MaxBackoff, and the upper half is randomized so that concurrent callers
+// don't retry in lockstep.
+func (rp RetryPolicy) backoff(retry int) time.Duration {
+	delay := rp.InitialBackoff
+	for i := 1; i < retry && delay < rp.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if rp.MaxBackoff > 0 && delay > rp.MaxBackoff {
+		delay = rp.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter parses a Retry-After header, which holds either a number of seconds or an HTTP date.
+// It returns 0 if the header is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
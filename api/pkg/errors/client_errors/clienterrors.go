@@ -4,13 +4,20 @@
 package client_errors
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 )
 
 // Base interface for all API client errors
+//
+//	Retryable: Reports whether the failure is transient, so repeating the same request may succeed.
 type ClientError interface {
 	error
 	ErrorCode() string
+	Retryable() bool
 }
 
 // HTTPRequestError occurs when a request to an external API fails.
@@ -32,6 +39,11 @@ func (e *HTTPRequestError) Unwrap() error {
 	return e.InnerError
 }
 
+// Network failures are transient, unless the caller cancelled the request themselves.
+func (e *HTTPRequestError) Retryable() bool {
+	return !errors.Is(e.InnerError, context.Canceled)
+}
+
 // Returns a new HTTPRequestError.
 //
 //	HTTPRequestError: Occurs when a request to an external API Fails.
@@ -49,6 +61,7 @@ type HTTPStatusError struct {
 	URL          string
 	StatusCode   int
 	ResponseBody string
+	RetryAfter   time.Duration // Parsed Retry-After header, 0 if the response did not include one
 }
 
 func (e *HTTPStatusError) Error() string {
@@ -59,6 +72,11 @@ func (e *HTTPStatusError) ErrorCode() string {
 	return "HTTP_STATUS_ERROR"
 }
 
+// Only throttling (429) and server side (5xx) failures are worth retrying.
+func (e *HTTPStatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
 // Returns a new HTTPStatusError
 //
 //	HTTPStatusError: Occurs when the returned status code is not 200.
@@ -90,6 +108,11 @@ func (e *ResponseReadError) Unwrap() error {
 	return e.InnerError
 }
 
+// The connection dropping mid-body is transient.
+func (e *ResponseReadError) Retryable() bool {
+	return true
+}
+
 // Returns a new ResponseReadError
 //
 //	ResponseReadError: Occurs when there is an error from READING the response.
@@ -117,6 +140,11 @@ func (e *ResponseParseError) Unwrap() error {
 	return e.InnerError
 }
 
+// A malformed response is returned again on retry.
+func (e *ResponseParseError) Retryable() bool {
+	return false
+}
+
 // Returns a new ResponseParseError
 //
 //	ResponseParseError: Occurs when there is an error PARSING the response.
@@ -140,6 +168,11 @@ func (e *APIError) ErrorCode() string {
 	return "API_ERROR"
 }
 
+// Explicit API errors, like an invalid symbol, do not change on retry.
+func (e *APIError) Retryable() bool {
+	return false
+}
+
 // Returns a new APIError
 //
 //	APIError: Occurs when there is an explicit API error.
@@ -163,6 +196,10 @@ func (e *DataNotFoundError) ErrorCode() string {
 	return "DATA_NOT_FOUND_ERROR"
 }
 
+func (e *DataNotFoundError) Retryable() bool {
+	return false
+}
+
 // Returns a new DataNotFoundError
 //
 //	DataNotFoundError: Occurs when the expected data is not found from the API.
@@ -173,6 +210,83 @@ func NewDataNotFoundError(key string) *DataNotFoundError {
 	}
 }
 
+// RateLimitExceededError occurs when a provider's rate limit leaves no room for another request.
+type RateLimitExceededError struct {
+	Provider   string        // Name of the rate limited provider
+	Window     string        // The exhausted limit, "minute" or "day"
+	Limit      int           // Number of requests allowed per window
+	RetryAfter time.Duration // How long until a request will be allowed again
+}
+
+func (e *RateLimitExceededError) Error() string {
+	return fmt.Sprintf("rate limit of %d requests per %s exceeded for %s, retry after %s",
+		e.Limit, e.Window, e.Provider, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitExceededError) ErrorCode() string {
+	return "RATE_LIMIT_EXCEEDED"
+}
+
+// Retrying immediately would defeat the limiter, callers are expected to honour RetryAfter instead.
+func (e *RateLimitExceededError) Retryable() bool {
+	return false
+}
+
+// Returns a new RateLimitExceededError
+//
+//	RateLimitExceededError: Occurs when a request would exceed the provider's rate limit.
+//	provider: The name of the provider
+//	window: The exhausted limit window, "minute" or "day"
+//	limit: The number of requests allowed per window
+//	retryAfter: How long the caller should wait before trying again
+func NewRateLimitExceededError(provider, window string, limit int, retryAfter time.Duration) *RateLimitExceededError {
+	return &RateLimitExceededError{
+		Provider:   provider,
+		Window:     window,
+		Limit:      limit,
+		RetryAfter: retryAfter,
+	}
+}
+
+// CircuitOpenError occurs when a provider's circuit breaker is open and requests are short-circuited
+// instead of waiting on a provider that is known to be failing.
+type CircuitOpenError struct {
+	Provider   string        // Name of the failing provider
+	RetryAfter time.Duration // How long until the breaker lets a trial request through
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open, retry after %s",
+		e.Provider, e.RetryAfter.Round(time.Second))
+}
+
+func (e *CircuitOpenError) ErrorCode() string {
+	return "CIRCUIT_OPEN"
+}
+
+// The breaker stays open for the cooldown, so retrying right away is pointless.
+func (e *CircuitOpenError) Retryable() bool {
+	return false
+}
+
+// Returns a new CircuitOpenError
+//
+//	CircuitOpenError: Occurs when requests to a failing provider are short-circuited.
+//	provider: The name of the provider
+//	retryAfter: How long until the breaker half-opens
+func NewCircuitOpenError(provider string, retryAfter time.Duration) *CircuitOpenError {
+	return &CircuitOpenError{
+		Provider:   provider,
+		RetryAfter: retryAfter,
+	}
+}
+
+// IsRetryable reports whether err is a ClientError that is safe to retry.
+func IsRetryable(err error) bool {
+	var clientErr ClientError
+	return errors.As(err, &clientErr) && clientErr.Retryable()
+}
+
 // Compile time check to see if each error implements the ClientError interface
 var (
 	_ ClientError = (*HTTPRequestError)(nil)
@@ -181,4 +295,6 @@ var (
 	_ ClientError = (*ResponseParseError)(nil)
 	_ ClientError = (*APIError)(nil)
 	_ ClientError = (*DataNotFoundError)(nil)
+	_ ClientError = (*RateLimitExceededError)(nil)
+	_ ClientError = (*CircuitOpenError)(nil)
 )
@@ -21,6 +21,10 @@ var (
 	// if err's type contains an Unwrap method returning error.
 	// Otherwise, Unwrap returns nil.
 	Unwrap = errors.Unwrap
+
+	// Returns an error that wraps the given errors,
+	// discarding any nil errors.
+	Join = errors.Join
 )
 
 // NotFoundError occurs when a requested resource is not found.
@@ -31,7 +35,7 @@ type NotFoundError struct {
 
 // Implements the error interface, returning a formatted NotFoundError.
 func (e *NotFoundError) Error() string {
-	return fmt.Sprintf("%s with ID %s was not found",
+	return fmt.Sprintf("%s with ID %v was not found",
 		e.EntityType,
 		e.ID)
 }
@@ -45,6 +49,50 @@ func NewNotFoundError(entityType string, id any) error {
 	}
 }
 
+// ConflictError occurs when a resource cannot be created or changed because
+// it would duplicate an existing one.
+type ConflictError struct {
+	EntityType string
+	Message    string
+}
+
+// Error returns a formatted error message for ConflictError.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s conflict: %s",
+		e.EntityType,
+		e.Message)
+}
+
+// NewConflictError creates a new conflict error for the given entity type.
+func NewConflictError(entityType, message string) error {
+	return &ConflictError{
+		EntityType: entityType,
+		Message:    message,
+	}
+}
+
+// UnavailableError occurs when an operation cannot be carried out by this
+// instance, e.g. because the component doing the work is disabled.
+type UnavailableError struct {
+	Operation string
+	Message   string
+}
+
+// Error returns a formatted error message for UnavailableError.
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s is unavailable: %s",
+		e.Operation,
+		e.Message)
+}
+
+// NewUnavailableError creates a new unavailable error for the given operation.
+func NewUnavailableError(operation, message string) error {
+	return &UnavailableError{
+		Operation: operation,
+		Message:   message,
+	}
+}
+
 // ServiceError wraps an error that occurred during a service operation
 type ServiceError struct {
 	Operation string
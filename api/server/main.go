@@ -4,7 +4,10 @@ import (
 	"log"
 	"os"
 	"pocketanalyst/internal/app"
+	"pocketanalyst/internal/services"
+	"pocketanalyst/pkg/clients"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
@@ -14,6 +17,14 @@ func main() {
 	// Load configuration from environment variables
 	config := loadConfig()
 
+	// Migrations run before the application is set up, as the setup needs the tables
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := app.RunMigrateCommand(config, os.Args[2:]); err != nil {
+			log.Fatalf("Command migrate failed: %v", err)
+		}
+		return
+	}
+
 	// Create and initalize the application
 	app, err := app.NewApp(config)
 	if err != nil {
@@ -27,6 +38,16 @@ func main() {
 		}
 	}()
 
+	// Run a subcommand instead of the server if one was given, e.g. "import"
+	if len(os.Args) > 1 {
+		if err := app.RunCommand(os.Args[1:]); err != nil {
+			log.Printf("Command %s failed: %v", os.Args[1], err)
+			app.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Start the server
 	if err := app.Start(); err != nil {
 		log.Fatalf("Server failed to start %v", err)
@@ -39,14 +60,62 @@ func loadConfig() *app.Config {
 
 	return &app.Config{
 		DatabaseURL:           dbURL,
+		AutoMigrate:           getEnvAsBool("AUTO_MIGRATE", false),
 		FMPAPIKey:             getEnvWithDefault("FMP_API_KEY", ""),
 		FMPBaseURL:            getEnvWithDefault("FMP_BASE_URL", "https://financialmodelingprep.com"),
+		AlphaVantageAPIKey:    getEnvWithDefault("ALPHAVANTAGE_API_KEY", ""),
+		AlphaVantageBaseURL:   getEnvWithDefault("ALPHAVANTAGE_BASE_URL", "https://www.alphavantage.co/query"),
+		CSVBaseURL:            getEnvWithDefault("CSV_BASE_URL", "./data/csv"),
+		Providers:             getEnvAsList("DATA_PROVIDERS", []string{"fmp"}),
 		Port:                  getEnvWithDefault("PORT", "8080"),
 		ReadTimeout:           time.Duration(getEnvAsInt("READ_TIMEOUT_SECONDS", 30)) * time.Second,
 		WriteTimeout:          time.Duration(getEnvAsInt("WRITE_TIMEOUT_SECONDS", 30)) * time.Second,
 		MaxIdleConnections:    getEnvAsInt("MAX_IDLE_CONNECTIONS", 10),
 		MaxOpenConnections:    getEnvAsInt("MAX_OPEN_CONNECTIONS", 100),
 		ConnectionMaxLifetime: time.Duration(getEnvAsInt("CONNECTION_MAX_LIFETIME_MINUTES", 60)) * time.Minute,
+		SyncOverlapDays:       getEnvAsInt("SYNC_OVERLAP_DAYS", 5),
+		SyncConcurrency:       getEnvAsInt("SYNC_CONCURRENCY", 4),
+		RateLimitFailFast:     getEnvAsBool("RATE_LIMIT_FAIL_FAST", false),
+		RetryPolicies: map[string]clients.RetryPolicy{
+			"fmp":          loadRetryPolicy("fmp"),
+			"alphavantage": loadRetryPolicy("alphavantage"),
+			"csv":          loadRetryPolicy("csv"),
+		},
+		CircuitFailureLimit: getEnvAsInt("CIRCUIT_BREAKER_FAILURE_THRESHOLD", 5),
+		CircuitCooldown:     time.Duration(getEnvAsInt("CIRCUIT_BREAKER_COOLDOWN_SECONDS", 60)) * time.Second,
+		ReconcileTolerances: services.ReconciliationTolerances{
+			PriceTolerance:  getEnvAsFloat("RECONCILE_PRICE_TOLERANCE", 0.005),
+			VolumeTolerance: getEnvAsFloat("RECONCILE_VOLUME_TOLERANCE", 0.05),
+		},
+		ImportChunkSize:     getEnvAsInt("IMPORT_CHUNK_SIZE", 500),
+		ImportDefaultSource: getEnvWithDefault("IMPORT_DEFAULT_SOURCE", "Import"),
+		SchedulerEnabled:    getEnvAsBool("SCHEDULER_ENABLED", true),
+		Scheduler: services.SchedulerConfig{
+			PollInterval: time.Duration(getEnvAsInt("SCHEDULER_POLL_INTERVAL_SECONDS", 60)) * time.Second,
+			BatchSize:    getEnvAsInt("SCHEDULER_BATCH_SIZE", 10),
+			JobTimeout:   time.Duration(getEnvAsInt("JOB_TIMEOUT_MINUTES", 10)) * time.Minute,
+			StaleAfter:   time.Duration(getEnvAsInt("JOB_STALE_AFTER_MINUTES", 30)) * time.Minute,
+		},
+		Backfill: services.BackfillConfig{
+			Workers:          getEnvAsInt("BACKFILL_WORKERS", 2),
+			PollInterval:     time.Duration(getEnvAsInt("BACKFILL_POLL_INTERVAL_SECONDS", 30)) * time.Second,
+			ChunkTimeout:     time.Duration(getEnvAsInt("BACKFILL_CHUNK_TIMEOUT_MINUTES", 10)) * time.Minute,
+			MaxAttempts:      getEnvAsInt("BACKFILL_MAX_ATTEMPTS", 3),
+			DefaultChunkDays: getEnvAsInt("BACKFILL_CHUNK_DAYS", 365),
+		},
+	}
+}
+
+// loadRetryPolicy reads a provider's retry policy from <PROVIDER>_RETRY_* variables, falling back to the
+// client defaults. E.g. FMP_RETRY_MAX_ATTEMPTS, FMP_RETRY_INITIAL_BACKOFF_MS, FMP_RETRY_MAX_BACKOFF_MS.
+func loadRetryPolicy(provider string) clients.RetryPolicy {
+	prefix := strings.ToUpper(provider) + "_RETRY_"
+	defaults := clients.DefaultRetryPolicy()
+
+	return clients.RetryPolicy{
+		MaxAttempts:    getEnvAsInt(prefix+"MAX_ATTEMPTS", defaults.MaxAttempts),
+		InitialBackoff: time.Duration(getEnvAsInt(prefix+"INITIAL_BACKOFF_MS", int(defaults.InitialBackoff.Milliseconds()))) * time.Millisecond,
+		MaxBackoff:     time.Duration(getEnvAsInt(prefix+"MAX_BACKOFF_MS", int(defaults.MaxBackoff.Milliseconds()))) * time.Millisecond,
 	}
 }
 
@@ -68,3 +137,41 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsFloat returns environment variables as float or default if not set/invalid
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if valueStr := os.Getenv(key); valueStr != "" {
+		if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+			return value
+		}
+		log.Printf("Warning: Invalid float value for %s: %s, using default: %g", key, valueStr, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvAsBool returns environment variables as boolean or default if not set/invalid
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if valueStr := os.Getenv(key); valueStr != "" {
+		if value, err := strconv.ParseBool(valueStr); err == nil {
+			return value
+		}
+		log.Printf("Warning: Invalid boolean value for %s: %s, using default: %t", key, valueStr, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvAsList returns a comma separated environment variable as a list or default if not set
+func getEnvAsList(key string, defaultValue []string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -1,4 +1,6 @@
 -- Database schema for analysis and prediction
+-- Snapshot of the schema after all migrations in api/internal/migrations/sql/postgres, which are authoritative.
+-- Change the schema by adding a migration, then update this snapshot.
 
 -- Companies table to store company information
 CREATE TABLE IF NOT EXISTS companies (
@@ -9,7 +11,10 @@ CREATE TABLE IF NOT EXISTS companies (
 	industry VARCHAR(100),
 	exchange VARCHAR(50),
 	is_active BOOLEAN DEFAULT TRUE,
-	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	profile_source VARCHAR(100),			-- Provider the profile came from, or Manual
+	profile_updated TIMESTAMP,			-- NULL for placeholders named after their symbol
+	profile_checked TIMESTAMP			-- When a profile was last requested for the placeholder
 );
 
 -- Data sources configuration table
@@ -22,9 +27,19 @@ CREATE TABLE IF NOT EXISTS data_sources (
 	rate_limit_per_day INTEGER,
 	config_parameters JSONB,			-- Non-sensitive configuration parameters
 	is_active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Daily request counts per data source, used to enforce rate_limit_per_day across restarts
+CREATE TABLE IF NOT EXISTS provider_quota_usage (
+	source_name VARCHAR(100) NOT NULL REFERENCES data_sources(source_name),
+	usage_date DATE NOT NULL,				-- UTC day the requests were made on
+	request_count INTEGER NOT NULL DEFAULT 0,
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (source_name, usage_date)
+);
+
 -- Data fetch jobs for tracking what to fetch and when
 CREATE TABLE IF NOT EXISTS data_fetch_jobs (
 	job_id SERIAL PRIMARY KEY,
@@ -37,12 +52,40 @@ CREATE TABLE IF NOT EXISTS data_fetch_jobs (
 	last_execution TIMESTAMP,			-- When job was last executed
 	last_success TIMESTAMP,				-- When job was last completed successfully
 	next_scheduled TIMESTAMP,			-- When the job should run next
+	run_requested TIMESTAMP,			-- When a run outside of the schedule was requested, NULL once it ran
 	status VARCHAR(20) DEFAULT 'PENDING',		-- "PENDING", "RUNNING", "SUCCESS", "FAILED"
 	is_active BOOLEAN DEFAULT TRUE,
 	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	CONSTRAINT fetch_job_unique UNIQUE (source_id, entity_type, entity_value, data_type)
 );
 
+-- Backfill runs loading a date range for a list of symbols
+CREATE TABLE IF NOT EXISTS backfill_runs (
+	run_id SERIAL PRIMARY KEY,
+	symbols TEXT[] NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	chunk_days INTEGER NOT NULL,			-- Length of the date range fetched per chunk
+	status VARCHAR(20) NOT NULL DEFAULT 'RUNNING',	-- "RUNNING", "PAUSED", "CANCELLED", "COMPLETED", "FAILED"
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Checkpoints of a backfill run, one per symbol and date range
+CREATE TABLE IF NOT EXISTS backfill_chunks (
+	chunk_id SERIAL PRIMARY KEY,
+	run_id INTEGER NOT NULL REFERENCES backfill_runs(run_id) ON DELETE CASCADE,
+	symbol VARCHAR(20) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'PENDING',	-- "PENDING", "RUNNING", "SUCCESS", "FAILED"
+	attempts INTEGER NOT NULL DEFAULT 0,
+	records_processed INTEGER NOT NULL DEFAULT 0,
+	error_message TEXT,
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT backfill_chunk_unique UNIQUE (run_id, symbol, start_date)
+);
+
 -- Stock price data
 CREATE TABLE IF NOT EXISTS stock_prices (
     price_id SERIAL PRIMARY KEY,
@@ -62,6 +105,20 @@ CREATE TABLE IF NOT EXISTS stock_prices (
     CONSTRAINT stock_price_unique UNIQUE (company_id, date, source_id)
 );
 
+-- Splits and cash dividends used to back-adjust stock prices
+CREATE TABLE IF NOT EXISTS corporate_actions (
+	action_id SERIAL PRIMARY KEY,
+	company_id INTEGER NOT NULL REFERENCES companies(company_id),
+	symbol VARCHAR(20) NOT NULL,
+	action_type VARCHAR(20) NOT NULL,		-- "SPLIT", "DIVIDEND"
+	ex_date DATE NOT NULL,
+	ratio NUMERIC(15, 8),				-- New shares per old share, splits only
+	amount NUMERIC(10, 5),				-- Cash per share, dividends only
+	source VARCHAR(100) NOT NULL,			-- Provider or "Manual"
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT corporate_action_unique UNIQUE (company_id, action_type, ex_date)
+);
+
 -- Table for storing calculated technical indicators
 CREATE TABLE IF NOT EXISTS technical_indicators (
 	indicator_id SERIAL PRIMARY KEY,
@@ -219,3 +276,4 @@ CREATE INDEX IF NOT EXISTS idx_feature_data_company_date ON feature_data(company
 CREATE INDEX IF NOT EXISTS idx_ml_predictions_company_target ON ml_predictions(company_id, target_date);
 CREATE INDEX IF NOT EXISTS idx_data_fetch_jobs_next_scheduled ON data_fetch_jobs(next_scheduled, is_active);
 CREATE INDEX IF NOT EXISTS idx_job_execution_logs_job_id ON job_execution_logs(job_id);
+CREATE INDEX IF NOT EXISTS idx_backfill_chunks_run_status ON backfill_chunks(run_id, status);